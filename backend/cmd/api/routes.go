@@ -1,18 +1,26 @@
 package api
 
 import (
+	"log"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/lipeichen/ticket-getter/config"
 	"github.com/lipeichen/ticket-getter/internal/controllers"
 	"github.com/lipeichen/ticket-getter/internal/middleware"
+	"github.com/lipeichen/ticket-getter/internal/models"
 	"github.com/lipeichen/ticket-getter/internal/services"
 	"github.com/lipeichen/ticket-getter/pkg/cache"
+	"github.com/lipeichen/ticket-getter/pkg/ipreputation"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"github.com/lipeichen/ticket-getter/pkg/oidc"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
 // RegisterRoutes 注冊所有 API 路由
-func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, redisClient *redis.Client) {
+func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, redisClient *redis.Client, signingKeys *jwtkeys.KeySet, rateLimitService *services.RateLimitService) {
 	cfg := config.LoadConfig()
 
 	// 初始化快取
@@ -20,20 +28,90 @@ func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, redisClient *redis.Cli
 	eventCache := cache.NewEventCache(redisCache)
 	ticketCache := cache.NewTicketCache(redisCache)
 
+	// 初始化郵件寄送，未設定 SMTP 時輸出到日誌
+	var mail mailer.Mailer = mailer.NewLogMailer()
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+
 	// 初始化服務
-	authService := services.NewAuthService(db, cfg)
-	ticketService := services.NewTicketService(db, redisClient)
+	authService := services.NewAuthService(db, redisClient, cfg, mail, signingKeys)
+	ticketService := services.NewTicketService(db, redisClient, cfg)
+	invoiceService := services.NewInvoiceService(db, cfg)
+	orderService := services.NewOrderService(db, invoiceService)
+	walletService := services.NewWalletService(db, cfg, ticketService)
+	eventService := services.NewEventService(db, eventCache, ticketCache)
+	eventService.WalletService = walletService
+	userService := services.NewUserService(db, cfg, mail, orderService, ticketService)
+	apiKeyService := services.NewAPIKeyService(db)
+
+	// 購票風險評估，IP 信譽名單由本地檔案載入
+	ipReputation, err := ipreputation.Load(cfg.RiskIPReputationFiles...)
+	if err != nil {
+		log.Fatalf("無法載入 IP 信譽名單: %v", err)
+	}
+	riskService := services.NewRiskService(db, redisClient, cfg, ipReputation)
+	powService := services.NewProofOfWorkService(redisClient, cfg)
+	captchaService, err := services.NewCaptchaService(redisClient, cfg)
+	if err != nil {
+		log.Fatalf("無法初始化 CAPTCHA 驗證: %v", err)
+	}
+	authService.Captcha = captchaService
+
+	// 郵件寄送與兩步驟驗證嘗試次數的計數與限流政策共用斷路器，Redis 故障時改用程序內計數
+	authService.RateLimits = rateLimitService
+	authService.MFAService.RateLimits = rateLimitService
+
+	// 記錄登入與購票裝置，找出共用裝置、IP 或付款方式的帳號群組
+	deviceGraphService := services.NewDeviceGraphService(db, redisClient)
+	authService.DeviceGraph = deviceGraphService
+	orderService.DeviceGraph = deviceGraphService
+	rateLimitService.DeviceGraph = deviceGraphService
+
+	// 依票種政策限制同一裝置指紋的購買次數
+	fingerprintService, err := services.NewFingerprintService(db, redisClient, cfg)
+	if err != nil {
+		log.Fatalf("無法載入指紋白名單: %v", err)
+	}
+
+	// 第三方登入提供者，僅啟用已設定用戶端的提供者
+	var oidcProviders []oidc.Provider
+	if cfg.GoogleClientID != "" {
+		oidcProviders = append(oidcProviders, oidc.NewGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.OIDCRedirectBaseURL+"/google"))
+	}
+	if cfg.LINEClientID != "" {
+		oidcProviders = append(oidcProviders, oidc.NewLINEProvider(cfg.LINEClientID, cfg.LINEClientSecret, cfg.OIDCRedirectBaseURL+"/line"))
+	}
+	oidcService := services.NewOIDCService(db, redisClient, authService, oidcProviders...)
 
 	// 初始化控制器
 	authController := controllers.NewAuthController(authService)
-	ticketController := controllers.NewTicketController(ticketService)
+	ticketController := controllers.NewTicketController(ticketService, fingerprintService)
+	orderController := controllers.NewOrderController(orderService, invoiceService)
+	adminOrderController := controllers.NewAdminOrderController(orderService, ticketService)
+	adminEventController := controllers.NewAdminEventController(eventService)
+	adminUserController := controllers.NewAdminUserController(userService, deviceGraphService)
+	adminAPIKeyController := controllers.NewAdminAPIKeyController(apiKeyService)
+	walletController := controllers.NewWalletController(walletService)
+	userController := controllers.NewUserController(userService, orderService, ticketService)
+	mfaController := controllers.NewMFAController(authService.MFAService)
+	oidcController := controllers.NewOIDCController(oidcService)
 
 	// 公開路由
 	authRoutes := router.Group("/auth")
 	{
 		authRoutes.POST("/register", authController.Register)
 		authRoutes.POST("/login", authController.Login)
+		authRoutes.POST("/login/mfa", authController.LoginMFA)
+		authRoutes.POST("/login/mfa/setup", authController.LoginMFASetup)
+		authRoutes.GET("/oidc/:provider/authorize", oidcController.Authorize)
+		authRoutes.POST("/oidc/:provider/callback", oidcController.Callback)
 		authRoutes.POST("/refresh", authController.RefreshToken)
+		authRoutes.POST("/forgot-password", authController.ForgotPassword)
+		authRoutes.POST("/reset-password", authController.ResetPassword)
+		authRoutes.POST("/verify-email", authController.VerifyEmail)
+		authRoutes.POST("/resend-verification", authController.ResendVerificationEmail)
+		authRoutes.POST("/confirm-email-change", userController.ConfirmEmailChange)
 	}
 
 	// 票券可用性檢查（公開路由）
@@ -43,21 +121,53 @@ func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, redisClient *redis.Cli
 		ticketRoutes.GET("/check-fingerprint/:ticket_type_id", ticketController.CheckFingerprint)
 	}
 
+	// Apple Wallet PassKit Web Service（以 ApplePass 令牌驗證）
+	walletRoutes := router.Group("/wallet/v1")
+	{
+		walletRoutes.POST("/devices/:device_id/registrations/:pass_type_id/:serial_number", walletController.RegisterDevice)
+		walletRoutes.DELETE("/devices/:device_id/registrations/:pass_type_id/:serial_number", walletController.UnregisterDevice)
+		walletRoutes.GET("/devices/:device_id/registrations/:pass_type_id", walletController.GetUpdatedSerialNumbers)
+		walletRoutes.GET("/passes/:pass_type_id/:serial_number", walletController.GetLatestPass)
+		walletRoutes.POST("/log", walletController.Log)
+	}
+
+	// API 金鑰僅能存取以 apiKeyRoutes.Handle 登記權限範圍的路由
+	apiKeyRoutes := middleware.NewAPIKeyRoutes()
+
 	// 需要認證的路由
 	authenticatedRoutes := router.Group("")
-	authenticatedRoutes.Use(middleware.AuthRequired())
+	authenticatedRoutes.Use(middleware.AuthRequired(db, signingKeys, apiKeyRoutes), middleware.RateLimit(rateLimitService))
 	{
 		// 認證相關路由
 		authRoutes := authenticatedRoutes.Group("/auth")
 		{
 			authRoutes.GET("/me", authController.Me)
 			authRoutes.POST("/logout", authController.Logout)
+			authRoutes.POST("/verify-email/resend", authController.ResendMyVerificationEmail)
+			authRoutes.GET("/sessions", authController.ListSessions)
+			authRoutes.DELETE("/sessions/:id", authController.RevokeSession)
+			authRoutes.POST("/mfa/setup", mfaController.Setup)
+			authRoutes.POST("/mfa/enable", mfaController.Enable)
+			authRoutes.POST("/mfa/disable", mfaController.Disable)
+			authRoutes.POST("/mfa/recovery-codes", mfaController.RegenerateRecoveryCodes)
 		}
 
-		// 用戶相關路由 (後續添加)
+		// 用戶相關路由
 		userRoutes := authenticatedRoutes.Group("/users")
 		{
-			// TODO: 添加用戶相關路由
+			userRoutes.GET("/me", userController.GetProfile)
+			userRoutes.PUT("/me", userController.UpdateProfile)
+			userRoutes.DELETE("/me", userController.DeleteAccount)
+			userRoutes.POST("/me/email", userController.ChangeEmail)
+			userRoutes.PUT("/me/password", userController.ChangePassword)
+			userRoutes.GET("/me/export", userController.ExportData)
+			userRoutes.GET("/me/orders", userController.GetMyOrders)
+			userRoutes.GET("/me/orders/:id", userController.GetMyOrder)
+			userRoutes.GET("/me/tickets", userController.GetMyTickets)
+			userRoutes.GET("/me/identities", oidcController.ListIdentities)
+			userRoutes.GET("/me/identities/:provider/authorize", oidcController.AuthorizeLink)
+			userRoutes.POST("/me/identities/:provider", oidcController.Link)
+			userRoutes.DELETE("/me/identities/:provider", oidcController.Unlink)
 		}
 
 		// 活動相關路由 (後續添加)
@@ -75,38 +185,70 @@ func RegisterRoutes(router *gin.RouterGroup, db *gorm.DB, redisClient *redis.Cli
 		// 訂單相關路由 (後續添加)
 		orderRoutes := authenticatedRoutes.Group("/orders")
 		{
-			// 購買票券 (添加使用者限流中間件)
-			orderRoutes.Use(middleware.UserRateLimiter(redisClient))
-			
-			// TODO: 添加訂單相關路由
+			// 購買票券 (使用者限流由 RateLimit 政策處理，記錄購票裝置，要求已驗證電子郵件，評估機器人風險並要求 CAPTCHA 與工作量證明，最後依票種政策限制同一裝置的購買次數)
+			orderRoutes.Use(middleware.RecordDevice(deviceGraphService))
+			orderRoutes.POST("",
+				middleware.EmailVerificationRequired(db),
+				middleware.RiskCheck(riskService),
+				middleware.CaptchaCheck(captchaService),
+				middleware.ProofOfWork(powService),
+				middleware.CheckTLSFingerprint(fingerprintService),
+				orderController.CreateOrder,
+			)
+
+			// 發票與折讓單下載
+			orderRoutes.GET("/:id/invoice", orderController.GetInvoice)
+			orderRoutes.GET("/:id/credit-note", orderController.GetCreditNote)
 		}
 
 		// 票券相關路由
 		ticketAuthRoutes := authenticatedRoutes.Group("/tickets")
 		{
-			ticketAuthRoutes.GET("/validate/:ticket_code", ticketController.ValidateTicket)
-			ticketAuthRoutes.POST("/use/:ticket_code", ticketController.UseTicket)
+			apiKeyRoutes.Handle(ticketAuthRoutes, http.MethodGet, "/validate/:ticket_code", models.PermissionTicketUse, ticketController.ValidateTicket)
+			apiKeyRoutes.Handle(ticketAuthRoutes, http.MethodPost, "/use/:ticket_code", models.PermissionTicketUse, ticketController.UseTicket)
+			ticketAuthRoutes.GET("/:id/pdf", ticketController.GetTicketPDF)
+			ticketAuthRoutes.GET("/:id/wallet/apple", walletController.GetApplePass)
+			ticketAuthRoutes.GET("/:id/wallet/google", walletController.GetGoogleSaveLink)
 		}
 	}
 
-	// 需要管理員權限的路由
+	// 管理後台路由，依角色權限控管
 	adminRoutes := router.Group("")
-	adminRoutes.Use(middleware.AuthRequired(), middleware.AdminRequired())
+	adminRoutes.Use(middleware.AuthRequired(db, signingKeys, apiKeyRoutes), middleware.RateLimit(rateLimitService))
 	{
-		// 管理員相關路由 (後續添加)
+		// 管理員活動路由，主辦單位僅能管理自己建立的活動
 		adminEventRoutes := adminRoutes.Group("/admin/events")
 		{
-			// TODO: 添加管理員活動相關路由
+			apiKeyRoutes.Handle(adminEventRoutes, http.MethodGet, "", models.PermissionEventRead, adminEventController.GetEvents)
+			apiKeyRoutes.Handle(adminEventRoutes, http.MethodPost, "", models.PermissionEventWrite, adminEventController.CreateEvent)
+			apiKeyRoutes.Handle(adminEventRoutes, http.MethodPut, "/:id", models.PermissionEventWrite, adminEventController.UpdateEvent)
+			apiKeyRoutes.Handle(adminEventRoutes, http.MethodDelete, "/:id", models.PermissionEventWrite, adminEventController.DeleteEvent)
+			apiKeyRoutes.Handle(adminEventRoutes, http.MethodPost, "/:id/ticket-types", models.PermissionEventWrite, adminEventController.CreateTicketType)
 		}
 
 		adminUserRoutes := adminRoutes.Group("/admin/users")
 		{
-			// TODO: 添加管理員用戶相關路由
+			apiKeyRoutes.Handle(adminUserRoutes, http.MethodGet, "/:id", models.PermissionUserRead, adminUserController.GetUser)
+			apiKeyRoutes.Handle(adminUserRoutes, http.MethodPut, "/:id/role", models.PermissionUserManage, adminUserController.UpdateRole)
+			apiKeyRoutes.Handle(adminUserRoutes, http.MethodGet, "/:id/device-cluster", models.PermissionUserRead, adminUserController.GetDeviceCluster)
+		}
+		apiKeyRoutes.Handle(adminRoutes, http.MethodGet, "/admin/device-links/shared", models.PermissionUserRead, adminUserController.ListSharedIdentifiers)
+
+		// 服務帳號與 API 金鑰管理，不開放以 API 金鑰存取
+		adminRoutes.POST("/admin/service-accounts", middleware.RequirePermission(models.PermissionAPIKeyManage), adminAPIKeyController.CreateServiceAccount)
+		adminAPIKeyRoutes := adminRoutes.Group("/admin/api-keys")
+		adminAPIKeyRoutes.Use(middleware.RequirePermission(models.PermissionAPIKeyManage))
+		{
+			adminAPIKeyRoutes.GET("", adminAPIKeyController.ListKeys)
+			adminAPIKeyRoutes.POST("", adminAPIKeyController.IssueKey)
+			adminAPIKeyRoutes.DELETE("/:id", adminAPIKeyController.RevokeKey)
 		}
 
 		adminOrderRoutes := adminRoutes.Group("/admin/orders")
 		{
-			// TODO: 添加管理員訂單相關路由
+			apiKeyRoutes.Handle(adminOrderRoutes, http.MethodPost, "/:id/pay", models.PermissionOrderPayment, adminOrderController.MarkOrderPaid)
+			apiKeyRoutes.Handle(adminOrderRoutes, http.MethodPost, "/:id/refund", models.PermissionOrderRefund, adminOrderController.RefundOrder)
+			apiKeyRoutes.Handle(adminOrderRoutes, http.MethodGet, "/:id/tickets/pdf", models.PermissionOrderRead, adminOrderController.ExportTicketsPDF)
 		}
 	}
 }
\ No newline at end of file
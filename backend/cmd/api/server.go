@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,8 +15,15 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/controllers"
 	"github.com/lipeichen/ticket-getter/internal/middleware"
 	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/captcha"
+	"github.com/lipeichen/ticket-getter/pkg/clientip"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+	"github.com/lipeichen/ticket-getter/pkg/pow"
+	"github.com/lipeichen/ticket-getter/pkg/tlsfp"
 	"github.com/redis/go-redis/v9"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -30,6 +39,11 @@ func Run() {
 	// 設置生產模式
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
+
+		// JWT_SECRET 仍用於電子郵件驗證簽章等 HMAC，生產環境不可使用預設值
+		if cfg.JWTSecret == "your-secret-key" {
+			log.Fatal("生產環境必須設定 JWT_SECRET")
+		}
 	}
 
 	// 連接數據庫
@@ -56,14 +70,41 @@ func Run() {
 		log.Printf("無法連接到 Redis: %v，繼續啟動服務但部分功能可能受限", err)
 	}
 
+	// 載入 JWT 簽章金鑰
+	signingKeys, err := loadSigningKeys(cfg)
+	if err != nil {
+		log.Fatalf("無法載入 JWT 簽章金鑰: %v", err)
+	}
+
+	// 可信任的反向代理，用於解析真實客戶端 IP
+	ipResolver, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("無效的 TRUSTED_PROXIES 設定: %v", err)
+	}
+
+	// 限流政策，設定政策檔時定期檢查變更並重新載入
+	rateLimitService, err := services.NewRateLimitService(redisClient, cfg)
+	if err != nil {
+		log.Fatalf("無法載入限流政策: %v", err)
+	}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go rateLimitService.Policies.Watch(watchCtx, time.Duration(cfg.RateLimitReloadSeconds)*time.Second)
+
 	// 創建 Gin 引擎
 	router := gin.Default()
 
+	// 客戶端 IP 統一由 ClientIP 中間件解析，gin 本身不採用任何轉發標頭
+	if err := router.SetTrustedProxies(nil); err != nil {
+		log.Fatalf("設定可信任代理失敗: %v", err)
+	}
+	router.Use(middleware.ClientIP(ipResolver))
+
 	// CORS 設定
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.FrontendURL},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", captcha.TokenHeader, pow.SolutionHeader},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -71,7 +112,8 @@ func Run() {
 
 	// 設置中間件
 	router.Use(middleware.Logger())
-	router.Use(middleware.RateLimiter(redisClient))
+	router.Use(middleware.TLSFingerprint())
+	router.Use(middleware.RateLimit(rateLimitService))
 
 	// API 版本前綴
 	apiV1 := router.Group("/api/v1")
@@ -81,11 +123,18 @@ func Run() {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ok",
 			"time":   time.Now().Format(time.RFC3339),
+			"rate_limit": gin.H{
+				"mode":    rateLimitService.Mode(),
+				"circuit": rateLimitService.Breaker.State(),
+			},
 		})
 	})
 	
 	// 註冊路由
-	RegisterRoutes(apiV1, db, redisClient)
+	RegisterRoutes(apiV1, db, redisClient, signingKeys, rateLimitService)
+
+	// 公開 JWT 驗證公鑰，供其他服務獨立驗證令牌
+	router.GET("/.well-known/jwks.json", controllers.NewJWKSController(signingKeys).GetJWKS)
 
 	// Swagger 文檔
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -99,7 +148,7 @@ func Run() {
 	// 在 goroutine 中啟動服務器
 	go func() {
 		log.Printf("服務器啟動於 :%s 端口\n", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serve(srv, cfg); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("服務器啟動失敗: %v\n", err)
 		}
 	}()
@@ -121,6 +170,38 @@ func Run() {
 	log.Println("服務器已優雅關閉")
 }
 
+// serve 啟動服務器；設定 TLS 憑證時直接終止 TLS，以便從 ClientHello 計算 JA3/JA4 指紋
+func serve(srv *http.Server, cfg *config.Config) error {
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return srv.ListenAndServe()
+	}
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = tlsfp.WrapTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	srv.ConnContext = tlsfp.ConnContext
+	return srv.ServeTLS(tlsfp.NewListener(ln), cfg.TLSCertFile, cfg.TLSKeyFile)
+}
+
+// loadSigningKeys 從 JWT_KEYS_DIR 載入簽章金鑰；非生產環境未設定時產生臨時金鑰
+func loadSigningKeys(cfg *config.Config) (*jwtkeys.KeySet, error) {
+	if cfg.JWTKeysDir != "" {
+		return jwtkeys.LoadDir(cfg.JWTKeysDir, cfg.JWTActiveKeyID)
+	}
+	if cfg.Environment == "production" {
+		return nil, fmt.Errorf("生產環境必須設定 JWT_KEYS_DIR")
+	}
+
+	log.Println("未設定 JWT_KEYS_DIR，使用臨時 Ed25519 金鑰，重新啟動後既有令牌將失效")
+	key, err := jwtkeys.GenerateEd25519(fmt.Sprintf("dev-%d", time.Now().Unix()))
+	if err != nil {
+		return nil, err
+	}
+	return jwtkeys.NewKeySet(key.ID, key)
+}
+
 // connectDB 連接到 PostgreSQL 數據庫
 func connectDB(cfg *config.Config) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
@@ -153,6 +234,19 @@ func autoMigrateDB(db *gorm.DB) {
 		&models.Order{},
 		&models.OrderItem{},
 		&models.Ticket{},
+		&models.InvoiceSequence{},
+		&models.Invoice{},
+		&models.InvoiceItem{},
+		&models.WalletPass{},
+		&models.WalletRegistration{},
+		&models.UserToken{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.MFARecoveryCode{},
+		&models.UserIdentity{},
+		&models.LoginAttempt{},
+		&models.DeviceLink{},
+		&models.APIKey{},
 	)
 	
 	if err != nil {
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config 應用程式配置結構
@@ -11,10 +13,90 @@ type Config struct {
 	DatabaseURL    string
 	JWTSecret      string
 	JWTExpiryHours int
+	JWTKeysDir     string // JWT 簽章金鑰目錄（PEM），檔名即為 kid
+	JWTActiveKeyID string // 用於簽章的 kid，其餘金鑰僅供驗證
+	TLSCertFile    string // 設定憑證與私鑰時直接以 HTTPS 提供服務，並計算 JA3/JA4 指紋
+	TLSKeyFile     string
+	TrustedProxies []string // 可信任的反向代理（CIDR 或 IP），僅採用來自這些位址的轉發標頭
 	RedisHost      string
 	RedisPort      string
 	RedisPassword  string
 	FrontendURL    string
+
+	// 發票與 PDF 相關設定
+	PDFFontPath          string
+	TicketBrandName      string
+	InvoiceIssuerName    string
+	InvoiceIssuerTaxID   string
+	InvoiceIssuerAddress string
+	InvoiceTaxRate       float64
+	InvoiceServiceFee    float64
+
+	// 郵件寄送設定
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// 兩步驟驗證設定
+	MFAIssuer            string
+	MFAEncryptionKey     string
+	MFARequiredForAdmins bool
+
+	// 第三方登入（OIDC）設定
+	OIDCRedirectBaseURL string
+	GoogleClientID      string
+	GoogleClientSecret  string
+	LINEClientID        string
+	LINEClientSecret    string
+
+	// 電子錢包票券設定
+	AppleWalletPassTypeID    string
+	AppleWalletTeamID        string
+	AppleWalletCertPath      string
+	AppleWalletKeyPath       string
+	AppleWalletWWDRPath      string
+	AppleWalletAssetsDir     string
+	AppleWalletWebServiceURL string
+	APNsURL                  string
+	GoogleWalletIssuerID     string
+	GoogleWalletKeyPath      string
+
+	// 購票風險評估設定
+	RiskIPReputationFiles []string // IP 信譽名單檔案，每行一個 IP 或 CIDR
+	RiskChallengeScore    int      // 風險分數達此值時要求額外驗證
+	RiskBlockScore        int      // 風險分數達此值時拒絕請求
+
+	// 購票工作量證明設定
+	POWSecret         string // 挑戰簽章金鑰，多台伺服器須一致
+	POWBaseDifficulty int    // 基本難度（SHA-256 前導零位元數）
+	POWMaxDifficulty  int    // 依負載與風險提高後的難度上限
+	POWLoadThreshold  int    // 每 10 秒購票請求超過此數時開始提高難度
+
+	// CAPTCHA 設定
+	CaptchaProvider           string  // hcaptcha、turnstile、recaptcha 或 fake（僅限非生產環境），空白表示停用
+	CaptchaSecret             string
+	CaptchaMinScore           float64 // reCAPTCHA v3 最低分數
+	CaptchaOnRegister         bool    // 註冊時要求 CAPTCHA
+	CaptchaLoginAfterFailures int     // 帳號登入失敗達此次數後要求 CAPTCHA，0 表示不要求
+	CaptchaOnPurchase         bool    // 購票一律要求 CAPTCHA；關閉時僅於風險評估為 challenge 時要求
+
+	// 多帳號裝置關聯設定
+	PurchaseLimitPerCluster bool // 購票頻率限制以共用裝置、IP 或付款方式的帳號群組為單位，而非單一帳號
+
+	// 限流政策設定
+	RateLimitPolicyFile    string // YAML 或 JSON 政策檔，空白時使用內建政策
+	RateLimitReloadSeconds int    // 檢查政策檔變更的間隔秒數，0 表示不重新載入
+	RateLimitDryRun        bool   // 所有政策只記錄將被拒絕的請求，不實際拒絕
+
+	// Redis 故障時的程序內限流設定
+	RateLimitBreakerThreshold       int // Redis 連續失敗幾次後改用程序內限流
+	RateLimitBreakerCooldownSeconds int // 改用程序內限流後，隔多久再探測 Redis 是否恢復
+	RateLimitFallbackInstances      int // 服務器數量，程序內限流的限制數為政策限制數除以此值
+
+	// 裝置指紋防重複購買設定
+	FingerprintAllowlist []string // 不受指紋購買次數限制的售票端 IP 或 CIDR（如現場售票亭）
 }
 
 // LoadConfig 從環境變數載入配置
@@ -48,10 +130,78 @@ func LoadConfig() *Config {
 		DatabaseURL:    getEnv("DATABASE_URL", ""),
 		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key"),
 		JWTExpiryHours: jwtExpiry,
+		JWTKeysDir:     getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKeyID: getEnv("JWT_ACTIVE_KEY_ID", ""),
+		TLSCertFile:    getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:     getEnv("TLS_KEY_FILE", ""),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		RedisHost:      redisHost,
 		RedisPort:      redisPort,
 		RedisPassword:  getEnv("REDIS_PASSWORD", ""),
 		FrontendURL:    frontendURL,
+
+		PDFFontPath:          getEnv("PDF_FONT_PATH", ""),
+		TicketBrandName:      getEnv("TICKET_BRAND_NAME", "Ticker-Getter"),
+		InvoiceIssuerName:    getEnv("INVOICE_ISSUER_NAME", "Ticker-Getter"),
+		InvoiceIssuerTaxID:   getEnv("INVOICE_ISSUER_TAX_ID", ""),
+		InvoiceIssuerAddress: getEnv("INVOICE_ISSUER_ADDRESS", ""),
+		InvoiceTaxRate:       getEnvFloat("INVOICE_TAX_RATE", 0.05),
+		InvoiceServiceFee:    getEnvFloat("INVOICE_SERVICE_FEE", 0),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@ticket-getter.local"),
+
+		MFAIssuer:            getEnv("MFA_ISSUER", "Ticket-Getter"),
+		MFAEncryptionKey:     getEnv("MFA_ENCRYPTION_KEY", ""),
+		MFARequiredForAdmins: getEnvBool("MFA_REQUIRED_FOR_ADMINS", true),
+
+		OIDCRedirectBaseURL: getEnv("OIDC_REDIRECT_BASE_URL", frontendURL+"/auth/callback"),
+		GoogleClientID:      getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:  getEnv("GOOGLE_CLIENT_SECRET", ""),
+		LINEClientID:        getEnv("LINE_CLIENT_ID", ""),
+		LINEClientSecret:    getEnv("LINE_CLIENT_SECRET", ""),
+
+		AppleWalletPassTypeID:    getEnv("APPLE_WALLET_PASS_TYPE_ID", ""),
+		AppleWalletTeamID:        getEnv("APPLE_WALLET_TEAM_ID", ""),
+		AppleWalletCertPath:      getEnv("APPLE_WALLET_CERT_PATH", ""),
+		AppleWalletKeyPath:       getEnv("APPLE_WALLET_KEY_PATH", ""),
+		AppleWalletWWDRPath:      getEnv("APPLE_WALLET_WWDR_PATH", ""),
+		AppleWalletAssetsDir:     getEnv("APPLE_WALLET_ASSETS_DIR", ""),
+		AppleWalletWebServiceURL: getEnv("APPLE_WALLET_WEB_SERVICE_URL", ""),
+		APNsURL:                  getEnv("APNS_URL", ""),
+		GoogleWalletIssuerID:     getEnv("GOOGLE_WALLET_ISSUER_ID", ""),
+		GoogleWalletKeyPath:      getEnv("GOOGLE_WALLET_KEY_PATH", ""),
+
+		RiskIPReputationFiles: getEnvList("RISK_IP_REPUTATION_FILES"),
+		RiskChallengeScore:    getEnvInt("RISK_CHALLENGE_SCORE", 40),
+		RiskBlockScore:        getEnvInt("RISK_BLOCK_SCORE", 80),
+
+		POWSecret:         getEnv("POW_SECRET", ""),
+		POWBaseDifficulty: getEnvInt("POW_BASE_DIFFICULTY", 16),
+		POWMaxDifficulty:  getEnvInt("POW_MAX_DIFFICULTY", 24),
+		POWLoadThreshold:  getEnvInt("POW_LOAD_THRESHOLD", 100),
+
+		CaptchaProvider:           getEnv("CAPTCHA_PROVIDER", ""),
+		CaptchaSecret:             getEnv("CAPTCHA_SECRET", ""),
+		CaptchaMinScore:           getEnvFloat("CAPTCHA_MIN_SCORE", 0.5),
+		CaptchaOnRegister:         getEnvBool("CAPTCHA_ON_REGISTER", true),
+		CaptchaLoginAfterFailures: getEnvInt("CAPTCHA_LOGIN_AFTER_FAILURES", 3),
+		CaptchaOnPurchase:         getEnvBool("CAPTCHA_ON_PURCHASE", false),
+
+		PurchaseLimitPerCluster: getEnvBool("PURCHASE_LIMIT_PER_CLUSTER", false),
+
+		RateLimitPolicyFile:    getEnv("RATE_LIMIT_POLICY_FILE", ""),
+		RateLimitReloadSeconds: getEnvInt("RATE_LIMIT_RELOAD_SECONDS", 10),
+		RateLimitDryRun:        getEnvBool("RATE_LIMIT_DRY_RUN", false),
+
+		RateLimitBreakerThreshold:       getEnvInt("RATE_LIMIT_BREAKER_THRESHOLD", 3),
+		RateLimitBreakerCooldownSeconds: getEnvInt("RATE_LIMIT_BREAKER_COOLDOWN_SECONDS", 10),
+		RateLimitFallbackInstances:      getEnvInt("RATE_LIMIT_FALLBACK_INSTANCES", 1),
+
+		FingerprintAllowlist: getEnvList("FINGERPRINT_ALLOWLIST"),
 	}
 }
 
@@ -63,3 +213,41 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt 獲取整數環境變數，若不存在或格式錯誤則返回默認值
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat 獲取浮點數環境變數，若不存在或格式錯誤則返回默認值
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvBool 獲取布林環境變數，若不存在或格式錯誤則返回默認值
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList 獲取以逗號分隔的環境變數，忽略空白項目
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,27 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE IF NOT EXISTS wallet_passes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    ticket_id UUID NOT NULL UNIQUE REFERENCES tickets(id),
+    serial_number VARCHAR(100) NOT NULL UNIQUE,
+    authentication_token VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS wallet_registrations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    device_library_identifier VARCHAR(255) NOT NULL,
+    serial_number VARCHAR(100) NOT NULL,
+    push_token VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_registrations_device_serial ON wallet_registrations(device_library_identifier, serial_number);
+CREATE INDEX IF NOT EXISTS idx_wallet_registrations_serial_number ON wallet_registrations(serial_number);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE IF EXISTS wallet_registrations;
+DROP TABLE IF EXISTS wallet_passes;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS fingerprint_window_minutes INTEGER NOT NULL DEFAULT 1440;
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS fingerprint_max_purchases INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE ticket_types ADD COLUMN IF NOT EXISTS fingerprint_scope VARCHAR(20) NOT NULL DEFAULT 'ticket_type';
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE ticket_types DROP COLUMN IF EXISTS fingerprint_scope;
+ALTER TABLE ticket_types DROP COLUMN IF EXISTS fingerprint_max_purchases;
+ALTER TABLE ticket_types DROP COLUMN IF EXISTS fingerprint_window_minutes;
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// AdminAPIKeyController 處理服務帳號與 API 金鑰管理相關 HTTP 請求
+type AdminAPIKeyController struct {
+	APIKeyService *services.APIKeyService
+}
+
+// NewAdminAPIKeyController 創建新的 AdminAPIKeyController 實例
+func NewAdminAPIKeyController(apiKeyService *services.APIKeyService) *AdminAPIKeyController {
+	return &AdminAPIKeyController{
+		APIKeyService: apiKeyService,
+	}
+}
+
+// CreateServiceAccount 建立服務帳號
+// @Summary 建立服務帳號
+// @Description 為票務中心或經銷商等系統整合建立服務帳號，服務帳號只能以 API 金鑰存取
+// @Tags 管理員-API 金鑰
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateServiceAccountRequest true "服務帳號資訊"
+// @Success 201 {object} vo.UserResponse "建立成功"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/service-accounts [post]
+func (c *AdminAPIKeyController) CreateServiceAccount(ctx *gin.Context) {
+	var req dto.CreateServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	account, err := c.APIKeyService.CreateServiceAccount(req)
+	if err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, account)
+}
+
+// ListKeys 列出 API 金鑰
+// @Summary API 金鑰列表
+// @Description 列出 API 金鑰，可依擁有者篩選；不會返回金鑰本身
+// @Tags 管理員-API 金鑰
+// @Produce json
+// @Param user_id query string false "擁有者 ID"
+// @Success 200 {object} map[string]interface{} "API 金鑰列表"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/api-keys [get]
+func (c *AdminAPIKeyController) ListKeys(ctx *gin.Context) {
+	var params dto.APIKeyQueryParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	keys, err := c.APIKeyService.ListKeys(params.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取 API 金鑰失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// IssueKey 簽發 API 金鑰
+// @Summary 簽發 API 金鑰
+// @Description 為用戶或服務帳號簽發 API 金鑰，以 `Authorization: ApiKey <金鑰>` 呼叫 API；金鑰僅在此回應中出現一次
+// @Tags 管理員-API 金鑰
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateAPIKeyRequest true "API 金鑰資訊"
+// @Success 201 {object} vo.APIKeyCreatedResponse "簽發成功"
+// @Failure 400 {object} map[string]string "無效的輸入或權限範圍"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "使用者不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/api-keys [post]
+func (c *AdminAPIKeyController) IssueKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	key, err := c.APIKeyService.IssueKey(userIDStr, req)
+	if err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// RevokeKey 撤銷 API 金鑰
+// @Summary 撤銷 API 金鑰
+// @Description 撤銷 API 金鑰，立即生效
+// @Tags 管理員-API 金鑰
+// @Produce json
+// @Param id path string true "API 金鑰 ID"
+// @Success 200 {object} vo.BaseResponse "已撤銷"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "API 金鑰不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/api-keys/{id} [delete]
+func (c *AdminAPIKeyController) RevokeKey(ctx *gin.Context) {
+	if err := c.APIKeyService.RevokeKey(ctx.Param("id")); err != nil {
+		respondAPIKeyError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "API 金鑰已撤銷"})
+}
+
+// respondAPIKeyError 將 API 金鑰管理錯誤轉換為 HTTP 回應
+func respondAPIKeyError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "無效的角色", "服務帳號不可為管理員", "無效的權限範圍", "到期時間必須晚於現在":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "使用者不存在", "API 金鑰不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "API 金鑰操作失敗"})
+	}
+}
@@ -24,7 +24,7 @@ func NewAdminEventController(eventService *services.EventService) *AdminEventCon
 
 // GetEvents 獲取所有事件
 // @Summary 管理員獲取事件列表
-// @Description 管理員獲取所有事件的分頁列表
+// @Description 具活動查詢權限的角色獲取所有事件的分頁列表
 // @Tags 管理員-事件
 // @Accept json
 // @Produce json
@@ -59,7 +59,7 @@ func (c *AdminEventController) GetEvents(ctx *gin.Context) {
 
 // CreateEvent 創建新事件
 // @Summary 創建事件
-// @Description 管理員創建新事件
+// @Description 管理員或主辦單位創建新事件
 // @Tags 管理員-事件
 // @Accept json
 // @Produce json
@@ -119,7 +119,7 @@ func (c *AdminEventController) CreateEvent(ctx *gin.Context) {
 
 // UpdateEvent 更新事件
 // @Summary 更新事件
-// @Description 管理員更新現有事件
+// @Description 更新現有事件，主辦單位僅能更新自己建立的事件
 // @Tags 管理員-事件
 // @Accept json
 // @Produce json
@@ -164,9 +164,10 @@ func (c *AdminEventController) UpdateEvent(ctx *gin.Context) {
 		EndTime:     req.EndTime,
 	}
 
-	updatedEvent, err := c.EventService.UpdateEvent(&event)
+	userIDStr, roleStr := actor(ctx)
+	updatedEvent, err := c.EventService.UpdateEvent(&event, userIDStr, roleStr)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新事件失敗"})
+		respondEventError(ctx, err, "更新事件失敗")
 		return
 	}
 
@@ -175,7 +176,7 @@ func (c *AdminEventController) UpdateEvent(ctx *gin.Context) {
 
 // DeleteEvent 刪除事件
 // @Summary 刪除事件
-// @Description 管理員刪除事件
+// @Description 刪除事件，主辦單位僅能刪除自己建立的事件
 // @Tags 管理員-事件
 // @Accept json
 // @Produce json
@@ -204,8 +205,9 @@ func (c *AdminEventController) DeleteEvent(ctx *gin.Context) {
 	}
 
 	// 刪除事件
-	if err := c.EventService.DeleteEvent(id); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "刪除事件失敗"})
+	userIDStr, roleStr := actor(ctx)
+	if err := c.EventService.DeleteEvent(id, userIDStr, roleStr); err != nil {
+		respondEventError(ctx, err, "刪除事件失敗")
 		return
 	}
 
@@ -214,7 +216,7 @@ func (c *AdminEventController) DeleteEvent(ctx *gin.Context) {
 
 // CreateTicketType 為事件創建票種
 // @Summary 創建票種
-// @Description 為指定事件創建新票種
+// @Description 為指定事件創建新票種，主辦單位僅能為自己建立的事件新增票種
 // @Tags 管理員-票種
 // @Accept json
 // @Produce json
@@ -255,6 +257,20 @@ func (c *AdminEventController) CreateTicketType(ctx *gin.Context) {
 		availableQuantity = req.TotalQuantity
 	}
 
+	// 未提供指紋政策時採用預設值：24 小時內每個裝置限購 1 次、以票種計算
+	fingerprintWindow := req.FingerprintWindowMinutes
+	if fingerprintWindow == 0 {
+		fingerprintWindow = 24 * 60
+	}
+	fingerprintMaxPurchases := 1
+	if req.FingerprintMaxPurchases != nil {
+		fingerprintMaxPurchases = *req.FingerprintMaxPurchases
+	}
+	fingerprintScope := req.FingerprintScope
+	if fingerprintScope == "" {
+		fingerprintScope = models.FingerprintScopeTicketType
+	}
+
 	// 創建票種模型
 	ticketType := models.TicketType{
 		EventID:          eventID,
@@ -264,14 +280,39 @@ func (c *AdminEventController) CreateTicketType(ctx *gin.Context) {
 		AvailableQuantity: availableQuantity,
 		SaleStart:        req.SaleStart,
 		SaleEnd:          req.SaleEnd,
+		FingerprintWindowMinutes: fingerprintWindow,
+		FingerprintMaxPurchases:  fingerprintMaxPurchases,
+		FingerprintScope:         fingerprintScope,
 	}
 
 	// 創建票種
-	createdTicketType, err := c.EventService.CreateTicketType(&ticketType)
+	userIDStr, roleStr := actor(ctx)
+	createdTicketType, err := c.EventService.CreateTicketType(&ticketType, userIDStr, roleStr)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "創建票種失敗"})
+		respondEventError(ctx, err, "創建票種失敗")
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, createdTicketType)
 }
+
+// actor 從上下文取得當前用戶 ID 與角色
+func actor(ctx *gin.Context) (string, string) {
+	userID, _ := ctx.Get("userID")
+	role, _ := ctx.Get("role")
+	userIDStr, _ := userID.(string)
+	roleStr, _ := role.(string)
+	return userIDStr, roleStr
+}
+
+// respondEventError 將事件管理錯誤轉換為 HTTP 回應
+func respondEventError(ctx *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "事件不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "無權管理此事件":
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
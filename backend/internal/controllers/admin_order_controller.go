@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// AdminOrderController 處理管理員訂單相關 HTTP 請求
+type AdminOrderController struct {
+	OrderService  *services.OrderService
+	TicketService *services.TicketService
+}
+
+// NewAdminOrderController 創建新的 AdminOrderController 實例
+func NewAdminOrderController(orderService *services.OrderService, ticketService *services.TicketService) *AdminOrderController {
+	return &AdminOrderController{
+		OrderService:  orderService,
+		TicketService: ticketService,
+	}
+}
+
+// RefundOrder 退款訂單
+// @Summary 退款訂單
+// @Description 管理員將已付款訂單退款，作廢票券並開立折讓單
+// @Tags 管理員-訂單
+// @Accept json
+// @Produce json
+// @Param id path string true "訂單 ID"
+// @Success 200 {object} vo.InvoiceResponse "折讓單"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "訂單不存在"
+// @Failure 409 {object} map[string]string "訂單無法退款"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/orders/{id}/refund [post]
+func (c *AdminOrderController) RefundOrder(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的訂單 ID"})
+		return
+	}
+
+	creditNote, err := c.OrderService.RefundOrder(orderID)
+	if err != nil {
+		switch err.Error() {
+		case "訂單不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "訂單已退款", "訂單尚未付款，無法退款":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("退款訂單失敗: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "退款失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toInvoiceResponse(creditNote))
+}
+
+// MarkOrderPaid 確認訂單收款
+// @Summary 確認訂單收款
+// @Description 管理員或財務確認待付款訂單已收款，產生票券並以收款時間開立發票；提供付款方式指紋時記錄為帳號群組關聯
+// @Tags 管理員-訂單
+// @Accept json
+// @Produce json
+// @Param id path string true "訂單 ID"
+// @Param request body dto.MarkOrderPaidRequest true "付款方式與付款方式指紋"
+// @Success 200 {object} vo.InvoiceResponse "發票"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "訂單不存在"
+// @Failure 409 {object} map[string]string "訂單已付款或已取消"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/orders/{id}/pay [post]
+func (c *AdminOrderController) MarkOrderPaid(ctx *gin.Context) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的訂單 ID"})
+		return
+	}
+
+	var req dto.MarkOrderPaidRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice, err := c.OrderService.MarkPaid(orderID, req.PaymentMethod, req.PaymentFingerprint)
+	if err != nil {
+		switch err.Error() {
+		case "訂單不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "訂單已付款", "訂單已取消，無法付款":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("確認訂單收款失敗: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "確認收款失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toInvoiceResponse(invoice))
+}
+
+// ExportTicketsPDF 匯出訂單所有票券
+// @Summary 匯出訂單票券
+// @Description 管理員將訂單的所有電子票券匯出為單一多頁 PDF
+// @Tags 管理員-訂單
+// @Produce application/pdf
+// @Param id path string true "訂單 ID"
+// @Success 200 {file} file "電子票券 PDF"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "訂單不存在或沒有票券"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/orders/{id}/tickets/pdf [get]
+func (c *AdminOrderController) ExportTicketsPDF(ctx *gin.Context) {
+	content, err := c.TicketService.GetOrderTicketsPDF(ctx.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "無效的訂單 ID", "訂單不存在", "訂單沒有票券":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "產生電子票券失敗"})
+		}
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=order-"+ctx.Param("id")+"-tickets.pdf")
+	ctx.Data(http.StatusOK, "application/pdf", content)
+}
+
+// toInvoiceResponse 將發票模型轉換為 VO
+func toInvoiceResponse(invoice *models.Invoice) vo.InvoiceResponse {
+	return vo.InvoiceResponse{
+		ID:            invoice.ID,
+		InvoiceNumber: invoice.InvoiceNumber,
+		Type:          invoice.Type,
+		OrderID:       invoice.OrderID,
+		Subtotal:      invoice.Subtotal,
+		FeeAmount:     invoice.FeeAmount,
+		TaxAmount:     invoice.TaxAmount,
+		TotalAmount:   invoice.TotalAmount,
+		IssuedAt:      invoice.IssuedAt,
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+)
+
+// AdminUserController 處理管理員用戶相關 HTTP 請求
+type AdminUserController struct {
+	UserService        *services.UserService
+	DeviceGraphService *services.DeviceGraphService
+}
+
+// NewAdminUserController 創建新的 AdminUserController 實例
+func NewAdminUserController(userService *services.UserService, deviceGraphService *services.DeviceGraphService) *AdminUserController {
+	return &AdminUserController{
+		UserService:        userService,
+		DeviceGraphService: deviceGraphService,
+	}
+}
+
+// GetUser 獲取用戶資料
+// @Summary 查詢用戶
+// @Description 客服或管理員查詢指定用戶的個人資料
+// @Tags 管理員-用戶
+// @Produce json
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} vo.UserResponse "用戶資料"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "使用者不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/users/{id} [get]
+func (c *AdminUserController) GetUser(ctx *gin.Context) {
+	user, err := c.UserService.GetProfile(ctx.Param("id"))
+	if err != nil {
+		respondAdminUserError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateRole 變更用戶角色
+// @Summary 變更用戶角色
+// @Description 管理員變更用戶角色（user、admin、organizer、gate_staff、finance、support），變更後該用戶需重新登入
+// @Tags 管理員-用戶
+// @Accept json
+// @Produce json
+// @Param id path string true "用戶 ID"
+// @Param request body dto.UpdateUserRoleRequest true "新角色"
+// @Success 200 {object} vo.UserResponse "變更成功"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "使用者不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/users/{id}/role [put]
+func (c *AdminUserController) UpdateRole(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.UpdateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	user, err := c.UserService.UpdateRole(userIDStr, ctx.Param("id"), req.Role)
+	if err != nil {
+		respondAdminUserError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetDeviceCluster 查詢用戶的裝置群組
+// @Summary 查詢裝置群組
+// @Description 列出與指定用戶共用裝置指紋、IP 或付款方式的帳號及其關聯，用於追查多帳號黃牛；僅採用近 30 天的關聯，被大量帳號共用的識別值（如電信業者 IP）不列入
+// @Tags 管理員-用戶
+// @Produce json
+// @Param id path string true "用戶 ID"
+// @Success 200 {object} vo.DeviceClusterResponse "裝置群組"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 404 {object} map[string]string "使用者不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/users/{id}/device-cluster [get]
+func (c *AdminUserController) GetDeviceCluster(ctx *gin.Context) {
+	cluster, err := c.DeviceGraphService.Cluster(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondAdminUserError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cluster)
+}
+
+// ListSharedIdentifiers 列出被多個帳號共用的識別值
+// @Summary 共用識別值列表
+// @Description 列出近 30 天內被多個帳號共用的裝置指紋、IP 或付款方式，依帳號數排序
+// @Tags 管理員-用戶
+// @Produce json
+// @Param kind query string false "類型（device、ip、payment）"
+// @Param min_accounts query int false "最少帳號數" default(2)
+// @Param limit query int false "筆數上限" default(50)
+// @Success 200 {object} map[string]interface{} "共用識別值列表"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未授權"
+// @Failure 403 {object} map[string]string "禁止訪問"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /admin/device-links/shared [get]
+func (c *AdminUserController) ListSharedIdentifiers(ctx *gin.Context) {
+	var params dto.SharedIdentifierQueryParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	identifiers, err := c.DeviceGraphService.SharedIdentifiers(ctx.Request.Context(), params.Kind, params.MinAccounts, params.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取共用識別值失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"identifiers": identifiers})
+}
+
+// respondAdminUserError 將用戶管理錯誤轉換為 HTTP 回應
+func respondAdminUserError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "無效的角色", "無法變更自己的角色":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "無效的使用者 ID", "使用者不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "使用者不存在"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "用戶管理操作失敗"})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lipeichen/ticket-getter/internal/dto"
 	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/utils"
 )
 
 // AuthController 處理認證相關 HTTP 請求
@@ -28,8 +30,9 @@ func NewAuthController(authService *services.AuthService) *AuthController {
 // @Produce json
 // @Param user body dto.RegisterRequest true "用戶註冊信息"
 // @Success 201 {object} vo.RegisterResponse "註冊成功"
-// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 400 {object} map[string]string "無效的輸入或 CAPTCHA 驗證失敗"
 // @Failure 409 {object} map[string]string "用戶已存在"
+// @Failure 428 {object} map[string]interface{} "需要完成 CAPTCHA 驗證"
 // @Failure 500 {object} map[string]string "內部服務器錯誤"
 // @Router /auth/register [post]
 func (c *AuthController) Register(ctx *gin.Context) {
@@ -40,8 +43,11 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		return
 	}
 	
-	response, err := c.AuthService.Register(req)
+	response, err := c.AuthService.Register(req, clientInfo(ctx))
 	if err != nil {
+		if respondCaptchaError(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -51,15 +57,18 @@ func (c *AuthController) Register(ctx *gin.Context) {
 
 // Login 處理用戶登入
 // @Summary 用戶登入
-// @Description 用戶登入並獲取認證令牌
+// @Description 用戶登入並獲取認證令牌；已啟用兩步驟驗證的帳號只會取得 mfa_token，需再呼叫 /auth/login/mfa。連續失敗會遞增等待時間，過多時暫時鎖定帳號與來源 IP
 // @Tags 認證
 // @Accept json
 // @Produce json
 // @Param credentials body dto.LoginRequest true "用戶登入憑證"
 // @Success 200 {object} vo.LoginResponse "登入成功"
 // @Failure 400 {object} map[string]string "無效的輸入"
-// @Failure 401 {object} map[string]string "登入失敗"
+// @Failure 401 {object} map[string]string "電子郵件或密碼不正確"
+// @Failure 428 {object} map[string]interface{} "登入失敗次數達門檻，需要完成 CAPTCHA 驗證"
+// @Failure 429 {object} map[string]string "登入失敗次數過多"
 // @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Failure 503 {object} map[string]string "無法確認鎖定狀態，暫時停止登入"
 // @Router /auth/login [post]
 func (c *AuthController) Login(ctx *gin.Context) {
 	var req dto.LoginRequest
@@ -69,9 +78,81 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 	
-	response, err := c.AuthService.Login(req)
+	response, err := c.AuthService.Login(req, clientInfo(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		switch err.Error() {
+		case "電子郵件或密碼不正確":
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case "登入失敗次數過多，請稍後再試":
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case "登入服務暫時無法使用，請稍後再試":
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			if respondCaptchaError(ctx, err) {
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "登入失敗"})
+		}
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, response)
+}
+
+// LoginMFA 完成兩步驟驗證登入
+// @Summary 兩步驟驗證登入
+// @Description 以登入取得的 mfa_token 搭配驗證碼或復原碼完成登入；尚未設定的管理員帳號在此完成啟用並取得復原碼
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.MFALoginRequest true "驗證請求令牌與驗證碼"
+// @Success 200 {object} vo.LoginResponse "登入成功"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "驗證碼不正確或驗證請求已過期"
+// @Failure 429 {object} map[string]string "請求過於頻繁"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/login/mfa [post]
+func (c *AuthController) LoginMFA(ctx *gin.Context) {
+	var req dto.MFALoginRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	response, err := c.AuthService.CompleteMFALogin(ctx.Request.Context(), req, clientInfo(ctx))
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, response)
+}
+
+// LoginMFASetup 登入過程中設定兩步驟驗證
+// @Summary 登入時設定兩步驟驗證
+// @Description 強制啟用兩步驟驗證但尚未設定的帳號，以 mfa_token 取得 TOTP 密鑰與 otpauth 連結
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.MFALoginSetupRequest true "驗證請求令牌"
+// @Success 200 {object} vo.MFASetupResponse "密鑰與 otpauth 連結"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "驗證請求已過期"
+// @Failure 409 {object} map[string]string "兩步驟驗證已啟用"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/login/mfa/setup [post]
+func (c *AuthController) LoginMFASetup(ctx *gin.Context) {
+	var req dto.MFALoginSetupRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	response, err := c.AuthService.SetupMFAChallenge(req)
+	if err != nil {
+		respondMFAError(ctx, err)
 		return
 	}
 	
@@ -138,7 +219,7 @@ func (c *AuthController) RefreshToken(ctx *gin.Context) {
 		return
 	}
 	
-	newToken, newRefreshToken, err := c.AuthService.RefreshToken(refreshToken)
+	newToken, newRefreshToken, err := c.AuthService.RefreshToken(refreshToken, clientInfo(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -150,16 +231,259 @@ func (c *AuthController) RefreshToken(ctx *gin.Context) {
 	})
 }
 
+// ForgotPassword 申請重設密碼
+// @Summary 忘記密碼
+// @Description 寄送重設密碼連結；無論電子郵件是否註冊都回傳相同結果
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "電子郵件"
+// @Success 202 {object} vo.BaseResponse "已受理"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Router /auth/forgot-password [post]
+func (c *AuthController) ForgotPassword(ctx *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	c.AuthService.ForgotPassword(req)
+	ctx.JSON(http.StatusAccepted, vo.BaseResponse{Message: "若此電子郵件已註冊，您將收到重設密碼連結"})
+}
+
+// ResetPassword 重設密碼
+// @Summary 重設密碼
+// @Description 使用郵件中的令牌設定新密碼，並登出所有裝置
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "重設令牌與新密碼"
+// @Success 200 {object} vo.BaseResponse "重設成功"
+// @Failure 400 {object} map[string]string "無效的輸入或令牌"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/reset-password [post]
+func (c *AuthController) ResetPassword(ctx *gin.Context) {
+	var req dto.ResetPasswordRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	if err := c.AuthService.ResetPassword(ctx.Request.Context(), req); err != nil {
+		if err.Error() == "無效或已過期的令牌" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "重設密碼失敗"})
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "密碼已重設，請重新登入"})
+}
+
+// VerifyEmail 驗證電子郵件
+// @Summary 驗證電子郵件
+// @Description 使用驗證郵件中的令牌完成電子郵件驗證
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "驗證令牌"
+// @Success 200 {object} vo.UserResponse "驗證成功"
+// @Failure 400 {object} map[string]string "無效或已過期的令牌"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/verify-email [post]
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var req dto.VerifyEmailRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	user, err := c.AuthService.VerifyEmail(req.Token)
+	if err != nil {
+		if err.Error() == "無效或已過期的令牌" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "驗證電子郵件失敗"})
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, user)
+}
+
+// ResendVerificationEmail 依電子郵件重新寄送驗證郵件
+// @Summary 重新寄送驗證郵件
+// @Description 重新寄送驗證郵件；無論電子郵件是否註冊都回傳相同結果
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param request body dto.ResendVerificationRequest true "電子郵件"
+// @Success 202 {object} vo.BaseResponse "已受理"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 429 {object} map[string]string "請求頻率過高"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/resend-verification [post]
+func (c *AuthController) ResendVerificationEmail(ctx *gin.Context) {
+	var req dto.ResendVerificationRequest
+	
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+	
+	if err := c.AuthService.ResendVerificationByEmail(ctx.Request.Context(), req); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "寄送驗證郵件失敗"})
+		return
+	}
+	
+	ctx.JSON(http.StatusAccepted, vo.BaseResponse{Message: "若此電子郵件尚未驗證，您將收到新的驗證郵件"})
+}
+
+// ResendMyVerificationEmail 重新寄送驗證郵件給當前用戶
+// @Summary 重新寄送我的驗證郵件
+// @Description 重新寄送驗證郵件至當前用戶的電子郵件
+// @Tags 認證
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} vo.BaseResponse "已寄送"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 409 {object} map[string]string "電子郵件已驗證"
+// @Failure 429 {object} map[string]string "請求過於頻繁"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/verify-email/resend [post]
+func (c *AuthController) ResendMyVerificationEmail(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	
+	if err := c.AuthService.ResendVerification(ctx.Request.Context(), userIDStr); err != nil {
+		switch err.Error() {
+		case "電子郵件已驗證":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "請求過於頻繁，請稍後再試":
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "寄送驗證郵件失敗"})
+		}
+		return
+	}
+	
+	ctx.JSON(http.StatusAccepted, vo.BaseResponse{Message: "驗證郵件已寄送"})
+}
+
 // Logout 處理用戶登出
 // @Summary 用戶登出
-// @Description 使當前用戶登出
+// @Description 撤銷當前工作階段，其刷新令牌將無法再使用
 // @Tags 認證
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} map[string]string "登出成功"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
 // @Router /auth/logout [post]
 func (c *AuthController) Logout(ctx *gin.Context) {
-	// 在前端處理令牌清理，這裡只返回成功響應
+	sessionID, exists := ctx.Get("sessionID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	sessionIDStr, _ := sessionID.(string)
+	
+	if err := c.AuthService.Logout(sessionIDStr); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "登出失敗"})
+		return
+	}
+	
 	ctx.JSON(http.StatusOK, gin.H{"message": "登出成功"})
 }
+
+// ListSessions 列出登入中的裝置
+// @Summary 登入裝置列表
+// @Description 列出當前用戶所有有效的登入工作階段
+// @Tags 認證
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "工作階段列表"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/sessions [get]
+func (c *AuthController) ListSessions(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	sessionID := ctx.GetString("sessionID")
+	
+	sessions, err := c.AuthService.ListSessions(userIDStr, sessionID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取登入裝置失敗"})
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession 登出指定裝置
+// @Summary 登出指定裝置
+// @Description 撤銷指定的登入工作階段，該裝置的令牌將立即失效
+// @Tags 認證
+// @Produce json
+// @Param id path string true "工作階段 ID"
+// @Security BearerAuth
+// @Success 200 {object} vo.BaseResponse "已登出"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "工作階段不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/sessions/{id} [delete]
+func (c *AuthController) RevokeSession(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	
+	if err := c.AuthService.RevokeSession(userIDStr, ctx.Param("id")); err != nil {
+		if err.Error() == "工作階段不存在" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "登出裝置失敗"})
+		return
+	}
+	
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "已登出該裝置"})
+}
+
+// clientInfo 從請求中擷取裝置資訊
+func clientInfo(ctx *gin.Context) dto.ClientInfo {
+	return dto.ClientInfo{
+		UserAgent:      ctx.Request.UserAgent(),
+		IPAddress:      ctx.ClientIP(),
+		TLSFingerprint: utils.ExtractTLSFingerprint(ctx.Request),
+	}
+}
+
+// respondCaptchaError 將 CAPTCHA 錯誤轉換為 HTTP 回應，並提示客戶端顯示 CAPTCHA；非 CAPTCHA 錯誤時返回 false
+func respondCaptchaError(ctx *gin.Context, err error) bool {
+	switch err.Error() {
+	case "需要完成 CAPTCHA 驗證":
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error(), "captcha_required": true})
+	case "CAPTCHA 驗證失敗", "CAPTCHA 驗證碼已使用":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "captcha_required": true})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+)
+
+// JWKSController 公開 JWT 驗證公鑰
+type JWKSController struct {
+	SigningKeys *jwtkeys.KeySet
+}
+
+// NewJWKSController 創建新的 JWKSController 實例
+func NewJWKSController(signingKeys *jwtkeys.KeySet) *JWKSController {
+	return &JWKSController{
+		SigningKeys: signingKeys,
+	}
+}
+
+// GetJWKS 返回 JSON Web Key Set
+// @Summary JWT 驗證公鑰
+// @Description 返回所有有效的簽章公鑰（含輪替中的舊金鑰），令牌標頭的 kid 對應其中一把金鑰
+// @Tags 認證
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (c *JWKSController) GetJWKS(ctx *gin.Context) {
+	// 允許快取，但需短於金鑰輪替的重疊期
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.SigningKeys.JWKS())
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// MFAController 處理兩步驟驗證設定相關 HTTP 請求
+type MFAController struct {
+	MFAService *services.MFAService
+}
+
+// NewMFAController 創建新的 MFAController 實例
+func NewMFAController(mfaService *services.MFAService) *MFAController {
+	return &MFAController{
+		MFAService: mfaService,
+	}
+}
+
+// Setup 產生 TOTP 密鑰
+// @Summary 設定兩步驟驗證
+// @Description 產生新的 TOTP 密鑰與 otpauth 連結，需再以驗證碼確認才會啟用
+// @Tags 兩步驟驗證
+// @Produce json
+// @Success 200 {object} vo.MFASetupResponse "密鑰與 otpauth 連結"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 409 {object} map[string]string "兩步驟驗證已啟用"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /auth/mfa/setup [post]
+func (c *MFAController) Setup(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	response, err := c.MFAService.Setup(userIDStr)
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Enable 啟用兩步驟驗證
+// @Summary 啟用兩步驟驗證
+// @Description 以驗證器 App 產生的驗證碼確認密鑰並啟用，復原碼僅顯示這一次
+// @Tags 兩步驟驗證
+// @Accept json
+// @Produce json
+// @Param request body dto.EnableMFARequest true "驗證碼"
+// @Success 200 {object} vo.MFARecoveryCodesResponse "復原碼"
+// @Failure 400 {object} map[string]string "無效的輸入或尚未設定"
+// @Failure 401 {object} map[string]string "未認證或驗證碼不正確"
+// @Failure 409 {object} map[string]string "兩步驟驗證已啟用"
+// @Failure 429 {object} map[string]string "請求過於頻繁"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /auth/mfa/enable [post]
+func (c *MFAController) Enable(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.EnableMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := c.MFAService.Enable(userIDStr, ctx.GetString("sessionID"), req.Code)
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.MFARecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// Disable 停用兩步驟驗證
+// @Summary 停用兩步驟驗證
+// @Description 驗證密碼與驗證碼後停用；強制啟用兩步驟驗證的管理員帳號無法停用
+// @Tags 兩步驟驗證
+// @Accept json
+// @Produce json
+// @Param request body dto.DisableMFARequest true "密碼與驗證碼"
+// @Success 200 {object} vo.BaseResponse "已停用"
+// @Failure 400 {object} map[string]string "無效的輸入或未啟用"
+// @Failure 401 {object} map[string]string "未認證、密碼或驗證碼不正確"
+// @Failure 403 {object} map[string]string "管理員帳號必須啟用兩步驟驗證，或未設定密碼的帳號須重新登入"
+// @Failure 429 {object} map[string]string "請求過於頻繁"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /auth/mfa/disable [post]
+func (c *MFAController) Disable(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.DisableMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.MFAService.Disable(userIDStr, ctx.GetString("sessionID"), req.Password, req.Code); err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "兩步驟驗證已停用"})
+}
+
+// RegenerateRecoveryCodes 重新產生復原碼
+// @Summary 重新產生復原碼
+// @Description 以驗證碼確認後產生新的復原碼，舊復原碼全部失效
+// @Tags 兩步驟驗證
+// @Accept json
+// @Produce json
+// @Param request body dto.RegenerateRecoveryCodesRequest true "驗證碼"
+// @Success 200 {object} vo.MFARecoveryCodesResponse "復原碼"
+// @Failure 400 {object} map[string]string "無效的輸入或未啟用"
+// @Failure 401 {object} map[string]string "未認證或驗證碼不正確"
+// @Failure 429 {object} map[string]string "請求過於頻繁"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /auth/mfa/recovery-codes [post]
+func (c *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.RegenerateRecoveryCodesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := c.MFAService.RegenerateRecoveryCodes(userIDStr, req.Code)
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.MFARecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// respondMFAError 將兩步驟驗證錯誤轉換為 HTTP 回應
+func respondMFAError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "請輸入驗證碼", "請先設定兩步驟驗證", "兩步驟驗證未啟用":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "驗證碼不正確", "復原碼不正確", "密碼不正確", "無效或已過期的驗證請求":
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "管理員帳號必須啟用兩步驟驗證", "請重新登入後再試":
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "使用者不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "兩步驟驗證已啟用":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "請求過於頻繁，請稍後再試":
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "兩步驟驗證處理失敗"})
+	}
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// OIDCController 處理第三方登入與身分綁定相關 HTTP 請求
+type OIDCController struct {
+	OIDCService *services.OIDCService
+}
+
+// NewOIDCController 創建新的 OIDCController 實例
+func NewOIDCController(oidcService *services.OIDCService) *OIDCController {
+	return &OIDCController{
+		OIDCService: oidcService,
+	}
+}
+
+// Authorize 產生第三方登入連結
+// @Summary 第三方登入連結
+// @Description 產生授權碼流程（PKCE）的登入連結，前端導向該連結後於回呼頁面呼叫 callback
+// @Tags 認證
+// @Produce json
+// @Param provider path string true "身分提供者" Enums(google, line)
+// @Success 200 {object} vo.OIDCAuthorizationResponse "授權連結"
+// @Failure 404 {object} map[string]string "不支援的登入方式"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/oidc/{provider}/authorize [get]
+func (c *OIDCController) Authorize(ctx *gin.Context) {
+	response, err := c.OIDCService.AuthorizationURL(ctx.Request.Context(), ctx.Param("provider"), "")
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Callback 完成第三方登入
+// @Summary 第三方登入回呼
+// @Description 以授權碼與 state 完成登入；首次登入時依已驗證的電子郵件綁定既有帳號或建立新帳號
+// @Tags 認證
+// @Accept json
+// @Produce json
+// @Param provider path string true "身分提供者" Enums(google, line)
+// @Param request body dto.OIDCCallbackRequest true "授權碼與 state"
+// @Success 200 {object} vo.LoginResponse "登入成功"
+// @Failure 400 {object} map[string]string "無效的輸入或登入請求已過期"
+// @Failure 401 {object} map[string]string "第三方登入驗證失敗"
+// @Failure 404 {object} map[string]string "不支援的登入方式"
+// @Failure 409 {object} map[string]string "電子郵件已被註冊"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/oidc/{provider}/callback [post]
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	var req dto.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	response, err := c.OIDCService.Login(ctx.Request.Context(), ctx.Param("provider"), req, clientInfo(ctx))
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ListIdentities 列出已綁定的第三方身分
+// @Summary 已綁定的第三方登入
+// @Description 列出當前用戶已綁定的第三方登入身分
+// @Tags 用戶
+// @Produce json
+// @Success 200 {object} map[string]interface{} "第三方身分列表"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/identities [get]
+func (c *OIDCController) ListIdentities(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	identities, err := c.OIDCService.ListIdentities(userIDStr)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取第三方登入失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// AuthorizeLink 產生綁定第三方身分的授權連結
+// @Summary 綁定第三方登入連結
+// @Description 產生綁定用的授權連結，回呼後以相同 state 呼叫綁定端點
+// @Tags 用戶
+// @Produce json
+// @Param provider path string true "身分提供者" Enums(google, line)
+// @Success 200 {object} vo.OIDCAuthorizationResponse "授權連結"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "不支援的登入方式"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/identities/{provider}/authorize [get]
+func (c *OIDCController) AuthorizeLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	response, err := c.OIDCService.AuthorizationURL(ctx.Request.Context(), ctx.Param("provider"), userIDStr)
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Link 綁定第三方身分
+// @Summary 綁定第三方登入
+// @Description 以授權碼與 state 將第三方身分綁定到當前帳號
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param provider path string true "身分提供者" Enums(google, line)
+// @Param request body dto.OIDCCallbackRequest true "授權碼與 state"
+// @Success 200 {object} vo.UserIdentityResponse "綁定成功"
+// @Failure 400 {object} map[string]string "無效的輸入或登入請求已過期"
+// @Failure 401 {object} map[string]string "未認證或第三方登入驗證失敗"
+// @Failure 404 {object} map[string]string "不支援的登入方式"
+// @Failure 409 {object} map[string]string "此身分已綁定其他帳號"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/identities/{provider} [post]
+func (c *OIDCController) Link(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的輸入: " + err.Error()})
+		return
+	}
+
+	identity, err := c.OIDCService.Link(ctx.Request.Context(), ctx.Param("provider"), userIDStr, req)
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, identity)
+}
+
+// Unlink 解除綁定第三方身分
+// @Summary 解除綁定第三方登入
+// @Description 解除綁定；未設定密碼的帳號至少需保留一種登入方式
+// @Tags 用戶
+// @Produce json
+// @Param provider path string true "身分提供者" Enums(google, line)
+// @Success 200 {object} vo.BaseResponse "已解除綁定"
+// @Failure 400 {object} map[string]string "請先設定密碼"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "尚未綁定此登入方式"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/identities/{provider} [delete]
+func (c *OIDCController) Unlink(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	if err := c.OIDCService.Unlink(userIDStr, ctx.Param("provider")); err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "已解除綁定"})
+}
+
+// respondOIDCError 將第三方登入錯誤轉換為 HTTP 回應
+func respondOIDCError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "無效或已過期的登入請求", "身分提供者未提供電子郵件", "身分提供者的電子郵件尚未驗證", "請先設定密碼再解除綁定":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "第三方登入驗證失敗":
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "不支援的登入方式", "尚未綁定此登入方式", "使用者不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "此電子郵件已註冊，請以密碼登入後再綁定", "此身分已綁定其他帳號", "已綁定其他同類型帳號，請先解除綁定":
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "第三方登入暫時無法使用":
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "第三方登入失敗"})
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+)
+
+// OrderController 處理訂單相關 HTTP 請求
+type OrderController struct {
+	OrderService   *services.OrderService
+	InvoiceService *services.InvoiceService
+}
+
+// NewOrderController 創建新的 OrderController 實例
+func NewOrderController(orderService *services.OrderService, invoiceService *services.InvoiceService) *OrderController {
+	return &OrderController{
+		OrderService:   orderService,
+		InvoiceService: invoiceService,
+	}
+}
+
+// CreateOrder 購買票券
+// @Summary 購買票券
+// @Description 購買一或多種票券並建立待付款訂單；須已驗證電子郵件，並依風險要求 CAPTCHA 與工作量證明（X-PoW-Solution）
+// @Tags 訂單
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateOrderRequest true "訂單項目"
+// @Success 201 {object} vo.OrderResponse "待付款訂單"
+// @Failure 400 {object} map[string]string "無效的請求"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "票券類型不存在"
+// @Failure 409 {object} map[string]string "票券不在銷售期間或數量不足"
+// @Failure 428 {object} map[string]interface{} "需要完成工作量證明"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /orders [post]
+func (c *OrderController) CreateOrder(ctx *gin.Context) {
+	// 請求內容已由 CheckTLSFingerprint 讀取過，須以 ShouldBindBodyWith 重複綁定
+	var req dto.CreateOrderRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	order, err := c.OrderService.CreateOrder(userIDStr, req)
+	if err != nil {
+		switch err.Error() {
+		case "無效的用戶 ID", "無效的票券類型 ID":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "票券類型不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "票券銷售尚未開始", "票券銷售已結束", "票券數量不足":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("建立訂單失敗: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "建立訂單失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, order)
+}
+
+// GetInvoice 下載訂單發票
+// @Summary 下載發票
+// @Description 下載已付款訂單的發票／收據 PDF，發票於訂單付款時開立
+// @Tags 訂單
+// @Produce application/pdf
+// @Param id path string true "訂單 ID"
+// @Success 200 {file} file "發票 PDF"
+// @Failure 400 {object} map[string]string "無效的訂單 ID"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "訂單或發票不存在"
+// @Failure 409 {object} map[string]string "訂單尚未付款"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /orders/{id}/invoice [get]
+func (c *OrderController) GetInvoice(ctx *gin.Context) {
+	order, ok := c.getOrder(ctx)
+	if !ok {
+		return
+	}
+
+	if order.PaymentStatus != "paid" && order.PaymentStatus != "refunded" {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "訂單尚未付款，無法開立發票"})
+		return
+	}
+
+	invoice, err := c.InvoiceService.GetInvoice(order.ID)
+	if err != nil {
+		if err.Error() == "發票不存在" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取發票失敗"})
+		return
+	}
+
+	c.renderPDF(ctx, invoice)
+}
+
+// GetCreditNote 下載訂單折讓單
+// @Summary 下載折讓單
+// @Description 下載已退款訂單的折讓單 PDF
+// @Tags 訂單
+// @Produce application/pdf
+// @Param id path string true "訂單 ID"
+// @Success 200 {file} file "折讓單 PDF"
+// @Failure 400 {object} map[string]string "無效的訂單 ID"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "訂單或折讓單不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /orders/{id}/credit-note [get]
+func (c *OrderController) GetCreditNote(ctx *gin.Context) {
+	order, ok := c.getOrder(ctx)
+	if !ok {
+		return
+	}
+
+	creditNote, err := c.InvoiceService.GetCreditNote(order.ID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.renderPDF(ctx, creditNote)
+}
+
+// getOrder 解析路徑中的訂單 ID 並確認當前用戶有權查看
+func (c *OrderController) getOrder(ctx *gin.Context) (*models.Order, bool) {
+	orderID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "無效的訂單 ID"})
+		return nil, false
+	}
+
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return nil, false
+	}
+	userIDStr, _ := userID.(string)
+	role, _ := ctx.Get("role")
+	roleStr, _ := role.(string)
+
+	order, err := c.OrderService.GetOrderForUser(orderID, userIDStr, roleStr)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "訂單不存在"})
+		return nil, false
+	}
+
+	return order, true
+}
+
+// renderPDF 輸出發票 PDF
+func (c *OrderController) renderPDF(ctx *gin.Context, invoice *models.Invoice) {
+	content, err := c.InvoiceService.RenderPDF(invoice)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "產生 PDF 失敗"})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", invoice.InvoiceNumber))
+	ctx.Data(http.StatusOK, "application/pdf", content)
+}
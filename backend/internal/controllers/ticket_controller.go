@@ -10,13 +10,15 @@ import (
 
 // TicketController 處理票券相關 HTTP 請求
 type TicketController struct {
-	TicketService *services.TicketService
+	TicketService      *services.TicketService
+	FingerprintService *services.FingerprintService
 }
 
 // NewTicketController 創建新的 TicketController 實例
-func NewTicketController(ticketService *services.TicketService) *TicketController {
+func NewTicketController(ticketService *services.TicketService, fingerprintService *services.FingerprintService) *TicketController {
 	return &TicketController{
-		TicketService: ticketService,
+		TicketService:      ticketService,
+		FingerprintService: fingerprintService,
 	}
 }
 
@@ -60,13 +62,14 @@ func (c *TicketController) CheckAvailability(ctx *gin.Context) {
 
 // CheckFingerprint 檢查指紋是否已購買特定票券
 // @Summary 檢查指紋購買狀態
-// @Description 檢查當前設備是否已購買特定票券
+// @Description 依票種的指紋政策檢查當前設備在區間內的購買次數
 // @Tags 票券
 // @Accept json
 // @Produce json
 // @Param ticket_type_id path string true "票券類型 ID"
-// @Success 200 {object} map[string]interface{} "指紋檢查結果"
+// @Success 200 {object} vo.FingerprintCheckResponse "指紋檢查結果"
 // @Failure 400 {object} map[string]string "無效的請求"
+// @Failure 404 {object} map[string]string "票券類型不存在"
 // @Router /tickets/check-fingerprint/{ticket_type_id} [get]
 func (c *TicketController) CheckFingerprint(ctx *gin.Context) {
 	ticketTypeID := ctx.Param("ticket_type_id")
@@ -74,16 +77,21 @@ func (c *TicketController) CheckFingerprint(ctx *gin.Context) {
 	// 提取 TLS 指紋
 	fingerprint := utils.GetClientIPFingerprint(ctx.Request)
 	
-	// 檢查是否已購買
-	alreadyPurchased, err := c.TicketService.CheckFingerprint(ctx, fingerprint, ticketTypeID)
+	// 檢查購買次數
+	result, err := c.FingerprintService.Check(ctx, fingerprint, ctx.ClientIP(), ticketTypeID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "檢查失敗"})
+		switch err.Error() {
+		case "無效的票券類型 ID":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "票券類型不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "檢查失敗"})
+		}
 		return
 	}
 	
-	ctx.JSON(http.StatusOK, gin.H{
-		"already_purchased": alreadyPurchased,
-	})
+	ctx.JSON(http.StatusOK, result)
 }
 
 // ValidateTicket 驗證票券有效性
@@ -122,3 +130,40 @@ func (c *TicketController) UseTicket(ctx *gin.Context) {
 		"message": "使用票券功能尚未實現",
 	})
 }
+
+// GetTicketPDF 下載電子票券
+// @Summary 下載電子票券
+// @Description 下載含活動資訊與票券 QR Code 的電子票券 PDF
+// @Tags 票券
+// @Produce application/pdf
+// @Param id path string true "票券 ID"
+// @Success 200 {file} file "電子票券 PDF"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "票券不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /tickets/{id}/pdf [get]
+func (c *TicketController) GetTicketPDF(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	role, _ := ctx.Get("role")
+	roleStr, _ := role.(string)
+
+	content, err := c.TicketService.GetTicketPDF(ctx.Param("id"), userIDStr, roleStr)
+	if err != nil {
+		switch err.Error() {
+		case "無效的票券 ID", "票券不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "票券不存在"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "產生電子票券失敗"})
+		}
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=ticket-"+ctx.Param("id")+".pdf")
+	ctx.Data(http.StatusOK, "application/pdf", content)
+}
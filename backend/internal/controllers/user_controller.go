@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// UserController 處理當前用戶相關 HTTP 請求
+type UserController struct {
+	UserService   *services.UserService
+	OrderService  *services.OrderService
+	TicketService *services.TicketService
+}
+
+// NewUserController 創建新的 UserController 實例
+func NewUserController(userService *services.UserService, orderService *services.OrderService, ticketService *services.TicketService) *UserController {
+	return &UserController{
+		UserService:   userService,
+		OrderService:  orderService,
+		TicketService: ticketService,
+	}
+}
+
+// GetProfile 獲取當前用戶個人資料
+// @Summary 我的個人資料
+// @Description 獲取當前用戶的個人資料
+// @Tags 用戶
+// @Produce json
+// @Success 200 {object} vo.UserResponse "個人資料"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "使用者不存在"
+// @Security BearerAuth
+// @Router /users/me [get]
+func (c *UserController) GetProfile(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	user, err := c.UserService.GetProfile(userIDStr)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile 更新當前用戶個人資料
+// @Summary 更新個人資料
+// @Description 更新當前用戶的姓名與電話
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param profile body dto.UpdateProfileRequest true "個人資料"
+// @Success 200 {object} vo.UserResponse "更新成功"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me [put]
+func (c *UserController) UpdateProfile(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.UpdateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.UserService.UpdateProfile(userIDStr, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新個人資料失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// ChangeEmail 申請變更電子郵件
+// @Summary 變更電子郵件
+// @Description 驗證密碼後寄送確認連結至新電子郵件，確認後才會生效
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangeEmailRequest true "新電子郵件"
+// @Success 202 {object} vo.BaseResponse "已寄送確認郵件"
+// @Failure 400 {object} map[string]string "無效的輸入或密碼不正確"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 403 {object} map[string]string "未設定密碼的帳號須重新登入"
+// @Failure 409 {object} map[string]string "電子郵件已被使用"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/email [post]
+func (c *UserController) ChangeEmail(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.ChangeEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.UserService.RequestEmailChange(ctx.Request.Context(), userIDStr, ctx.GetString("sessionID"), req); err != nil {
+		switch err.Error() {
+		case "密碼不正確", "新電子郵件與目前相同":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "請重新登入後再試":
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "電子郵件已被使用":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "申請變更電子郵件失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, vo.BaseResponse{Message: "確認郵件已寄送至新電子郵件"})
+}
+
+// ConfirmEmailChange 確認變更電子郵件
+// @Summary 確認變更電子郵件
+// @Description 使用郵件中的令牌完成電子郵件變更
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param request body dto.ConfirmEmailChangeRequest true "確認令牌"
+// @Success 200 {object} vo.UserResponse "變更成功"
+// @Failure 400 {object} map[string]string "無效或已過期的令牌"
+// @Failure 409 {object} map[string]string "電子郵件已被使用"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Router /auth/confirm-email-change [post]
+func (c *UserController) ConfirmEmailChange(ctx *gin.Context) {
+	var req dto.ConfirmEmailChangeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.UserService.ConfirmEmailChange(req.Token)
+	if err != nil {
+		switch err.Error() {
+		case "無效或已過期的令牌":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "電子郵件已被使用":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "變更電子郵件失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// ChangePassword 變更密碼
+// @Summary 變更密碼
+// @Description 驗證目前密碼後變更密碼，並登出其他裝置；僅以第三方登入的帳號須於近期登入，免填目前密碼
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangePasswordRequest true "密碼"
+// @Success 200 {object} vo.BaseResponse "變更成功"
+// @Failure 400 {object} map[string]string "無效的輸入或目前密碼不正確"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 403 {object} map[string]string "未設定密碼的帳號須重新登入"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/password [put]
+func (c *UserController) ChangePassword(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.UserService.ChangePassword(userIDStr, ctx.GetString("sessionID"), req); err != nil {
+		switch err.Error() {
+		case "目前密碼不正確":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "請重新登入後再試":
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "變更密碼失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "密碼已變更"})
+}
+
+// DeleteAccount 刪除帳號
+// @Summary 刪除帳號
+// @Description 匿名化個人資料並停用帳號，訂單紀錄因會計需求保留
+// @Tags 用戶
+// @Accept json
+// @Produce json
+// @Param request body dto.DeleteAccountRequest true "密碼確認"
+// @Success 200 {object} vo.BaseResponse "刪除成功"
+// @Failure 400 {object} map[string]string "無效的輸入或密碼不正確"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 403 {object} map[string]string "管理員帳號無法自行刪除，或未設定密碼的帳號須重新登入"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me [delete]
+func (c *UserController) DeleteAccount(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var req dto.DeleteAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.UserService.DeleteAccount(userIDStr, ctx.GetString("sessionID"), req.Password); err != nil {
+		switch err.Error() {
+		case "密碼不正確":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "請重新登入後再試", "管理員帳號無法自行刪除":
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "刪除帳號失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, vo.BaseResponse{Message: "帳號已刪除"})
+}
+
+// ExportData 匯出個人資料
+// @Summary 匯出個人資料
+// @Description 以 JSON 匯出我們保存的所有用戶資料，包含訂單、票券、發票、工作階段與登入紀錄
+// @Tags 用戶
+// @Produce json
+// @Success 200 {object} vo.UserDataExport "個人資料"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/export [get]
+func (c *UserController) ExportData(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	export, err := c.UserService.ExportUserData(userIDStr)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "匯出個人資料失敗"})
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=my-data.json")
+	ctx.JSON(http.StatusOK, export)
+}
+
+// GetMyOrders 獲取當前用戶的訂單列表
+// @Summary 我的訂單
+// @Description 分頁獲取當前用戶的訂單，可依狀態篩選
+// @Tags 用戶
+// @Produce json
+// @Param status query string false "訂單狀態 (pending, paid, cancelled)"
+// @Param page query int false "頁碼，默認為 1"
+// @Param limit query int false "每頁數量，默認為 10"
+// @Success 200 {object} vo.OrderListResponse "訂單列表"
+// @Failure 400 {object} map[string]string "無效的輸入"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/orders [get]
+func (c *UserController) GetMyOrders(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	var params dto.OrderQueryParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orders, total, err := c.OrderService.GetUserOrders(userIDStr, params.Status, params.Page, params.Limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取訂單列表失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+		"total":  total,
+		"page":   params.Page,
+		"limit":  params.Limit,
+	})
+}
+
+// GetMyOrder 獲取當前用戶的訂單詳情
+// @Summary 我的訂單詳情
+// @Description 獲取當前用戶的單筆訂單，包含訂單項目與票券
+// @Tags 用戶
+// @Produce json
+// @Param id path string true "訂單 ID"
+// @Success 200 {object} vo.OrderDetailResponse "訂單詳情"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "訂單不存在"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/orders/{id} [get]
+func (c *UserController) GetMyOrder(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	order, err := c.OrderService.GetUserOrderDetail(ctx.Param("id"), userIDStr)
+	if err != nil {
+		switch err.Error() {
+		case "無效的訂單 ID", "訂單不存在":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "訂單不存在"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取訂單詳情失敗"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// GetMyTickets 獲取當前用戶的票券
+// @Summary 我的票券
+// @Description 獲取當前用戶持有的票券，依活動分為即將到來與已結束
+// @Tags 用戶
+// @Produce json
+// @Success 200 {object} vo.UserTicketsResponse "票券列表"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 500 {object} map[string]string "內部服務器錯誤"
+// @Security BearerAuth
+// @Router /users/me/tickets [get]
+func (c *UserController) GetMyTickets(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+
+	tickets, err := c.TicketService.GetUserTickets(userIDStr)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "獲取票券列表失敗"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tickets)
+}
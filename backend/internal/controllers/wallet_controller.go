@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/services"
+)
+
+// WalletController 處理電子錢包票券相關 HTTP 請求
+type WalletController struct {
+	WalletService *services.WalletService
+}
+
+// NewWalletController 創建新的 WalletController 實例
+func NewWalletController(walletService *services.WalletService) *WalletController {
+	return &WalletController{
+		WalletService: walletService,
+	}
+}
+
+// GetApplePass 下載 Apple Wallet 票券
+// @Summary 下載 Apple Wallet 票券
+// @Description 產生並下載已簽署的 .pkpass 檔案
+// @Tags 電子錢包
+// @Produce application/vnd.apple.pkpass
+// @Param id path string true "票券 ID"
+// @Success 200 {file} file ".pkpass 檔案"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "票券不存在"
+// @Failure 503 {object} map[string]string "未啟用 Apple Wallet"
+// @Security BearerAuth
+// @Router /tickets/{id}/wallet/apple [get]
+func (c *WalletController) GetApplePass(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	role, _ := ctx.Get("role")
+	roleStr, _ := role.(string)
+
+	content, err := c.WalletService.GenerateApplePass(ctx.Param("id"), userIDStr, roleStr)
+	if err != nil {
+		respondWalletError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=ticket-"+ctx.Param("id")+".pkpass")
+	ctx.Data(http.StatusOK, "application/vnd.apple.pkpass", content)
+}
+
+// GetGoogleSaveLink 獲取 Google Wallet 加入連結
+// @Summary 獲取 Google Wallet 加入連結
+// @Description 產生「加入 Google 錢包」連結
+// @Tags 電子錢包
+// @Produce json
+// @Param id path string true "票券 ID"
+// @Success 200 {object} map[string]string "加入連結"
+// @Failure 401 {object} map[string]string "未認證"
+// @Failure 404 {object} map[string]string "票券不存在"
+// @Failure 503 {object} map[string]string "未啟用 Google Wallet"
+// @Security BearerAuth
+// @Router /tickets/{id}/wallet/google [get]
+func (c *WalletController) GetGoogleSaveLink(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+		return
+	}
+	userIDStr, _ := userID.(string)
+	role, _ := ctx.Get("role")
+	roleStr, _ := role.(string)
+
+	saveURL, err := c.WalletService.GoogleSaveLink(ctx.Param("id"), userIDStr, roleStr)
+	if err != nil {
+		respondWalletError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"save_url": saveURL})
+}
+
+// RegisterDevice Apple Wallet 裝置註冊 (PassKit Web Service)
+// @Summary 註冊 Wallet 裝置
+// @Description 由 Apple Wallet 呼叫，註冊裝置以接收票券更新推播
+// @Tags 電子錢包
+// @Accept json
+// @Param device_id path string true "裝置識別碼"
+// @Param pass_type_id path string true "票券類型識別碼"
+// @Param serial_number path string true "票券序號"
+// @Success 200 "已註冊"
+// @Success 201 "註冊成功"
+// @Failure 401 "驗證失敗"
+// @Router /wallet/v1/devices/{device_id}/registrations/{pass_type_id}/{serial_number} [post]
+func (c *WalletController) RegisterDevice(ctx *gin.Context) {
+	var req struct {
+		PushToken string `json:"pushToken" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	created, err := c.WalletService.RegisterDevice(
+		ctx.Param("device_id"),
+		ctx.Param("pass_type_id"),
+		ctx.Param("serial_number"),
+		applePassToken(ctx),
+		req.PushToken,
+	)
+	if err != nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	if created {
+		ctx.Status(http.StatusCreated)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// UnregisterDevice 取消 Apple Wallet 裝置註冊 (PassKit Web Service)
+// @Summary 取消註冊 Wallet 裝置
+// @Description 由 Apple Wallet 呼叫，票券從裝置移除時取消註冊
+// @Tags 電子錢包
+// @Param device_id path string true "裝置識別碼"
+// @Param pass_type_id path string true "票券類型識別碼"
+// @Param serial_number path string true "票券序號"
+// @Success 200 "取消成功"
+// @Failure 401 "驗證失敗"
+// @Router /wallet/v1/devices/{device_id}/registrations/{pass_type_id}/{serial_number} [delete]
+func (c *WalletController) UnregisterDevice(ctx *gin.Context) {
+	err := c.WalletService.UnregisterDevice(
+		ctx.Param("device_id"),
+		ctx.Param("pass_type_id"),
+		ctx.Param("serial_number"),
+		applePassToken(ctx),
+	)
+	if err != nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// GetUpdatedSerialNumbers 獲取裝置上已更新的票券序號 (PassKit Web Service)
+// @Summary 獲取已更新票券
+// @Description 由 Apple Wallet 呼叫，返回自指定標記後有更新的票券序號
+// @Tags 電子錢包
+// @Produce json
+// @Param device_id path string true "裝置識別碼"
+// @Param pass_type_id path string true "票券類型識別碼"
+// @Param passesUpdatedSince query string false "上次更新標記"
+// @Success 200 {object} map[string]interface{} "票券序號與更新標記"
+// @Success 204 "沒有更新"
+// @Failure 404 "票券類型不存在"
+// @Router /wallet/v1/devices/{device_id}/registrations/{pass_type_id} [get]
+func (c *WalletController) GetUpdatedSerialNumbers(ctx *gin.Context) {
+	serialNumbers, lastUpdated, err := c.WalletService.GetUpdatedSerialNumbers(
+		ctx.Param("device_id"),
+		ctx.Param("pass_type_id"),
+		ctx.Query("passesUpdatedSince"),
+	)
+	if err != nil {
+		ctx.Status(http.StatusNotFound)
+		return
+	}
+
+	if len(serialNumbers) == 0 {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"serialNumbers": serialNumbers,
+		"lastUpdated":   lastUpdated,
+	})
+}
+
+// GetLatestPass 獲取最新版本票券 (PassKit Web Service)
+// @Summary 獲取最新票券
+// @Description 由 Apple Wallet 呼叫，下載最新版本的 .pkpass
+// @Tags 電子錢包
+// @Produce application/vnd.apple.pkpass
+// @Param pass_type_id path string true "票券類型識別碼"
+// @Param serial_number path string true "票券序號"
+// @Success 200 {file} file ".pkpass 檔案"
+// @Success 304 "票券未變更"
+// @Failure 401 "驗證失敗"
+// @Router /wallet/v1/passes/{pass_type_id}/{serial_number} [get]
+func (c *WalletController) GetLatestPass(ctx *gin.Context) {
+	content, modifiedAt, err := c.WalletService.GetLatestApplePass(
+		ctx.Param("pass_type_id"),
+		ctx.Param("serial_number"),
+		applePassToken(ctx),
+	)
+	if err != nil {
+		ctx.Status(http.StatusUnauthorized)
+		return
+	}
+
+	if since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since")); err == nil && !modifiedAt.Truncate(time.Second).After(since) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Header("Last-Modified", modifiedAt.UTC().Format(http.TimeFormat))
+	ctx.Data(http.StatusOK, "application/vnd.apple.pkpass", content)
+}
+
+// Log 接收 Apple Wallet 錯誤日誌 (PassKit Web Service)
+// @Summary 接收 Wallet 日誌
+// @Description 由 Apple Wallet 呼叫，回報裝置端錯誤
+// @Tags 電子錢包
+// @Accept json
+// @Success 200 "已記錄"
+// @Router /wallet/v1/log [post]
+func (c *WalletController) Log(ctx *gin.Context) {
+	var req struct {
+		Logs []string `json:"logs"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err == nil {
+		for _, entry := range req.Logs {
+			log.Printf("Apple Wallet: %s", entry)
+		}
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// applePassToken 從 Authorization 標頭取出 ApplePass 驗證令牌
+func applePassToken(ctx *gin.Context) string {
+	return strings.TrimPrefix(ctx.GetHeader("Authorization"), "ApplePass ")
+}
+
+// respondWalletError 將錢包服務錯誤轉換為 HTTP 回應
+func respondWalletError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "無效的票券 ID", "票券不存在":
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "票券不存在"})
+	case "未啟用 Apple Wallet", "未啟用 Google Wallet":
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "產生錢包票券失敗"})
+	}
+}
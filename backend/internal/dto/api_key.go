@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// 建立服務帳號請求
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" binding:"required,min=2" example:"票務中心售票系統"`
+	Role string `json:"role" binding:"required" example:"gate_staff"`
+}
+
+// 簽發 API 金鑰請求
+type CreateAPIKeyRequest struct {
+	UserID    string     `json:"user_id" binding:"required,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name      string     `json:"name" binding:"required" example:"經銷商 A 正式環境"`
+	Scopes    []string   `json:"scopes" example:"ticket:use"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2025-12-31T23:59:59+08:00"`
+}
+
+// API 金鑰查詢參數
+type APIKeyQueryParams struct {
+	UserID string `form:"user_id" binding:"omitempty,uuid"`
+}
@@ -2,16 +2,18 @@ package dto
 
 // 登入請求 DTO
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email" example:"user@example.com"`
-	Password string `json:"password" binding:"required,min=6" example:"password123"`
+	Email        string `json:"email" binding:"required,email" example:"user@example.com"`
+	Password     string `json:"password" binding:"required,min=6" example:"password123"`
+	CaptchaToken string `json:"captcha_token,omitempty" example:"10000000-aaaa-bbbb-cccc-000000000001"` // 登入失敗次數達門檻後必填
 }
 
 // 註冊請求 DTO
 type RegisterRequest struct {
-	Name     string `json:"name" binding:"required,min=2" example:"張三"`
-	Email    string `json:"email" binding:"required,email" example:"user@example.com"`
-	Phone    string `json:"phone" binding:"required" example:"0912345678"`
-	Password string `json:"password" binding:"required,min=8" example:"password123"`
+	Name         string `json:"name" binding:"required,min=2" example:"張三"`
+	Email        string `json:"email" binding:"required,email" example:"user@example.com"`
+	Phone        string `json:"phone" binding:"required" example:"0912345678"`
+	Password     string `json:"password" binding:"required,min=8" example:"password123"`
+	CaptchaToken string `json:"captcha_token,omitempty" example:"10000000-aaaa-bbbb-cccc-000000000001"` // 啟用註冊 CAPTCHA 時必填
 }
 
 // 重設密碼請求 DTO
@@ -24,3 +26,54 @@ type ResetPasswordRequest struct {
 	Token    string `json:"token" binding:"required" example:"1234567890abcdef"`
 	Password string `json:"password" binding:"required,min=8" example:"newPassword123"`
 }
+
+// 驗證電子郵件請求 DTO
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"eyJ1aWQiOiI1NTBlODQwMC1lMjliLTQxZDQtYTcxNi00NDY2NTU0NDAwMDAifQ.c2lnbmF0dXJl"`
+}
+
+// 重新寄送驗證郵件請求 DTO
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// 請求來源資訊，記錄於登入工作階段
+type ClientInfo struct {
+	UserAgent      string
+	IPAddress      string
+	TLSFingerprint string
+}
+
+// 兩步驟驗證登入請求 DTO，驗證碼與復原碼擇一提供
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code         string `json:"code" binding:"omitempty,len=6,numeric" example:"123456"`
+	RecoveryCode string `json:"recovery_code" example:"k7m2-9xqa"`
+}
+
+// 兩步驟驗證登入時設定驗證器 DTO
+type MFALoginSetupRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// 啟用兩步驟驗證請求 DTO
+type EnableMFARequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+// 停用兩步驟驗證請求 DTO
+type DisableMFARequest struct {
+	Password string `json:"password" example:"password123"` // 未設定密碼的帳號免填，改為須於近期登入
+	Code     string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+// 重新產生復原碼請求 DTO
+type RegenerateRecoveryCodesRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+// 第三方登入回呼請求 DTO，由前端將授權碼與 state 轉交後端
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required" example:"4/0AX4XfWh..."`
+	State string `json:"state" binding:"required" example:"Qm9iIGlzIGEgZnJpZW5kIG9mIEFsaWNl"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+// 訂單列表查詢參數
+type OrderQueryParams struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending paid cancelled"`
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+// 確認收款請求
+type MarkOrderPaidRequest struct {
+	PaymentMethod      string `json:"payment_method" binding:"required,max=50" example:"credit_card"`
+	PaymentFingerprint string `json:"payment_fingerprint" binding:"omitempty,max=255" example:"fp_1Nv0aB2cD3eF4gH5"` // 金流服務提供的付款方式指紋，不可為卡號
+}
@@ -0,0 +1,12 @@
+package dto
+
+// RateLimitRequest 限流政策比對與計數所需的請求資訊，未知的欄位留空
+type RateLimitRequest struct {
+	Route       string
+	Method      string
+	Role        string
+	IPAddress   string
+	UserID      string
+	APIKeyID    string
+	Fingerprint string
+}
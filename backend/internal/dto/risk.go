@@ -0,0 +1,10 @@
+package dto
+
+import "net/http"
+
+// RiskRequest 購票風險評估所需的請求資訊
+type RiskRequest struct {
+	UserID string
+	Client ClientInfo
+	Header http.Header
+}
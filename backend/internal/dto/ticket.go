@@ -10,6 +10,11 @@ type CreateTicketTypeRequest struct {
 	AvailableQuantity int      `json:"available_quantity" binding:"omitempty,min=0" example:"100"`
 	SaleStart        time.Time `json:"sale_start" binding:"required" example:"2024-07-01T10:00:00+08:00"`
 	SaleEnd          time.Time `json:"sale_end" binding:"required" example:"2024-08-14T23:59:59+08:00"`
+
+	// 裝置指紋防重複購買政策，未提供時為 24 小時內每個裝置限購 1 次、以票種計算
+	FingerprintWindowMinutes int    `json:"fingerprint_window_minutes" binding:"omitempty,min=1" example:"1440"`
+	FingerprintMaxPurchases  *int   `json:"fingerprint_max_purchases" binding:"omitempty,min=0" example:"1"`
+	FingerprintScope         string `json:"fingerprint_scope" binding:"omitempty,oneof=ticket_type event" example:"ticket_type"`
 }
 
 // 更新票種請求
@@ -20,6 +25,10 @@ type UpdateTicketTypeRequest struct {
 	AvailableQuantity int      `json:"available_quantity" binding:"omitempty,min=0" example:"120"`
 	SaleStart        time.Time `json:"sale_start" example:"2024-07-01T10:00:00+08:00"`
 	SaleEnd          time.Time `json:"sale_end" example:"2024-08-14T23:59:59+08:00"`
+
+	FingerprintWindowMinutes int    `json:"fingerprint_window_minutes" binding:"omitempty,min=1" example:"1440"`
+	FingerprintMaxPurchases  *int   `json:"fingerprint_max_purchases" binding:"omitempty,min=0" example:"1"`
+	FingerprintScope         string `json:"fingerprint_scope" binding:"omitempty,oneof=ticket_type event" example:"ticket_type"`
 }
 
 // 票券查詢參數
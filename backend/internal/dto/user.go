@@ -0,0 +1,41 @@
+package dto
+
+// 更新個人資料請求
+type UpdateProfileRequest struct {
+	Name  string `json:"name" binding:"omitempty,min=2" example:"張三"`
+	Phone string `json:"phone" example:"0912345678"`
+}
+
+// 變更電子郵件請求
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email" example:"new@example.com"`
+	Password string `json:"password" example:"password123"` // 未設定密碼（僅以第三方登入）的帳號免填，改為須於近期登入
+}
+
+// 確認變更電子郵件請求
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required" example:"1234567890abcdef"`
+}
+
+// 變更密碼請求
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"password123"` // 未設定密碼的帳號免填，改為須於近期登入
+	NewPassword     string `json:"new_password" binding:"required,min=8" example:"newPassword123"`
+}
+
+// 刪除帳號請求
+type DeleteAccountRequest struct {
+	Password string `json:"password" example:"password123"` // 未設定密碼的帳號免填，改為須於近期登入
+}
+
+// 變更用戶角色請求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"organizer"`
+}
+
+// 共用識別值查詢參數
+type SharedIdentifierQueryParams struct {
+	Kind        string `form:"kind" binding:"omitempty,oneof=device ip payment"`
+	MinAccounts int    `form:"min_accounts,default=2" binding:"min=2"`
+	Limit       int    `form:"limit,default=50" binding:"min=1,max=200"`
+}
@@ -1,26 +1,34 @@
 package middleware
 
 import (
-	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/apikey"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+	"gorm.io/gorm"
 )
 
 // JWTClaims 定義 JWT 聲明結構
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
+	MFA       bool   `json:"mfa"` // 工作階段是否已完成兩步驟驗證
 	jwt.RegisteredClaims
 }
 
-// AuthRequired 驗證 JWT token
-func AuthRequired() gin.HandlerFunc {
-	cfg := config.LoadConfig()
-	
+// sessionTouchInterval 更新工作階段最後活動時間的最小間隔，避免每個請求都寫入資料庫
+const sessionTouchInterval = time.Minute
+
+// AuthRequired 驗證 JWT token 或 API 金鑰，並拒絕已被撤銷的工作階段；API 金鑰只能存取 apiKeyRoutes 中登記的路由
+func AuthRequired(db *gorm.DB, signingKeys *jwtkeys.KeySet, apiKeyRoutes *APIKeyRoutes) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -29,21 +37,17 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 		
+		// 系統整合以 API 金鑰認證
+		if rawKey, ok := strings.CutPrefix(authHeader, "ApiKey "); ok {
+			authenticateAPIKey(c, db, apiKeyRoutes, strings.TrimSpace(rawKey))
+			return
+		}
+		
 		// 從 Bearer Token 中提取令牌
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 		
 		// 解析令牌
-		token, err := jwt.ParseWithClaims(
-			tokenString,
-			&JWTClaims{},
-			func(token *jwt.Token) (interface{}, error) {
-				// 檢查簽名方法
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("無效的簽名方法: %v", token.Header["alg"])
-				}
-				return []byte(cfg.JWTSecret), nil
-			},
-		)
+		token, err := signingKeys.Parse(tokenString, &JWTClaims{})
 		
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的認證令牌"})
@@ -53,9 +57,33 @@ func AuthRequired() gin.HandlerFunc {
 		
 		// 驗證令牌並提取聲明
 		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+			// 僅接受存取令牌，刷新令牌不能用來呼叫 API
+			if claims.TokenType != models.TokenTypeAccess {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的認證令牌"})
+				c.Abort()
+				return
+			}
+
+			// 檢查工作階段是否已登出，或在重設密碼等操作後被撤銷
+			var session models.Session
+			if err := db.Select("id", "revoked_at", "last_seen_at").
+				Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).
+				First(&session).Error; err != nil || session.RevokedAt != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "認證令牌已失效，請重新登入"})
+				c.Abort()
+				return
+			}
+			if time.Since(session.LastSeenAt) > sessionTouchInterval {
+				if err := db.Model(&session).Update("last_seen_at", time.Now()).Error; err != nil {
+					log.Printf("更新工作階段活動時間失敗: %v", err)
+				}
+			}
+
 			// 將聲明信息設置在上下文中
 			c.Set("userID", claims.UserID)
 			c.Set("role", claims.Role)
+			c.Set("sessionID", claims.SessionID)
+			c.Set("mfa", claims.MFA)
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "認證令牌已過期或無效"})
@@ -65,24 +93,46 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
-// AdminRequired 檢查用戶是否為管理員角色
-func AdminRequired() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 檢查是否已經通過了 AuthRequired 中間件的認證
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
-			c.Abort()
-			return
-		}
-		
-		// 檢查用戶角色是否為管理員
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理員權限"})
-			c.Abort()
-			return
+// authenticateAPIKey 驗證 API 金鑰，以擁有者的角色與金鑰的權限範圍處理請求
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, apiKeyRoutes *APIKeyRoutes, rawKey string) {
+	var key models.APIKey
+	if err := db.Where("key_hash = ?", apikey.Hash(rawKey)).First(&key).Error; err != nil || !key.Active(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 API 金鑰"})
+		c.Abort()
+		return
+	}
+
+	var user models.User
+	if err := db.Select("id", "role").First(&user, key.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 API 金鑰"})
+		c.Abort()
+		return
+	}
+
+	// 預設拒絕，僅登記了權限範圍的路由可用 API 金鑰存取
+	scopes := key.ScopeList()
+	registered, allowed := apiKeyRoutes.Permit(c.Request.Method, c.FullPath(), scopes)
+	if !registered {
+		c.JSON(http.StatusForbidden, gin.H{"error": "此操作不支援 API 金鑰"})
+		c.Abort()
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API 金鑰權限範圍不足"})
+		c.Abort()
+		return
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > sessionTouchInterval {
+		if err := db.Model(&key).Update("last_used_at", time.Now()).Error; err != nil {
+			log.Printf("更新 API 金鑰使用時間失敗: %v", err)
 		}
-		
-		c.Next()
 	}
+
+	c.Set("userID", user.ID.String())
+	c.Set("role", user.Role)
+	c.Set("apiKeyID", key.ID.String())
+	c.Set("apiKeyScopes", scopes)
+	c.Set("mfa", false)
+	c.Next()
 }
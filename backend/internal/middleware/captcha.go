@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/captcha"
+)
+
+// CaptchaCheck 依設定或 RiskCheck 的風險評估，要求購票等寫入請求附上 CAPTCHA 驗證碼（X-Captcha-Token）；
+// 須置於 RiskCheck 之後
+func CaptchaCheck(captchaService *services.CaptchaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 讀取類請求不需 CAPTCHA
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		decision := services.RiskAllow
+		if value, exists := c.Get("riskAssessment"); exists {
+			if assessment, ok := value.(*services.RiskAssessment); ok {
+				decision = assessment.Decision
+			}
+		}
+		if !captchaService.RequiredForPurchase(decision) {
+			c.Next()
+			return
+		}
+
+		err := captchaService.Verify(c.Request.Context(), c.GetHeader(captcha.TokenHeader), c.ClientIP())
+		switch {
+		case err == nil:
+			c.Next()
+			return
+		case err.Error() == "需要完成 CAPTCHA 驗證":
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error(), "captcha_required": true})
+		case err.Error() == "CAPTCHA 驗證失敗", err.Error() == "CAPTCHA 驗證碼已使用":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "captcha_required": true})
+		default:
+			log.Printf("CAPTCHA 驗證失敗: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "CAPTCHA 驗證失敗，請稍後再試"})
+		}
+		c.Abort()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/pkg/clientip"
+)
+
+// ClientIP 每個請求只解析一次真實客戶端 IP，存入 context 並改寫 RemoteAddr，
+// 使 gin 的 ClientIP、頻率限制與指紋計算都取得相同的值；須置於其他中間件之前
+func ClientIP(resolver *clientip.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := resolver.Resolve(c.Request)
+		if ip != "" {
+			_, port, err := net.SplitHostPort(c.Request.RemoteAddr)
+			if err != nil {
+				port = "0"
+			}
+			c.Request = c.Request.WithContext(clientip.WithIP(c.Request.Context(), ip))
+			c.Request.RemoteAddr = net.JoinHostPort(ip, port)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/utils"
+)
+
+// RecordDevice 記錄購票請求的裝置指紋與 IP 至帳號裝置關聯；僅處理非 GET 請求，記錄失敗不影響請求
+func RecordDevice(deviceGraph *services.DeviceGraphService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("userID")
+		userIDStr, _ := userID.(string)
+		id, err := uuid.Parse(userIDStr)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if err := deviceGraph.Record(c.Request.Context(), id, dto.ClientInfo{
+			UserAgent:      c.Request.UserAgent(),
+			IPAddress:      c.ClientIP(),
+			TLSFingerprint: utils.ExtractTLSFingerprint(c.Request),
+		}); err != nil {
+			log.Printf("記錄購票裝置失敗: %v", err)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationRequired 要求用戶已完成電子郵件驗證，防止以大量未驗證帳號繞過每人購票限制
+func EmailVerificationRequired(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("id", "email_verified_at").Where("id = ?", userID).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "使用者不存在"})
+			c.Abort()
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "請先完成電子郵件驗證"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,86 +1,85 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
+	"log"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/tlsfp"
+	"github.com/lipeichen/ticket-getter/pkg/utils"
 )
 
-// CheckTLSFingerprint 檢查 TLS 指紋，防止重複購買
-func CheckTLSFingerprint(client *redis.Client) gin.HandlerFunc {
+// TLSFingerprint 將服務器在 TLS 交握時計算的 JA3/JA4 指紋存入 context（ja3、ja3_hash、ja4）
+func TLSFingerprint() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 僅針對購票相關操作檢查指紋
-		if !strings.Contains(c.Request.URL.Path, "/tickets/purchase") && 
-		   !strings.Contains(c.Request.URL.Path, "/orders") {
-			c.Next()
-			return
-		}
-		
-		// 獲取 TLS 指紋
-		// 在實際應用中，這可能是從請求標頭或客戶端證書中提取的
-		fingerprint := c.GetHeader("X-TLS-Fingerprint")
-		if fingerprint == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 TLS 指紋識別"})
-			c.Abort()
-			return
-		}
-		
-		// 檢查當前操作的資源（如票券 ID）
-		resourceID := c.Param("id")
-		if resourceID == "" {
-			// 如果 URL 中沒有 ID，嘗試從請求體中獲取
-			resourceID = c.PostForm("ticket_type_id")
+		if fp := tlsfp.FromRequest(c.Request); fp != nil {
+			c.Set("ja3", fp.JA3)
+			c.Set("ja3_hash", fp.JA3Hash)
+			c.Set("ja4", fp.JA4)
 		}
-		
-		if resourceID == "" {
-			// 無法識別資源 ID，跳過檢查
+		c.Next()
+	}
+}
+
+// CheckTLSFingerprint 依票種的指紋政策限制同一裝置的購買次數，防止重複購買；已達上限的請求直接拒絕，
+// 訂單成立（2xx）後才記錄購買，失敗的訂單不佔用次數。須註冊於購票路由，票種由請求內容（dto.CreateOrderRequest）取得，
+// 處理器須以 ShouldBindBodyWith 讀取請求內容
+func CheckTLSFingerprint(fingerprints *services.FingerprintService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req dto.CreateOrderRequest
+		if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+			// 請求內容無效，交由處理器回應
 			c.Next()
 			return
 		}
-		
-		// 建立 Redis key
-		key := fmt.Sprintf("fingerprint:%s:%s", fingerprint, resourceID)
-		
-		ctx := context.Background()
-		
-		// 檢查是否已存在記錄
-		exists, err := client.Exists(ctx, key).Result()
-		if err != nil {
-			fmt.Printf("Redis 錯誤: %v\n", err)
-			c.Next() // 出錯時繼續處理
-			return
+
+		// 與 /tickets/check-fingerprint 使用相同的裝置指紋
+		fingerprint := utils.GetClientIPFingerprint(c.Request)
+
+		ticketTypeIDs := make([]string, 0, len(req.Items))
+		seen := make(map[string]bool, len(req.Items))
+		for _, item := range req.Items {
+			if !seen[item.TicketTypeID] {
+				seen[item.TicketTypeID] = true
+				ticketTypeIDs = append(ticketTypeIDs, item.TicketTypeID)
+			}
+		}
+
+		// 任一票種已達上限即拒絕
+		for _, ticketTypeID := range ticketTypeIDs {
+			result, err := fingerprints.Check(c.Request.Context(), fingerprint, c.ClientIP(), ticketTypeID)
+			if err != nil {
+				switch err.Error() {
+				case "無效的票券類型 ID", "票券類型不存在":
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					c.Abort()
+					return
+				default:
+					// 出錯時繼續處理
+					log.Printf("檢查裝置指紋購買次數失敗: %v", err)
+					continue
+				}
+			}
+			if result.AlreadyPurchased {
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "您已經購買過此票券，請勿重複購買"})
+				c.Abort()
+				return
+			}
 		}
-		
-		// 如果指紋已存在，表示可能是重複購買
-		if exists > 0 {
-			// 獲取相關信息
-			userInfo, _ := client.Get(ctx, key).Result()
-			
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "您已經購買過此票券，請勿重複購買",
-				"info":  userInfo,
-			})
-			c.Abort()
+
+		c.Next()
+
+		if status := c.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
 			return
 		}
-		
-		// 設置一個過期時間較長的標記，以便後續檢查
-		// 在實際應用中，可能需要根據業務需求調整過期時間
+
 		userID, _ := c.Get("userID")
 		userIDStr, _ := userID.(string)
-		
-		// 存儲信息
-		info := fmt.Sprintf("購買者ID:%s,時間:%s", userIDStr, time.Now().Format(time.RFC3339))
-		_, err = client.Set(ctx, key, info, 24*time.Hour).Result()
-		if err != nil {
-			fmt.Printf("Redis 錯誤: %v\n", err)
+		if err := fingerprints.RecordPurchase(c.Request.Context(), fingerprint, c.ClientIP(), ticketTypeIDs, userIDStr); err != nil {
+			log.Printf("記錄裝置指紋購買失敗: %v", err)
 		}
-		
-		c.Next()
 	}
 }
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+)
+
+// RequirePermission 檢查用戶角色（及 API 金鑰的權限範圍）是否擁有所有指定權限；須在 AuthRequired 之後使用
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	cfg := config.LoadConfig()
+
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未經認證"})
+			c.Abort()
+			return
+		}
+		roleStr, _ := role.(string)
+
+		// 以 API 金鑰存取時，權限同時受金鑰的權限範圍限制
+		scopes, isAPIKey := c.Get("apiKeyScopes")
+		scopeList, _ := scopes.([]models.Permission)
+
+		for _, permission := range permissions {
+			if !models.HasPermission(roleStr, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+				c.Abort()
+				return
+			}
+			if isAPIKey && !hasScope(scopeList, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API 金鑰權限範圍不足"})
+				c.Abort()
+				return
+			}
+		}
+
+		// 僅憑角色聲明不足以信任，管理員須以已完成兩步驟驗證的工作階段操作
+		if roleStr == models.RoleAdmin && cfg.MFARequiredForAdmins && !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "管理員帳號需要完成兩步驟驗證"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hasScope 檢查權限是否在 API 金鑰的權限範圍內
+func hasScope(scopes []models.Permission, permission models.Permission) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRoutes 允許以 API 金鑰存取的路由；未登記的路由一律拒絕 API 金鑰，須在開始處理請求前完成登記
+type APIKeyRoutes struct {
+	routes map[string][]models.Permission
+}
+
+// NewAPIKeyRoutes 創建新的 APIKeyRoutes 實例
+func NewAPIKeyRoutes() *APIKeyRoutes {
+	return &APIKeyRoutes{
+		routes: make(map[string][]models.Permission),
+	}
+}
+
+// Handle 註冊需要指定權限的路由，並允許具備相同權限範圍的 API 金鑰存取
+func (r *APIKeyRoutes) Handle(group *gin.RouterGroup, method string, relativePath string, permission models.Permission, handlers ...gin.HandlerFunc) {
+	fullPath := group.BasePath()
+	if relativePath != "" {
+		fullPath = path.Join(fullPath, relativePath)
+	}
+	r.routes[method+" "+fullPath] = append(r.routes[method+" "+fullPath], permission)
+
+	group.Handle(method, relativePath, append([]gin.HandlerFunc{RequirePermission(permission)}, handlers...)...)
+}
+
+// Permit 檢查 API 金鑰的權限範圍是否涵蓋路由所需的所有權限；fullPath 為 gin 的路由樣式
+func (r *APIKeyRoutes) Permit(method string, fullPath string, scopes []models.Permission) (registered bool, allowed bool) {
+	if r == nil {
+		return false, false
+	}
+	permissions, ok := r.routes[method+" "+fullPath]
+	if !ok {
+		return false, false
+	}
+	for _, permission := range permissions {
+		if !hasScope(scopes, permission) {
+			return true, false
+		}
+	}
+	return true, true
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/pow"
+)
+
+// ProofOfWork 要求購票等寫入請求附上工作量證明解答（X-PoW-Solution）；
+// 缺少或無效時返回 428 與新的挑戰，難度依負載及 RiskCheck 的風險分數提高，須置於 RiskCheck 之後
+func ProofOfWork(powService *services.ProofOfWorkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 讀取類請求不需工作量證明
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("userID")
+		userIDStr, _ := userID.(string)
+
+		riskScore := 0
+		if value, exists := c.Get("riskAssessment"); exists {
+			if assessment, ok := value.(*services.RiskAssessment); ok {
+				riskScore = assessment.Score
+			}
+		}
+
+		solution := c.GetHeader(pow.SolutionHeader)
+		if solution == "" {
+			respondPoWChallenge(c, powService, userIDStr, riskScore, "需要完成工作量證明")
+			return
+		}
+
+		if err := powService.Redeem(c.Request.Context(), userIDStr, solution, powService.MinimumDifficulty(riskScore)); err != nil {
+			switch err.Error() {
+			case "無效的工作量證明", "工作量證明挑戰已過期", "工作量證明難度不足", "工作量證明已使用":
+				respondPoWChallenge(c, powService, userIDStr, riskScore, err.Error())
+			default:
+				log.Printf("驗證工作量證明失敗: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "驗證工作量證明失敗"})
+				c.Abort()
+			}
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// respondPoWChallenge 返回 428 與新的工作量證明挑戰
+func respondPoWChallenge(c *gin.Context, powService *services.ProofOfWorkService, userID string, riskScore int, message string) {
+	challenge, err := powService.IssueChallenge(userID, powService.ChallengeDifficulty(c.Request.Context(), riskScore))
+	if err != nil {
+		log.Printf("簽發工作量證明挑戰失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "簽發工作量證明挑戰失敗"})
+		c.Abort()
+		return
+	}
+
+	c.JSON(http.StatusPreconditionRequired, gin.H{
+		"error": message,
+		"pow":   challenge,
+	})
+	c.Abort()
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/utils"
+)
+
+// RiskCheck 評估購票請求的機器人風險，結果存入 context（riskAssessment）供後續處理使用；
+// 高風險請求直接拒絕，中風險請求交由 ProofOfWork 以較高難度驗證
+func RiskCheck(riskService *services.RiskService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 讀取類請求不評估風險
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("userID")
+		userIDStr, _ := userID.(string)
+
+		assessment := riskService.Assess(c.Request.Context(), dto.RiskRequest{
+			UserID: userIDStr,
+			Client: dto.ClientInfo{
+				UserAgent:      c.Request.UserAgent(),
+				IPAddress:      c.ClientIP(),
+				TLSFingerprint: utils.ExtractTLSFingerprint(c.Request),
+			},
+			Header: c.Request.Header,
+		})
+		c.Set("riskAssessment", assessment)
+
+		if assessment.Decision == services.RiskBlock {
+			c.JSON(http.StatusForbidden, gin.H{"error": "請求已被拒絕"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
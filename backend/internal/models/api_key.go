@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey 供系統整合使用的 API 金鑰，僅保存雜湊；權限為擁有者角色與金鑰權限範圍的交集
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index"` // 擁有者，可為一般用戶或服務帳號
+	Name       string     `gorm:"type:varchar(100);not null"`
+	Prefix     string     `gorm:"type:varchar(20);not null"` // 金鑰前幾碼，供辨識
+	KeyHash    string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string     `gorm:"type:text;not null;default:''"` // 以逗號分隔的權限
+	ExpiresAt  *time.Time `gorm:""`
+	LastUsedAt *time.Time `gorm:""`
+	RevokedAt  *time.Time `gorm:""`
+	CreatedBy  uuid.UUID  `gorm:"type:uuid;not null"`
+	CreatedAt  time.Time  `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// ScopeList 返回金鑰的權限範圍
+func (k *APIKey) ScopeList() []Permission {
+	if k.Scopes == "" {
+		return []Permission{}
+	}
+	parts := strings.Split(k.Scopes, ",")
+	scopes := make([]Permission, len(parts))
+	for i, part := range parts {
+		scopes[i] = Permission(part)
+	}
+	return scopes
+}
+
+// Active 檢查金鑰是否未撤銷且未過期
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
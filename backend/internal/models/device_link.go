@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 裝置關聯類型（DeviceLink.Kind）
+const (
+	DeviceLinkDevice  = "device"  // TLS 指紋
+	DeviceLinkIP      = "ip"      // 客戶端 IP
+	DeviceLinkPayment = "payment" // 金流服務提供的付款方式指紋（如信用卡指紋），不保存卡號
+)
+
+// DeviceLink 用戶與裝置、IP 或付款方式的關聯，共用相同識別值的帳號構成同一群組
+type DeviceLink struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_device_links_user_kind_value"`
+	Kind        string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_device_links_user_kind_value;index:idx_device_links_kind_value"`
+	Value       string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_device_links_user_kind_value;index:idx_device_links_kind_value"`
+	SeenCount   int       `gorm:"not null;default:1"`
+	FirstSeenAt time.Time `gorm:"not null;default:now()"`
+	LastSeenAt  time.Time `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (l *DeviceLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 發票類型
+const (
+	InvoiceTypeInvoice    = "invoice"
+	InvoiceTypeCreditNote = "credit_note"
+)
+
+// Invoice 發票模型（包含退款時開立的折讓單）
+type Invoice struct {
+	ID                uuid.UUID     `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	InvoiceNumber     string        `gorm:"type:varchar(30);not null;unique"`
+	Type              string        `gorm:"type:varchar(20);not null;default:'invoice';uniqueIndex:idx_invoices_order_type"` // invoice 或 credit_note
+	OrderID           uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex:idx_invoices_order_type"`
+	UserID            uuid.UUID     `gorm:"type:uuid;not null;index"`
+	OriginalInvoiceID *uuid.UUID    `gorm:"type:uuid"` // 折讓單對應的原發票
+	Year              int           `gorm:"not null"`
+	SequenceNumber    int           `gorm:"not null"`
+	BuyerName         string        `gorm:"type:varchar(100);not null"`
+	BuyerEmail        string        `gorm:"type:varchar(255);not null"`
+	Subtotal          float64       `gorm:"type:decimal(10,2);not null"`
+	FeeAmount         float64       `gorm:"type:decimal(10,2);not null;default:0"`
+	TaxRate           float64       `gorm:"type:decimal(5,4);not null;default:0"`
+	TaxAmount         float64       `gorm:"type:decimal(10,2);not null;default:0"`
+	TotalAmount       float64       `gorm:"type:decimal(10,2);not null"`
+	IssuedAt          time.Time     `gorm:"not null"`
+	CreatedAt         time.Time     `gorm:"not null;default:now()"`
+	UpdatedAt         time.Time     `gorm:"not null;default:now()"`
+	InvoiceItems      []InvoiceItem `gorm:"foreignKey:InvoiceID"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (i *Invoice) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceItem 發票明細模型，開立時從訂單項目複製，之後不再變動
+type InvoiceItem struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	InvoiceID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Description string    `gorm:"type:varchar(255);not null"`
+	Quantity    int       `gorm:"not null"`
+	UnitPrice   float64   `gorm:"type:decimal(10,2);not null"`
+	Amount      float64   `gorm:"type:decimal(10,2);not null"`
+	CreatedAt   time.Time `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (i *InvoiceItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
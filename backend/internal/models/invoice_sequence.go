@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// InvoiceSequence 發票號碼序列，每個前綴每年一組，於交易中鎖定遞增以確保號碼連續
+type InvoiceSequence struct {
+	Prefix     string    `gorm:"type:varchar(10);primaryKey"`
+	Year       int       `gorm:"primaryKey"`
+	LastNumber int       `gorm:"not null;default:0"`
+	UpdatedAt  time.Time `gorm:"not null;default:now()"`
+}
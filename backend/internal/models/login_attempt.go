@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 登入嘗試結果
+const (
+	LoginResultSuccess            = "success"
+	LoginResultInvalidCredentials = "invalid_credentials"
+	LoginResultThrottled          = "throttled"      // 因失敗次數過多而被延遲或鎖定
+	LoginResultCaptchaFailed      = "captcha_failed" // 失敗次數達門檻後未通過 CAPTCHA
+)
+
+// LoginAttempt 登入嘗試稽核紀錄；帳號不存在時 UserID 為空
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`
+	Email     string     `gorm:"type:varchar(255);not null;index"`
+	IPAddress string     `gorm:"type:varchar(45)"`
+	UserAgent string     `gorm:"type:varchar(512)"`
+	Result    string     `gorm:"type:varchar(30);not null"`
+	CreatedAt time.Time  `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (a *LoginAttempt) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
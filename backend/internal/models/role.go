@@ -0,0 +1,80 @@
+package models
+
+// 用戶角色（User.Role）
+const (
+	RoleUser      = "user"
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"  // 主辦單位，僅能管理自己建立的活動
+	RoleGateStaff = "gate_staff" // 入場工作人員，僅能驗票
+	RoleFinance   = "finance"    // 財務，查詢訂單、確認收款與退款
+	RoleSupport   = "support"    // 客服，查詢訂單與用戶資料
+)
+
+// Permission 操作權限
+type Permission string
+
+// 操作權限
+const (
+	PermissionEventRead    Permission = "event:read"
+	PermissionEventWrite   Permission = "event:write"      // 建立活動及管理自己建立的活動
+	PermissionEventManage  Permission = "event:manage_all" // 管理所有活動，不受建立者限制
+	PermissionTicketUse    Permission = "ticket:use"
+	PermissionOrderRead    Permission = "order:read"
+	PermissionOrderPayment Permission = "order:payment" // 確認訂單收款
+	PermissionOrderRefund  Permission = "order:refund"
+	PermissionUserRead     Permission = "user:read"
+	PermissionUserManage   Permission = "user:manage"
+	PermissionAPIKeyManage Permission = "api_key:manage" // 建立服務帳號、簽發與撤銷 API 金鑰
+)
+
+// rolePermissions 各角色擁有的權限；一般用戶不具任何管理權限
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermissionEventRead,
+		PermissionEventWrite,
+		PermissionEventManage,
+		PermissionTicketUse,
+		PermissionOrderRead,
+		PermissionOrderPayment,
+		PermissionOrderRefund,
+		PermissionUserRead,
+		PermissionUserManage,
+		PermissionAPIKeyManage,
+	},
+	RoleOrganizer: {
+		PermissionEventRead,
+		PermissionEventWrite,
+	},
+	RoleGateStaff: {
+		PermissionTicketUse,
+	},
+	RoleFinance: {
+		PermissionOrderRead,
+		PermissionOrderPayment,
+		PermissionOrderRefund,
+	},
+	RoleSupport: {
+		PermissionEventRead,
+		PermissionOrderRead,
+		PermissionUserRead,
+	},
+}
+
+// ValidRole 檢查角色是否存在
+func ValidRole(role string) bool {
+	if role == RoleUser {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 檢查角色是否擁有指定權限
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JWT 令牌類型（typ 聲明）
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	// 兩步驟驗證請求令牌，僅能用於完成登入
+	TokenTypeMFAChallenge = "mfa_challenge"
+)
+
+// Session 登入工作階段，同一次登入輪替產生的刷新令牌屬於同一個令牌家族
+type Session struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index"`
+	UserAgent      string     `gorm:"type:varchar(512)"`
+	IPAddress      string     `gorm:"type:varchar(45)"`
+	TLSFingerprint string     `gorm:"type:varchar(255)"`
+	LastSeenAt     time.Time  `gorm:"not null;default:now()"`
+	MFAVerified    bool       `gorm:"not null;default:false"` // 登入時是否完成兩步驟驗證
+	RevokedAt      *time.Time `gorm:""`
+	CreatedAt      time.Time  `gorm:"not null;default:now()"`
+	UpdatedAt      time.Time  `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -7,6 +7,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// 指紋防重複購買的計算範圍
+const (
+	FingerprintScopeTicketType = "ticket_type" // 每個票種分別計算
+	FingerprintScopeEvent      = "event"       // 同一事件的所有票種合併計算
+)
+
 // TicketType 票券類型模型
 type TicketType struct {
 	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -17,6 +23,9 @@ type TicketType struct {
 	AvailableQuantity int           `gorm:"not null"`
 	SaleStart        time.Time      `gorm:"not null"`
 	SaleEnd          time.Time      `gorm:"not null"`
+	FingerprintWindowMinutes int    `gorm:"not null;default:1440"` // 同一裝置指紋的購買次數統計區間
+	FingerprintMaxPurchases int     `gorm:"not null;default:1"` // 區間內同一裝置指紋的購買次數上限，0 表示不限制
+	FingerprintScope string         `gorm:"type:varchar(20);not null;default:'ticket_type'"` // ticket_type 或 event
 	CreatedAt        time.Time      `gorm:"not null;default:now()"`
 	UpdatedAt        time.Time      `gorm:"not null;default:now()"`
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
@@ -11,11 +11,16 @@ import (
 type User struct {
 	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	Email         string         `gorm:"type:varchar(255);not null;unique"`
+	EmailVerifiedAt *time.Time   `gorm:""`
 	PasswordHash  string         `gorm:"type:varchar(255);not null"`
 	Name          string         `gorm:"type:varchar(100);not null"`
 	Phone         string         `gorm:"type:varchar(20)"`
-	Role          string         `gorm:"type:varchar(20);not null;default:'user'"` // user 或 admin
+	Role          string         `gorm:"type:varchar(20);not null;default:'user'"` // 見 role.go 的角色常數
 	TLSFingerprint string        `gorm:"type:varchar(255)"`
+	MFASecret     string         `gorm:"type:varchar(255)"` // 加密後的 TOTP 密鑰
+	MFAEnabledAt  *time.Time     `gorm:""`
+	MFALastUsedStep int64        `gorm:"not null;default:0"` // 最後使用的 TOTP 時間步，防止驗證碼重放
+	IsServiceAccount bool        `gorm:"not null;default:false"` // 服務帳號僅能以 API 金鑰存取
 	CreatedAt     time.Time      `gorm:"not null;default:now()"`
 	UpdatedAt     time.Time      `gorm:"not null;default:now()"`
 	DeletedAt     gorm.DeletedAt `gorm:"index"`
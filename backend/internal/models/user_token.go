@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 用戶令牌用途
+const (
+	UserTokenPurposeEmailChange   = "email_change"
+	UserTokenPurposePasswordReset = "password_reset"
+)
+
+// UserToken 寄送給用戶的一次性令牌，資料庫僅保存 SHA-256 雜湊
+type UserToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Purpose   string     `gorm:"type:varchar(30);not null"`
+	TokenHash string     `gorm:"type:varchar(64);not null;unique"`
+	NewEmail  string     `gorm:"type:varchar(255)"` // 變更電子郵件時的新地址
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:""`
+	CreatedAt time.Time  `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (t *UserToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WalletPass 電子錢包票券模型，記錄 Apple Wallet 更新所需的序號與驗證令牌
+type WalletPass struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TicketID            uuid.UUID `gorm:"type:uuid;not null;unique"`
+	SerialNumber        string    `gorm:"type:varchar(100);not null;unique"`
+	AuthenticationToken string    `gorm:"type:varchar(64);not null"`
+	CreatedAt           time.Time `gorm:"not null;default:now()"`
+	UpdatedAt           time.Time `gorm:"not null;default:now()"` // 票券內容最後變更時間
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (p *WalletPass) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
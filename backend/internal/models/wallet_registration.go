@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WalletRegistration Apple Wallet 裝置註冊模型，用於推播票券更新
+type WalletRegistration struct {
+	ID                      uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	DeviceLibraryIdentifier string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_wallet_registrations_device_serial"`
+	SerialNumber            string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_wallet_registrations_device_serial;index"`
+	PushToken               string    `gorm:"type:varchar(255);not null"`
+	CreatedAt               time.Time `gorm:"not null;default:now()"`
+	UpdatedAt               time.Time `gorm:"not null;default:now()"`
+}
+
+// BeforeCreate 在創建前生成 UUID
+func (r *WalletRegistration) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/apikey"
+	"gorm.io/gorm"
+)
+
+// APIKeyService 處理服務帳號與 API 金鑰的簽發及撤銷
+type APIKeyService struct {
+	DB *gorm.DB
+}
+
+// NewAPIKeyService 創建新的 APIKeyService 實例
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		DB: db,
+	}
+}
+
+// CreateServiceAccount 建立服務帳號；服務帳號沒有可用的密碼，只能以 API 金鑰存取
+func (s *APIKeyService) CreateServiceAccount(req dto.CreateServiceAccountRequest) (*vo.UserResponse, error) {
+	if !models.ValidRole(req.Role) {
+		return nil, errors.New("無效的角色")
+	}
+	// 管理員操作須經兩步驟驗證，無法以 API 金鑰進行
+	if req.Role == models.RoleAdmin {
+		return nil, errors.New("服務帳號不可為管理員")
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	user := models.User{
+		ID:               id,
+		Email:            fmt.Sprintf("svc-%s@service-accounts.invalid", id),
+		EmailVerifiedAt:  &now,
+		PasswordHash:     unusablePasswordHash,
+		Name:             req.Name,
+		Role:             req.Role,
+		IsServiceAccount: true,
+	}
+	if err := s.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(&user), nil
+}
+
+// IssueKey 為用戶或服務帳號簽發 API 金鑰；權限範圍不得超出擁有者角色的權限
+func (s *APIKeyService) IssueKey(createdBy string, req dto.CreateAPIKeyRequest) (*vo.APIKeyCreatedResponse, error) {
+	creatorID, err := uuid.Parse(createdBy)
+	if err != nil {
+		return nil, errors.New("無效的使用者 ID")
+	}
+
+	var owner models.User
+	if err := s.DB.First(&owner, "id = ?", req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("使用者不存在")
+		}
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if seen[scope] {
+			continue
+		}
+		if !models.HasPermission(owner.Role, models.Permission(scope)) {
+			return nil, errors.New("無效的權限範圍")
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("到期時間必須晚於現在")
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.APIKey{
+		UserID:    owner.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: creatorID,
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &vo.APIKeyCreatedResponse{
+		APIKeyResponse: *toAPIKeyResponse(&record),
+		Key:            key,
+	}, nil
+}
+
+// ListKeys 列出 API 金鑰，userID 非空時僅列出該用戶的金鑰
+func (s *APIKeyService) ListKeys(userID string) ([]vo.APIKeyResponse, error) {
+	query := s.DB.Order("created_at DESC")
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var keys []models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]vo.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = *toAPIKeyResponse(&keys[i])
+	}
+	return responses, nil
+}
+
+// RevokeKey 撤銷 API 金鑰，立即生效
+func (s *APIKeyService) RevokeKey(id string) error {
+	keyID, err := uuid.Parse(id)
+	if err != nil {
+		return errors.New("API 金鑰不存在")
+	}
+
+	result := s.DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("API 金鑰不存在")
+	}
+	return nil
+}
+
+// revokeUserAPIKeys 撤銷用戶所有 API 金鑰
+func revokeUserAPIKeys(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// toAPIKeyResponse 轉換 API 金鑰回應
+func toAPIKeyResponse(key *models.APIKey) *vo.APIKeyResponse {
+	scopes := make([]string, 0)
+	for _, scope := range key.ScopeList() {
+		scopes = append(scopes, string(scope))
+	}
+
+	return &vo.APIKeyResponse{
+		ID:         key.ID,
+		UserID:     key.UserID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
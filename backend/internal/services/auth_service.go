@@ -1,8 +1,15 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,45 +18,119 @@ import (
 	"github.com/lipeichen/ticket-getter/internal/dto"
 	"github.com/lipeichen/ticket-getter/internal/models"
 	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 重設密碼連結的有效期限
+	passwordResetTokenTTL = time.Hour
+
+	// 每個電子郵件每小時最多寄送的重設密碼郵件數
+	passwordResetEmailLimit = 3
+
+	// 刷新令牌有效期限，同時也是閒置工作階段的保留時間
+	refreshTokenTTL = 7 * 24 * time.Hour
+
+	// 電子郵件驗證連結的有效期限
+	emailVerificationTTL = 48 * time.Hour
+
+	// 每個帳號每小時最多寄送的驗證郵件數
+	emailVerificationEmailLimit = 3
+
+	// 兩步驟驗證登入請求的有效期限
+	mfaChallengeTTL = 5 * time.Minute
 )
 
 // AuthService 處理認證相關邏輯
 type AuthService struct {
-	DB     *gorm.DB
-	Config *config.Config
+	DB           *gorm.DB
+	RedisClient  *redis.Client
+	Config       *config.Config
+	Mailer       mailer.Mailer
+	MFAService   *MFAService
+	SigningKeys  *jwtkeys.KeySet
+	LockNotifier AccountLockNotifier // 帳號因登入失敗被鎖定時的通知，預設寄送電子郵件
+	Captcha      *CaptchaService     // 註冊及登入失敗後的 CAPTCHA 驗證，未設定時不要求
+	DeviceGraph  *DeviceGraphService // 記錄登入裝置以找出多帳號群組，未設定時不記錄
+	RateLimits   *RateLimitService   // 登入鎖定與郵件寄送頻率限制共用的斷路器，未設定時只使用 Redis
 }
 
 // NewAuthService 創建新的 AuthService 實例
-func NewAuthService(db *gorm.DB, config *config.Config) *AuthService {
+func NewAuthService(db *gorm.DB, redisClient *redis.Client, config *config.Config, mailer mailer.Mailer, signingKeys *jwtkeys.KeySet) *AuthService {
 	return &AuthService{
-		DB:     db,
-		Config: config,
+		DB:           db,
+		RedisClient:  redisClient,
+		Config:       config,
+		Mailer:       mailer,
+		MFAService:   NewMFAService(db, redisClient, config),
+		SigningKeys:  signingKeys,
+		LockNotifier: NewMailAccountLockNotifier(mailer),
 	}
 }
 
-// Login 處理使用者登入
-func (s *AuthService) Login(req dto.LoginRequest) (*vo.LoginResponse, error) {
-	var user models.User
+// Login 處理使用者登入；帳號不存在與密碼錯誤回傳相同錯誤，並依失敗次數延遲或暫時鎖定帳號與 IP
+func (s *AuthService) Login(req dto.LoginRequest, client dto.ClientInfo) (*vo.LoginResponse, error) {
+	ctx := context.Background()
+	email := strings.ToLower(strings.TrimSpace(req.Email))
 	
 	// 查找使用者
-	result := s.DB.Where("email = ?", req.Email).First(&user)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("使用者不存在")
-		}
+	var user *models.User
+	var found models.User
+	result := s.DB.Where("LOWER(email) = ?", email).First(&found)
+	if result.Error == nil {
+		user = &found
+	} else if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, result.Error
 	}
 	
-	// 驗證密碼
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	// 鎖定或延遲期間內不驗證密碼；無法確認鎖定狀態時拒絕登入
+	failures, err := s.loginFailureCount(ctx, email)
+	if err != nil {
+		log.Printf("讀取登入失敗次數失敗: %v", err)
+		return nil, errors.New("登入服務暫時無法使用，請稍後再試")
+	}
+	allowed, err := s.loginAllowed(ctx, email, client.IPAddress, failures)
 	if err != nil {
-		return nil, errors.New("密碼不正確")
+		log.Printf("檢查登入鎖定狀態失敗: %v", err)
+		return nil, errors.New("登入服務暫時無法使用，請稍後再試")
+	}
+	if !allowed {
+		s.recordLoginAttempt(user, email, client, models.LoginResultThrottled)
+		return nil, errors.New("登入失敗次數過多，請稍後再試")
+	}
+	
+	// 失敗次數達門檻後須先完成 CAPTCHA
+	if s.Captcha.RequiredForLogin(failures) {
+		if err := s.Captcha.Verify(ctx, req.CaptchaToken, client.IPAddress); err != nil {
+			s.recordLoginAttempt(user, email, client, models.LoginResultCaptchaFailed)
+			return nil, err
+		}
+	}
+	
+	// 驗證密碼；帳號不存在或未設定密碼時仍比對一次雜湊，避免以回應時間判斷帳號是否存在
+	passwordHash := dummyPasswordHash()
+	if user != nil && user.PasswordHash != unusablePasswordHash {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password)); err != nil || user == nil || user.PasswordHash == unusablePasswordHash {
+		s.recordLoginFailure(ctx, user, email, client)
+		return nil, errors.New("電子郵件或密碼不正確")
+	}
+	s.recordLoginSuccess(ctx, user, email, client)
+	
+	// 已啟用兩步驟驗證或強制啟用的帳號，需先完成驗證才簽發令牌
+	if s.MFAService.Required(user) {
+		return s.mfaChallengeResponse(user)
 	}
 	
 	// 生成令牌
-	token, refreshToken, err := s.generateTokens(user.ID.String(), user.Role)
+	token, refreshToken, err := s.startSession(user, client, false)
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +140,13 @@ func (s *AuthService) Login(req dto.LoginRequest) (*vo.LoginResponse, error) {
 		Token:        token,
 		RefreshToken: refreshToken,
 		User: vo.UserResponse{
-			ID:    user.ID.String(),
-			Name:  user.Name,
-			Email: user.Email,
-			Phone: user.Phone,
-			Role:  user.Role,
+			ID:            user.ID.String(),
+			Name:          user.Name,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			MFAEnabled:    user.MFAEnabledAt != nil,
 		},
 	}
 	
@@ -71,10 +154,18 @@ func (s *AuthService) Login(req dto.LoginRequest) (*vo.LoginResponse, error) {
 }
 
 // Register 處理使用者註冊
-func (s *AuthService) Register(req dto.RegisterRequest) (*vo.RegisterResponse, error) {
-	// 檢查使用者是否已存在
+func (s *AuthService) Register(req dto.RegisterRequest, client dto.ClientInfo) (*vo.RegisterResponse, error) {
+	// 先完成 CAPTCHA，避免大量註冊帳號
+	if s.Captcha.RequiredForRegistration() {
+		if err := s.Captcha.Verify(context.Background(), req.CaptchaToken, client.IPAddress); err != nil {
+			return nil, err
+		}
+	}
+	
+	// 檢查使用者是否已存在，電子郵件不分大小寫
+	email := strings.ToLower(strings.TrimSpace(req.Email))
 	var existingUser models.User
-	result := s.DB.Where("email = ?", req.Email).First(&existingUser)
+	result := s.DB.Where("LOWER(email) = ?", email).First(&existingUser)
 	if result.Error == nil {
 		return nil, errors.New("使用者已存在")
 	} else if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -89,11 +180,11 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*vo.RegisterResponse, e
 	
 	// 創建新使用者
 	user := models.User{
-		Email:        req.Email,
+		Email:        email,
 		PasswordHash: string(hashedPassword),
 		Name:         req.Name,
 		Phone:        req.Phone,
-		Role:         "user", // 預設為普通用戶
+		Role:         models.RoleUser, // 預設為普通用戶
 	}
 	
 	// 儲存使用者
@@ -101,8 +192,13 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*vo.RegisterResponse, e
 		return nil, err
 	}
 	
+	// 寄送驗證郵件，驗證完成前無法購票
+	if err := s.sendVerificationEmail(context.Background(), &user); err != nil {
+		log.Printf("寄送驗證郵件失敗: %v", err)
+	}
+	
 	// 生成令牌
-	token, refreshToken, err := s.generateTokens(user.ID.String(), user.Role)
+	token, refreshToken, err := s.startSession(&user, client, false)
 	if err != nil {
 		return nil, err
 	}
@@ -112,11 +208,13 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*vo.RegisterResponse, e
 		Token:        token,
 		RefreshToken: refreshToken,
 		User: vo.UserResponse{
-			ID:    user.ID.String(),
-			Name:  user.Name,
-			Email: user.Email,
-			Phone: user.Phone,
-			Role:  user.Role,
+			ID:            user.ID.String(),
+			Name:          user.Name,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			MFAEnabled:    user.MFAEnabledAt != nil,
 		},
 	}
 	
@@ -141,86 +239,627 @@ func (s *AuthService) GetUserByID(id string) (*vo.UserResponse, error) {
 	}
 	
 	response := &vo.UserResponse{
-		ID:    user.ID.String(),
-		Name:  user.Name,
-		Email: user.Email,
-		Phone: user.Phone,
-		Role:  user.Role,
+		ID:            user.ID.String(),
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		MFAEnabled:    user.MFAEnabledAt != nil,
 	}
 	
 	return response, nil
 }
 
-// RefreshToken 刷新令牌
-func (s *AuthService) RefreshToken(refreshToken string) (string, string, error) {
+// RefreshToken 輪替刷新令牌：舊令牌作廢並簽發新令牌對；已輪替的令牌再次被使用時撤銷整個工作階段
+func (s *AuthService) RefreshToken(refreshToken string, client dto.ClientInfo) (string, string, error) {
 	// 解析刷新令牌
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		// 檢查簽名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("無效的簽名方法: %v", token.Header["alg"])
-		}
-		return []byte(s.Config.JWTSecret), nil
-	})
+	claims := &refreshClaims{}
+	token, err := s.SigningKeys.Parse(refreshToken, claims)
 	
-	if err != nil {
+	if err != nil || !token.Valid {
 		return "", "", errors.New("無效的刷新令牌")
 	}
 	
-	// 檢查令牌有效性
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 獲取使用者 ID 和角色
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			return "", "", errors.New("無效的令牌聲明")
+	// 存取令牌不能當作刷新令牌使用
+	if claims.TokenType != models.TokenTypeRefresh {
+		return "", "", errors.New("無效的令牌聲明")
+	}
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return "", "", errors.New("無效的令牌聲明")
+	}
+
+	var newToken, newRefreshToken string
+	reused := false
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stored, jti).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("無效的刷新令牌")
+			}
+			return err
+		}
+
+		var session models.Session
+		if err := tx.First(&session, stored.SessionID).Error; err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return errors.New("無效的刷新令牌")
 		}
-		
-		role, ok := claims["role"].(string)
-		if !ok {
-			return "", "", errors.New("無效的令牌聲明")
+
+		// 已輪替的令牌再次出現，代表令牌可能遭竊，撤銷整個令牌家族
+		if stored.UsedAt != nil {
+			reused = true
+			return tx.Model(&session).Update("revoked_at", time.Now()).Error
+		}
+		if time.Now().After(stored.ExpiresAt) {
+			return errors.New("無效的刷新令牌")
+		}
+
+		if err := tx.Model(&stored).Update("used_at", time.Now()).Error; err != nil {
+			return err
 		}
-		
-		// 生成新的令牌對
-		newToken, newRefreshToken, err := s.generateTokens(userID, role)
-		if err != nil {
-			return "", "", err
+
+		// 更新裝置最後活動資訊
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"user_agent":   client.UserAgent,
+			"ip_address":   client.IPAddress,
+			"last_seen_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// 重新讀取使用者，確保帳號仍存在並取得最新角色
+		var user models.User
+		if err := tx.First(&user, stored.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("無效的刷新令牌")
+			}
+			return err
 		}
-		
-		return newToken, newRefreshToken, nil
+
+		var err error
+		newToken, newRefreshToken, err = s.generateTokens(tx, user.ID, user.Role, &session)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if reused {
+		return "", "", errors.New("刷新令牌已被重複使用，請重新登入")
 	}
 	
-	return "", "", errors.New("無效的刷新令牌")
+	return newToken, newRefreshToken, nil
+}
+
+// Logout 撤銷工作階段，使其刷新令牌無法再使用
+func (s *AuthService) Logout(sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return errors.New("無效的工作階段")
+	}
+
+	return s.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ListSessions 列出用戶目前有效的登入工作階段
+func (s *AuthService) ListSessions(userID string, currentSessionID string) ([]vo.SessionResponse, error) {
+	var sessions []models.Session
+	if err := s.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, time.Now().Add(-refreshTokenTTL)).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]vo.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = vo.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID.String() == currentSessionID,
+		}
+	}
+
+	return responses, nil
+}
+
+// RevokeSession 撤銷用戶的指定工作階段，該裝置的令牌將立即失效
+func (s *AuthService) RevokeSession(userID string, sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return errors.New("工作階段不存在")
+	}
+
+	result := s.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("工作階段不存在")
+	}
+
+	return nil
+}
+
+// CompleteMFALogin 以驗證碼或復原碼完成兩步驟驗證登入；尚未設定的強制帳號在此完成啟用
+func (s *AuthService) CompleteMFALogin(ctx context.Context, req dto.MFALoginRequest, client dto.ClientInfo) (*vo.LoginResponse, error) {
+	claims, user, err := s.parseMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabledAt == nil {
+		recoveryCodes, err = s.MFAService.enable(user, req.Code)
+	} else {
+		err = s.MFAService.Verify(user, req.Code, req.RecoveryCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 驗證請求僅能使用一次
+	consumed, err := s.RedisClient.SetNX(ctx, "mfa_challenge_used:"+claims.ID, 1, mfaChallengeTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errors.New("無效或已過期的驗證請求")
+	}
+
+	token, refreshToken, err := s.startSession(user, client, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vo.LoginResponse{
+		Token:         token,
+		RefreshToken:  refreshToken,
+		User:          *toUserResponse(user),
+		RecoveryCodes: recoveryCodes,
+	}, nil
 }
 
-// 生成 JWT 令牌
-func (s *AuthService) generateTokens(userID string, role string) (string, string, error) {
+// SetupMFAChallenge 強制啟用兩步驟驗證的帳號在登入過程中取得 TOTP 密鑰
+func (s *AuthService) SetupMFAChallenge(req dto.MFALoginSetupRequest) (*vo.MFASetupResponse, error) {
+	_, user, err := s.parseMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("兩步驟驗證已啟用")
+	}
+
+	return s.MFAService.setup(user)
+}
+
+// mfaChallengeResponse 簽發短效的兩步驟驗證請求令牌，此時尚不建立工作階段
+func (s *AuthService) mfaChallengeResponse(user *models.User) (*vo.LoginResponse, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": user.ID.String(),
+		"typ":     models.TokenTypeMFAChallenge,
+		"jti":     uuid.New().String(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(mfaChallengeTTL).Unix(),
+	}
+	token, err := s.SigningKeys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vo.LoginResponse{
+		User:                  *toUserResponse(user),
+		MFARequired:           true,
+		MFAEnrollmentRequired: user.MFAEnabledAt == nil,
+		MFAToken:              token,
+	}, nil
+}
+
+// parseMFAChallenge 驗證兩步驟驗證請求令牌並讀取對應用戶
+func (s *AuthService) parseMFAChallenge(tokenString string) (*refreshClaims, *models.User, error) {
+	invalid := errors.New("無效或已過期的驗證請求")
+
+	claims := &refreshClaims{}
+	token, err := s.SigningKeys.Parse(tokenString, claims)
+	if err != nil || !token.Valid || claims.TokenType != models.TokenTypeMFAChallenge || claims.ID == "" {
+		return nil, nil, invalid
+	}
+
+	var user models.User
+	if err := s.DB.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, invalid
+		}
+		return nil, nil, err
+	}
+	if !s.MFAService.Required(&user) {
+		return nil, nil, invalid
+	}
+
+	return claims, &user, nil
+}
+
+// ForgotPassword 在背景寄送重設密碼連結；電子郵件是否存在或寄送是否失敗都不影響回應與回應時間，避免洩漏帳號資訊
+func (s *AuthService) ForgotPassword(req dto.ForgotPasswordRequest) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	go func() {
+		if err := s.sendPasswordReset(context.Background(), email); err != nil {
+			log.Printf("處理重設密碼請求失敗: %v", err)
+		}
+	}()
+}
+
+// sendPasswordReset 簽發重設令牌並寄送重設密碼郵件，電子郵件未註冊時不做任何事
+func (s *AuthService) sendPasswordReset(ctx context.Context, email string) error {
+	var user models.User
+	if err := s.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 限制同一電子郵件的寄送頻率，避免被用來濫發郵件
+	allowed, _, _, err := s.rateLimiter("password_reset").Allow(ctx, user.ID.String(), passwordResetEmailLimit, time.Hour)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return nil
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// 新的重設連結寄出後，舊連結即失效
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, models.UserTokenPurposePasswordReset).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   models.UserTokenPurposePasswordReset,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.Config.FrontendURL, token)
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "重設您的密碼",
+		Body:    fmt.Sprintf("您好 %s，\n\n請於 1 小時內點擊以下連結重設密碼：\n%s\n\n若您沒有提出此請求，請忽略此郵件，您的密碼不會變更。", user.Name, link),
+	})
+}
+
+// ResetPassword 使用重設令牌設定新密碼，並撤銷該用戶所有既有的工作階段
+func (s *AuthService) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var userToken models.UserToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND purpose = ?", hashToken(req.Token), models.UserTokenPurposePasswordReset).
+			First(&userToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("無效或已過期的令牌")
+			}
+			return err
+		}
+		if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+			return errors.New("無效或已過期的令牌")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&userToken).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ?", userToken.UserID).
+			Updates(map[string]interface{}{
+				"password_hash": string(hashedPassword),
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("無效或已過期的令牌")
+		}
+
+		// 撤銷工作階段後，其存取令牌與刷新令牌一併失效
+		return revokeUserSessions(tx, userToken.UserID)
+	})
+}
+
+// VerifyEmail 驗證電子郵件連結中的簽章令牌，並標記電子郵件為已驗證
+func (s *AuthService) VerifyEmail(token string) (*vo.UserResponse, error) {
+	userID, email, err := s.parseEmailVerificationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("無效或已過期的令牌")
+		}
+		return nil, err
+	}
+
+	// 寄出連結後若已變更電子郵件，舊連結即失效
+	if !strings.EqualFold(user.Email, email) {
+		return nil, errors.New("無效或已過期的令牌")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.DB.Model(&user).Updates(map[string]interface{}{
+			"email_verified_at": now,
+			"updated_at":        now,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return toUserResponse(&user), nil
+}
+
+// ResendVerification 重新寄送驗證郵件給當前用戶
+func (s *AuthService) ResendVerification(ctx context.Context, userID string) error {
+	var user models.User
+	if err := s.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("使用者不存在")
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return errors.New("電子郵件已驗證")
+	}
+
+	allowed, err := s.allowVerificationEmail(ctx, &user)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("請求過於頻繁，請稍後再試")
+	}
+
+	return s.sendVerificationEmail(ctx, &user)
+}
+
+// ResendVerificationByEmail 依電子郵件重新寄送驗證郵件；不回報帳號是否存在或已驗證
+func (s *AuthService) ResendVerificationByEmail(ctx context.Context, req dto.ResendVerificationRequest) error {
+	var user models.User
+	if err := s.DB.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	allowed, err := s.allowVerificationEmail(ctx, &user)
+	if err != nil || !allowed {
+		return err
+	}
+
+	if err := s.sendVerificationEmail(ctx, &user); err != nil {
+		log.Printf("寄送驗證郵件失敗: %v", err)
+	}
+	return nil
+}
+
+// allowVerificationEmail 限制同一帳號的驗證郵件寄送頻率
+func (s *AuthService) allowVerificationEmail(ctx context.Context, user *models.User) (bool, error) {
+	allowed, _, _, err := s.rateLimiter("email_verification").Allow(ctx, user.ID.String(), emailVerificationEmailLimit, time.Hour)
+	return allowed, err
+}
+
+// rateLimiter 取得以 prefix 計數的限制器；設定 RateLimits 時 Redis 故障會改用程序內計數
+func (s *AuthService) rateLimiter(prefix string) limiter.Limiter {
+	if s.RateLimits != nil {
+		return s.RateLimits.Limiter(prefix)
+	}
+	return limiter.NewRateLimiter(s.RedisClient, prefix)
+}
+
+// sendVerificationEmail 寄送含簽章令牌的驗證連結
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token := s.signEmailVerificationToken(user.ID, user.Email, time.Now().Add(emailVerificationTTL))
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.Config.FrontendURL, token)
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "請驗證您的電子郵件",
+		Body:    fmt.Sprintf("您好 %s，\n\n感謝您註冊！請於 48 小時內點擊以下連結驗證電子郵件，驗證完成後即可購票：\n%s", user.Name, link),
+	})
+}
+
+// signEmailVerificationToken 產生電子郵件驗證令牌：base64url(用戶ID|電子郵件|到期時間).base64url(HMAC-SHA256)
+func (s *AuthService) signEmailVerificationToken(userID uuid.UUID, email string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s|%s|%d", userID, email, expiresAt.Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.emailVerificationMAC(encoded))
+}
+
+// parseEmailVerificationToken 驗證簽章與到期時間，返回用戶 ID 與電子郵件
+func (s *AuthService) parseEmailVerificationToken(token string) (uuid.UUID, string, error) {
+	invalid := errors.New("無效或已過期的令牌")
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return uuid.Nil, "", invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.emailVerificationMAC(encoded)) {
+		return uuid.Nil, "", invalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return uuid.Nil, "", invalid
+	}
+	// 電子郵件本身可能含有 "|"，因此從兩端切割
+	id, rest, found := strings.Cut(string(payload), "|")
+	sep := strings.LastIndex(rest, "|")
+	if !found || sep < 0 {
+		return uuid.Nil, "", invalid
+	}
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", invalid
+	}
+	expiresAt, err := strconv.ParseInt(rest[sep+1:], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return uuid.Nil, "", invalid
+	}
+
+	return userID, rest[:sep], nil
+}
+
+// emailVerificationMAC 計算驗證令牌的 HMAC，加入用途前綴避免與其他簽章混用
+func (s *AuthService) emailVerificationMAC(payload string) []byte {
+	h := hmac.New(sha256.New, []byte(s.Config.JWTSecret))
+	h.Write([]byte("email-verification:" + payload))
+	return h.Sum(nil)
+}
+
+// refreshClaims 刷新令牌與兩步驟驗證請求令牌的聲明
+type refreshClaims struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// startSession 建立新的登入工作階段並簽發第一組令牌
+func (s *AuthService) startSession(user *models.User, client dto.ClientInfo, mfaVerified bool) (string, string, error) {
+	var token, refreshToken string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			UserID:         user.ID,
+			UserAgent:      client.UserAgent,
+			IPAddress:      client.IPAddress,
+			TLSFingerprint: client.TLSFingerprint,
+			LastSeenAt:     time.Now(),
+			MFAVerified:    mfaVerified,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		token, refreshToken, err = s.generateTokens(tx, user.ID, user.Role, &session)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	// 記錄登入裝置；失敗不影響登入
+	if s.DeviceGraph != nil {
+		if err := s.DeviceGraph.Record(context.Background(), user.ID, client); err != nil {
+			log.Printf("記錄登入裝置失敗: %v", err)
+		}
+	}
+
+	return token, refreshToken, nil
+}
+
+// revokeUserSessions 撤銷用戶所有工作階段
+func revokeUserSessions(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeOtherSessions 撤銷用戶除目前工作階段以外的所有工作階段，刷新令牌隨工作階段一併失效
+func revokeOtherSessions(tx *gorm.DB, userID uuid.UUID, currentSessionID string) error {
+	current, err := uuid.Parse(currentSessionID)
+	if err != nil {
+		return revokeUserSessions(tx, userID)
+	}
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, current).
+		Update("revoked_at", time.Now()).Error
+}
+
+// 生成 JWT 令牌，並記錄刷新令牌的 jti 以便輪替與撤銷
+func (s *AuthService) generateTokens(tx *gorm.DB, userID uuid.UUID, role string, session *models.Session) (string, string, error) {
 	// 設置令牌過期時間
-	expiresAt := time.Now().Add(time.Hour * time.Duration(s.Config.JWTExpiryHours))
-	refreshExpiresAt := time.Now().Add(time.Hour * 24 * 7) // 一週
+	now := time.Now()
+	expiresAt := now.Add(time.Hour * time.Duration(s.Config.JWTExpiryHours))
+	refreshExpiresAt := now.Add(refreshTokenTTL)
 	
 	// 創建令牌聲明
 	claims := jwt.MapClaims{
-		"user_id": userID,
+		"user_id": userID.String(),
 		"role":    role,
+		"sid":     session.ID.String(),
+		"typ":     models.TokenTypeAccess,
+		"mfa":     session.MFAVerified,
+		"iat":     now.Unix(),
 		"exp":     expiresAt.Unix(),
 	}
 	
-	// 創建令牌
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.Config.JWTSecret))
+	// 以啟用中的金鑰簽署令牌
+	tokenString, err := s.SigningKeys.Sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 	
-	// 創建刷新令牌聲明
+	// 記錄刷新令牌
+	stored := models.RefreshToken{
+		SessionID: session.ID,
+		UserID:    userID,
+		ExpiresAt: refreshExpiresAt,
+	}
+	if err := tx.Create(&stored).Error; err != nil {
+		return "", "", err
+	}
+	
+	// 創建刷新令牌聲明（不含角色，刷新時從資料庫重新讀取）
 	refreshClaims := jwt.MapClaims{
-		"user_id": userID,
-		"role":    role,
+		"user_id": userID.String(),
+		"sid":     session.ID.String(),
+		"typ":     models.TokenTypeRefresh,
+		"jti":     stored.ID.String(),
+		"iat":     now.Unix(),
 		"exp":     refreshExpiresAt.Unix(),
 	}
 	
-	// 創建刷新令牌
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.Config.JWTSecret))
+	// 簽署刷新令牌
+	refreshTokenString, err := s.SigningKeys.Sign(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
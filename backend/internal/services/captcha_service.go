@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/pkg/captcha"
+	"github.com/redis/go-redis/v9"
+)
+
+// CAPTCHA 驗證碼結果的保留時間，長於各服務驗證碼本身的有效期限
+const captchaResultTTL = 10 * time.Minute
+
+// captchaFakeToken 使用 fake 驗證器時唯一接受的驗證碼
+const captchaFakeToken = "captcha-test-pass"
+
+// CaptchaVerifier CAPTCHA 驗證服務
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token string, remoteIP string) (*captcha.Result, error)
+}
+
+// CaptchaService 依設定或風險等級要求 CAPTCHA，並以 Redis 記錄每個驗證碼的結果以阻擋重複使用
+type CaptchaService struct {
+	RedisClient *redis.Client
+	Verifier    CaptchaVerifier
+	Config      *config.Config
+}
+
+// NewCaptchaService 創建新的 CaptchaService 實例；未設定 CAPTCHA_PROVIDER 時停用
+func NewCaptchaService(redisClient *redis.Client, cfg *config.Config) (*CaptchaService, error) {
+	var verifier CaptchaVerifier
+	switch cfg.CaptchaProvider {
+	case "":
+	case "hcaptcha":
+		verifier = captcha.NewHCaptcha(cfg.CaptchaSecret)
+	case "turnstile":
+		verifier = captcha.NewTurnstile(cfg.CaptchaSecret)
+	case "recaptcha":
+		verifier = captcha.NewReCAPTCHA(cfg.CaptchaSecret, cfg.CaptchaMinScore)
+	case "fake":
+		if cfg.Environment == "production" {
+			return nil, errors.New("生產環境不可使用 fake CAPTCHA")
+		}
+		verifier = captcha.NewFake(captchaFakeToken)
+	default:
+		return nil, fmt.Errorf("不支援的 CAPTCHA 服務: %s", cfg.CaptchaProvider)
+	}
+
+	return &CaptchaService{
+		RedisClient: redisClient,
+		Verifier:    verifier,
+		Config:      cfg,
+	}, nil
+}
+
+// Enabled 是否已設定 CAPTCHA 驗證服務
+func (s *CaptchaService) Enabled() bool {
+	return s != nil && s.Verifier != nil
+}
+
+// RequiredForRegistration 註冊是否須完成 CAPTCHA
+func (s *CaptchaService) RequiredForRegistration() bool {
+	return s.Enabled() && s.Config.CaptchaOnRegister
+}
+
+// RequiredForLogin 帳號登入失敗次數達門檻後須完成 CAPTCHA
+func (s *CaptchaService) RequiredForLogin(failures int) bool {
+	return s.Enabled() && s.Config.CaptchaLoginAfterFailures > 0 && failures >= s.Config.CaptchaLoginAfterFailures
+}
+
+// RequiredForPurchase 購票是否須完成 CAPTCHA：設定為一律要求，或風險評估結果為 challenge
+func (s *CaptchaService) RequiredForPurchase(decision RiskDecision) bool {
+	return s.Enabled() && (s.Config.CaptchaOnPurchase || decision == RiskChallenge)
+}
+
+// Verify 驗證 CAPTCHA 驗證碼；每個驗證碼只能使用一次，結果保留於 Redis 供稽核
+func (s *CaptchaService) Verify(ctx context.Context, token string, remoteIP string) error {
+	if token == "" {
+		return errors.New("需要完成 CAPTCHA 驗證")
+	}
+
+	// 先佔用驗證碼，避免同一驗證碼在驗證期間被並行使用
+	key := captchaTokenKey(token)
+	claimed, err := s.RedisClient.SetNX(ctx, key, "pending", captchaResultTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errors.New("CAPTCHA 驗證碼已使用")
+	}
+
+	result, err := s.Verifier.Verify(ctx, token, remoteIP)
+	if err != nil {
+		// 驗證服務錯誤時釋放驗證碼，允許客戶端重試
+		if delErr := s.RedisClient.Del(ctx, key).Err(); delErr != nil {
+			log.Printf("釋放 CAPTCHA 驗證碼失敗: %v", delErr)
+		}
+		return err
+	}
+
+	if data, err := json.Marshal(result); err == nil {
+		if err := s.RedisClient.Set(ctx, key, data, captchaResultTTL).Err(); err != nil {
+			log.Printf("記錄 CAPTCHA 驗證結果失敗: %v", err)
+		}
+	}
+
+	if !result.Success {
+		return errors.New("CAPTCHA 驗證失敗")
+	}
+	return nil
+}
+
+// captchaTokenKey 驗證碼結果的 Redis key，以雜湊避免保存驗證碼本身
+func captchaTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "captcha:token:" + hex.EncodeToString(sum[:])
+}
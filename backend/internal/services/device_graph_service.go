@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 只採用此期間內出現過的關聯計算群組
+	deviceLinkWindow = 30 * 24 * time.Hour
+
+	// 群組帳號數上限，超過即停止展開
+	deviceClusterMaxUsers = 100
+
+	// 共用同一識別值的帳號超過此數時不視為關聯（如電信業者共用 IP、公用電腦）
+	deviceLinkMaxSharedUsers = 50
+
+	// 群組 ID 快取時間
+	deviceClusterCacheTTL = 10 * time.Minute
+)
+
+// DeviceGraphService 記錄用戶與裝置、IP、付款方式的關聯，找出疑似同一人操作的帳號群組
+type DeviceGraphService struct {
+	DB          *gorm.DB
+	RedisClient *redis.Client
+}
+
+// NewDeviceGraphService 創建新的 DeviceGraphService 實例
+func NewDeviceGraphService(db *gorm.DB, redisClient *redis.Client) *DeviceGraphService {
+	return &DeviceGraphService{
+		DB:          db,
+		RedisClient: redisClient,
+	}
+}
+
+// Record 記錄登入或購票時的裝置指紋與 IP，並更新用戶最近一次的 TLS 指紋
+func (s *DeviceGraphService) Record(ctx context.Context, userID uuid.UUID, client dto.ClientInfo) error {
+	if client.TLSFingerprint != "" {
+		if err := s.link(ctx, userID, models.DeviceLinkDevice, client.TLSFingerprint); err != nil {
+			return err
+		}
+		if err := s.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+			Update("tls_fingerprint", client.TLSFingerprint).Error; err != nil {
+			return err
+		}
+	}
+	if client.IPAddress != "" {
+		if err := s.link(ctx, userID, models.DeviceLinkIP, client.IPAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkPaymentMethod 記錄付款方式指紋，於訂單確認收款後呼叫；fingerprint 須為金流服務提供的代表值，不可為卡號
+func (s *DeviceGraphService) LinkPaymentMethod(ctx context.Context, userID uuid.UUID, fingerprint string) error {
+	if fingerprint == "" {
+		return nil
+	}
+	return s.link(ctx, userID, models.DeviceLinkPayment, fingerprint)
+}
+
+// link 新增關聯或更新最後出現時間
+func (s *DeviceGraphService) link(ctx context.Context, userID uuid.UUID, kind string, value string) error {
+	now := time.Now()
+
+	// 已存在且仍在期間內的關聯不改變群組，只需更新出現時間
+	var existing []models.DeviceLink
+	if err := s.DB.WithContext(ctx).Select("last_seen_at").
+		Where("user_id = ? AND kind = ? AND value = ?", userID, kind, value).
+		Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	changed := len(existing) == 0 || existing[0].LastSeenAt.Before(now.Add(-deviceLinkWindow))
+
+	link := models.DeviceLink{
+		UserID:      userID,
+		Kind:        kind,
+		Value:       value,
+		SeenCount:   1,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "kind"}, {Name: "value"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_seen_at": now,
+			"seen_count":   gorm.Expr("device_links.seen_count + 1"),
+		}),
+	}).Create(&link).Error
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		s.invalidateCluster(ctx, userID)
+	}
+	return nil
+}
+
+// invalidateCluster 新的關聯可能合併群組，清除合併後群組內每個帳號的群組 ID 快取
+func (s *DeviceGraphService) invalidateCluster(ctx context.Context, userID uuid.UUID) {
+	userIDs, _, _, err := s.expand(ctx, userID)
+	if err != nil {
+		log.Printf("查詢裝置群組失敗: %v", err)
+		userIDs = []uuid.UUID{userID}
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = deviceClusterKey(id.String())
+	}
+	if err := s.RedisClient.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("清除裝置群組快取失敗: %v", err)
+	}
+}
+
+// ClusterID 返回用戶所屬群組的 ID（群組中最小的用戶 ID），結果快取於 Redis
+//
+// 群組超過 deviceClusterMaxUsers 時每次只展開部分帳號，自不同帳號出發會得到不同的最小 ID；
+// 因此計算時一併採用已展開帳號快取中的 ID 取最小值，並寫回所有已展開的帳號，使同一群組的帳號收斂到相同 ID。
+func (s *DeviceGraphService) ClusterID(ctx context.Context, userID string) (string, error) {
+	key := deviceClusterKey(userID)
+	if clusterID, err := s.RedisClient.Get(ctx, key).Result(); err == nil {
+		return clusterID, nil
+	} else if err != redis.Nil {
+		log.Printf("讀取裝置群組快取失敗: %v", err)
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return "", errors.New("無效的使用者 ID")
+	}
+	userIDs, _, _, err := s.expand(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, memberID := range userIDs {
+		keys[i] = deviceClusterKey(memberID.String())
+	}
+
+	clusterID := userIDs[0].String()
+	cached, err := s.RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.Printf("讀取裝置群組快取失敗: %v", err)
+	}
+	for _, value := range cached {
+		if other, ok := value.(string); ok && other < clusterID {
+			clusterID = other
+		}
+	}
+
+	pipe := s.RedisClient.Pipeline()
+	for _, memberKey := range keys {
+		pipe.Set(ctx, memberKey, clusterID, deviceClusterCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("寫入裝置群組快取失敗: %v", err)
+	}
+	return clusterID, nil
+}
+
+// Cluster 返回用戶所屬群組的帳號與關聯，供管理員檢視
+func (s *DeviceGraphService) Cluster(ctx context.Context, userID string) (*vo.DeviceClusterResponse, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("使用者不存在")
+	}
+	if err := s.DB.WithContext(ctx).Select("id").First(&models.User{}, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("使用者不存在")
+		}
+		return nil, err
+	}
+
+	userIDs, links, truncated, err := s.expand(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := s.DB.WithContext(ctx).Where("id IN ?", userIDs).Order("created_at").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	response := &vo.DeviceClusterResponse{
+		ClusterID: userIDs[0].String(),
+		Users:     make([]vo.DeviceClusterUser, len(users)),
+		Links:     make([]vo.DeviceLinkResponse, len(links)),
+		Truncated: truncated,
+	}
+	for i, user := range users {
+		response.Users[i] = vo.DeviceClusterUser{
+			ID:             user.ID.String(),
+			Email:          user.Email,
+			Name:           user.Name,
+			TLSFingerprint: user.TLSFingerprint,
+			CreatedAt:      user.CreatedAt,
+		}
+	}
+	for i := range links {
+		response.Links[i] = toDeviceLinkResponse(&links[i])
+	}
+	return response, nil
+}
+
+// SharedIdentifiers 列出期間內被至少 minAccounts 個帳號共用的識別值，kind 為空時列出所有類型
+func (s *DeviceGraphService) SharedIdentifiers(ctx context.Context, kind string, minAccounts int, limit int) ([]vo.SharedIdentifierResponse, error) {
+	query := s.DB.WithContext(ctx).Model(&models.DeviceLink{}).
+		Select("kind, value, COUNT(DISTINCT user_id) AS accounts, MAX(last_seen_at) AS last_seen_at").
+		Where("last_seen_at > ?", time.Now().Add(-deviceLinkWindow))
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	results := make([]vo.SharedIdentifierResponse, 0)
+	err := query.Group("kind, value").
+		Having("COUNT(DISTINCT user_id) >= ?", minAccounts).
+		Order("accounts DESC").
+		Limit(limit).
+		Scan(&results).Error
+	return results, err
+}
+
+// expand 自用戶出發，經共用識別值逐層找出同群組的帳號；返回排序後的用戶 ID、相關關聯及是否因超過上限而截斷
+func (s *DeviceGraphService) expand(ctx context.Context, root uuid.UUID) ([]uuid.UUID, []models.DeviceLink, bool, error) {
+	since := time.Now().Add(-deviceLinkWindow)
+	visited := map[uuid.UUID]bool{root: true}
+	expanded := map[string]bool{}
+	frontier := []uuid.UUID{root}
+	var links []models.DeviceLink
+	truncated := false
+
+	for len(frontier) > 0 && !truncated {
+		var userLinks []models.DeviceLink
+		if err := s.DB.WithContext(ctx).Where("user_id IN ? AND last_seen_at > ?", frontier, since).Find(&userLinks).Error; err != nil {
+			return nil, nil, false, err
+		}
+
+		var next []uuid.UUID
+		for _, link := range userLinks {
+			key := link.Kind + ":" + link.Value
+			if expanded[key] {
+				continue
+			}
+			expanded[key] = true
+
+			var shared []models.DeviceLink
+			if err := s.DB.WithContext(ctx).
+				Where("kind = ? AND value = ? AND last_seen_at > ?", link.Kind, link.Value, since).
+				Limit(deviceLinkMaxSharedUsers + 1).
+				Find(&shared).Error; err != nil {
+				return nil, nil, false, err
+			}
+			if len(shared) > deviceLinkMaxSharedUsers {
+				continue
+			}
+
+			for _, other := range shared {
+				if other.UserID != link.UserID {
+					links = append(links, other)
+				}
+				if visited[other.UserID] {
+					continue
+				}
+				if len(visited) >= deviceClusterMaxUsers {
+					truncated = true
+					break
+				}
+				visited[other.UserID] = true
+				next = append(next, other.UserID)
+			}
+		}
+		links = append(links, userLinks...)
+		frontier = next
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(visited))
+	for id := range visited {
+		userIDs = append(userIDs, id)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i].String() < userIDs[j].String() })
+
+	return userIDs, dedupeDeviceLinks(links), truncated, nil
+}
+
+// dedupeDeviceLinks 移除重複的關聯並依類型、識別值排序
+func dedupeDeviceLinks(links []models.DeviceLink) []models.DeviceLink {
+	seen := make(map[uuid.UUID]bool, len(links))
+	result := make([]models.DeviceLink, 0, len(links))
+	for _, link := range links {
+		if seen[link.ID] {
+			continue
+		}
+		seen[link.ID] = true
+		result = append(result, link)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		if result[i].Value != result[j].Value {
+			return result[i].Value < result[j].Value
+		}
+		return result[i].UserID.String() < result[j].UserID.String()
+	})
+	return result
+}
+
+// toDeviceLinkResponse 轉換裝置關聯回應
+func toDeviceLinkResponse(link *models.DeviceLink) vo.DeviceLinkResponse {
+	return vo.DeviceLinkResponse{
+		UserID:      link.UserID.String(),
+		Kind:        link.Kind,
+		Value:       link.Value,
+		SeenCount:   link.SeenCount,
+		FirstSeenAt: link.FirstSeenAt,
+		LastSeenAt:  link.LastSeenAt,
+	}
+}
+
+// deviceClusterKey 用戶群組 ID 的 Redis key
+func deviceClusterKey(userID string) string {
+	return "device_cluster:" + userID
+}
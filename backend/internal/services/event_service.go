@@ -17,6 +17,9 @@ type EventService struct {
 	DB          *gorm.DB
 	EventCache  *cache.EventCache
 	TicketCache *cache.TicketCache
+
+	// WalletService 為選用，設定後活動時間變更時會同步更新錢包票券
+	WalletService *WalletService
 }
 
 // NewEventService 創建新的 EventService 實例
@@ -186,8 +189,8 @@ func (s *EventService) CreateEvent(event *models.Event) (*vo.EventResponse, erro
 	return eventResponse, nil
 }
 
-// UpdateEvent 更新事件
-func (s *EventService) UpdateEvent(event *models.Event) (*vo.EventResponse, error) {
+// UpdateEvent 更新事件；主辦單位僅能更新自己建立的事件
+func (s *EventService) UpdateEvent(event *models.Event, userID string, role string) (*vo.EventResponse, error) {
 	// 查找要更新的事件
 	var existingEvent models.Event
 	if err := s.DB.First(&existingEvent, event.ID).Error; err != nil {
@@ -196,6 +199,13 @@ func (s *EventService) UpdateEvent(event *models.Event) (*vo.EventResponse, erro
 		}
 		return nil, err
 	}
+	if !canManageEvent(&existingEvent, userID, role) {
+		return nil, errors.New("無權管理此事件")
+	}
+
+	originalStartTime := existingEvent.StartTime
+	originalEndTime := existingEvent.EndTime
+	originalLocation := existingEvent.Location
 
 	// 更新事件
 	updateData := map[string]interface{}{
@@ -221,6 +231,14 @@ func (s *EventService) UpdateEvent(event *models.Event) (*vo.EventResponse, erro
 	go s.EventCache.DeleteEvent(ctx, event.ID.String())
 	go s.EventCache.DeleteEventList(ctx)
 
+	// 活動時間或地點變更時更新錢包票券
+	if s.WalletService != nil &&
+		(!originalStartTime.Equal(existingEvent.StartTime) ||
+			!originalEndTime.Equal(existingEvent.EndTime) ||
+			originalLocation != existingEvent.Location) {
+		go s.WalletService.NotifyEventUpdated(existingEvent.ID)
+	}
+
 	// 將模型轉換為 VO
 	eventResponse := &vo.EventResponse{
 		ID:          existingEvent.ID,
@@ -236,8 +254,8 @@ func (s *EventService) UpdateEvent(event *models.Event) (*vo.EventResponse, erro
 	return eventResponse, nil
 }
 
-// DeleteEvent 刪除事件
-func (s *EventService) DeleteEvent(id uuid.UUID) error {
+// DeleteEvent 刪除事件；主辦單位僅能刪除自己建立的事件
+func (s *EventService) DeleteEvent(id uuid.UUID, userID string, role string) error {
 	// 在事務中刪除事件及相關數據
 	return s.DB.Transaction(func(tx *gorm.DB) error {
 		// 查找要刪除的事件
@@ -248,6 +266,9 @@ func (s *EventService) DeleteEvent(id uuid.UUID) error {
 			}
 			return err
 		}
+		if !canManageEvent(&event, userID, role) {
+			return errors.New("無權管理此事件")
+		}
 
 		// 獲取事件的票種
 		var ticketTypes []models.TicketType
@@ -319,6 +340,7 @@ func (s *EventService) GetEventTicketTypes(eventID uuid.UUID) ([]vo.TicketTypeRe
 				AvailableQuantity: tt.AvailableQuantity,
 				SaleStart:        tt.SaleStart,
 				SaleEnd:          tt.SaleEnd,
+				FingerprintPolicy: toFingerprintPolicyResponse(tt),
 				CreatedAt:        tt.CreatedAt,
 				UpdatedAt:        tt.UpdatedAt,
 			}
@@ -344,6 +366,7 @@ func (s *EventService) GetEventTicketTypes(eventID uuid.UUID) ([]vo.TicketTypeRe
 			AvailableQuantity: tt.AvailableQuantity,
 			SaleStart:        tt.SaleStart,
 			SaleEnd:          tt.SaleEnd,
+			FingerprintPolicy: toFingerprintPolicyResponse(&tt),
 			CreatedAt:        tt.CreatedAt,
 			UpdatedAt:        tt.UpdatedAt,
 		}
@@ -357,10 +380,32 @@ func (s *EventService) GetEventTicketTypes(eventID uuid.UUID) ([]vo.TicketTypeRe
 	return ticketTypeResponses, nil
 }
 
-// CreateTicketType 創建票種
-func (s *EventService) CreateTicketType(ticketType *models.TicketType) (*vo.TicketTypeResponse, error) {
-	// 在數據庫中創建票種
-	if err := s.DB.Create(ticketType).Error; err != nil {
+// CreateTicketType 創建票種；主辦單位僅能為自己建立的事件新增票種
+func (s *EventService) CreateTicketType(ticketType *models.TicketType, userID string, role string) (*vo.TicketTypeResponse, error) {
+	var event models.Event
+	if err := s.DB.First(&event, ticketType.EventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("事件不存在")
+		}
+		return nil, err
+	}
+	if !canManageEvent(&event, userID, role) {
+		return nil, errors.New("無權管理此事件")
+	}
+
+	// 在數據庫中創建票種；指紋購買上限 0（不限制）為零值，建立時會被欄位預設值取代，須另行寫入
+	maxPurchases := ticketType.FingerprintMaxPurchases
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ticketType).Error; err != nil {
+			return err
+		}
+		if maxPurchases == 0 {
+			ticketType.FingerprintMaxPurchases = 0
+			return tx.Model(ticketType).Update("fingerprint_max_purchases", 0).Error
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -378,6 +423,7 @@ func (s *EventService) CreateTicketType(ticketType *models.TicketType) (*vo.Tick
 		AvailableQuantity: ticketType.AvailableQuantity,
 		SaleStart:        ticketType.SaleStart,
 		SaleEnd:          ticketType.SaleEnd,
+		FingerprintPolicy: toFingerprintPolicyResponse(ticketType),
 		CreatedAt:        ticketType.CreatedAt,
 		UpdatedAt:        ticketType.UpdatedAt,
 	}
@@ -385,6 +431,14 @@ func (s *EventService) CreateTicketType(ticketType *models.TicketType) (*vo.Tick
 	return ticketTypeResponse, nil
 }
 
+// canManageEvent 檢查用戶能否管理事件：擁有管理所有事件的權限，或為事件建立者
+func canManageEvent(event *models.Event, userID string, role string) bool {
+	if models.HasPermission(role, models.PermissionEventManage) {
+		return true
+	}
+	return models.HasPermission(role, models.PermissionEventWrite) && event.CreatedBy.String() == userID
+}
+
 // SearchEvents 搜索事件
 func (s *EventService) SearchEvents(query string, page, limit int) ([]vo.EventResponse, int64, error) {
 	var events []models.Event
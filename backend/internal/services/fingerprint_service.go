@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 票種未設定時的指紋政策預設值
+const (
+	defaultFingerprintWindowMinutes = 24 * 60
+	defaultFingerprintMaxPurchases  = 1
+)
+
+// FingerprintPolicy 票種的裝置指紋防重複購買政策
+type FingerprintPolicy struct {
+	EventID      uuid.UUID
+	TicketTypeID uuid.UUID
+	Window       time.Duration
+	MaxPurchases int    // 0 表示不限制
+	Scope        string // models.FingerprintScopeTicketType 或 models.FingerprintScopeEvent
+}
+
+// FingerprintService 依票種政策限制同一裝置指紋的購買次數，購買紀錄保存於 Redis
+type FingerprintService struct {
+	DB          *gorm.DB
+	RedisClient *redis.Client
+	Allowlist   []*net.IPNet // 不受限制的售票端網段
+}
+
+// NewFingerprintService 創建新的 FingerprintService 實例；FINGERPRINT_ALLOWLIST 格式錯誤時返回錯誤
+func NewFingerprintService(db *gorm.DB, redisClient *redis.Client, cfg *config.Config) (*FingerprintService, error) {
+	allowlist := make([]*net.IPNet, 0, len(cfg.FingerprintAllowlist))
+	for _, entry := range cfg.FingerprintAllowlist {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("無效的指紋白名單項目 %q: %w", entry, err)
+		}
+		allowlist = append(allowlist, network)
+	}
+
+	return &FingerprintService{
+		DB:          db,
+		RedisClient: redisClient,
+		Allowlist:   allowlist,
+	}, nil
+}
+
+// Exempt 請求是否來自白名單中的售票端
+func (s *FingerprintService) Exempt(clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.Allowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy 取得票種的指紋政策
+func (s *FingerprintService) Policy(ctx context.Context, ticketTypeID string) (*FingerprintPolicy, error) {
+	id, err := uuid.Parse(ticketTypeID)
+	if err != nil {
+		return nil, errors.New("無效的票券類型 ID")
+	}
+
+	var ticketType models.TicketType
+	if err := s.DB.WithContext(ctx).
+		Select("id", "event_id", "fingerprint_window_minutes", "fingerprint_max_purchases", "fingerprint_scope").
+		First(&ticketType, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("票券類型不存在")
+		}
+		return nil, err
+	}
+	return fingerprintPolicyOf(&ticketType), nil
+}
+
+// Check 查詢裝置指紋在政策區間內的購買次數，不記錄購買
+func (s *FingerprintService) Check(ctx context.Context, fingerprint string, clientIP string, ticketTypeID string) (*vo.FingerprintCheckResponse, error) {
+	policy, err := s.Policy(ctx, ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &vo.FingerprintCheckResponse{
+		Remaining: -1,
+		Exempt:    s.Exempt(clientIP),
+		Policy:    policy.response(),
+	}
+	if response.Exempt || policy.MaxPurchases == 0 {
+		return response, nil
+	}
+
+	since := strconv.FormatInt(time.Now().Add(-policy.Window).UnixNano(), 10)
+	count, err := s.RedisClient.ZCount(ctx, policy.key(fingerprint), "("+since, "+inf").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	response.Purchases = int(count)
+	response.Remaining = policy.MaxPurchases - response.Purchases
+	if response.Remaining < 0 {
+		response.Remaining = 0
+	}
+	response.AlreadyPurchased = response.Remaining == 0
+	return response, nil
+}
+
+// Record 記錄一次購買；已達政策上限時不記錄並返回錯誤。檢查與記錄在同一交易中完成，並行請求不會超過上限
+func (s *FingerprintService) Record(ctx context.Context, fingerprint string, clientIP string, ticketTypeID string, userID string) error {
+	if fingerprint == "" {
+		return errors.New("缺少 TLS 指紋識別")
+	}
+
+	policy, err := s.Policy(ctx, ticketTypeID)
+	if err != nil {
+		return err
+	}
+	if policy.MaxPurchases == 0 || s.Exempt(clientIP) {
+		return nil
+	}
+
+	member, count, err := s.add(ctx, policy, fingerprint, userID)
+	if err != nil {
+		return err
+	}
+	if count > int64(policy.MaxPurchases) {
+		// 超過上限，撤回本次紀錄
+		if err := s.RedisClient.ZRem(ctx, policy.key(fingerprint), member).Err(); err != nil {
+			return err
+		}
+		return errors.New("您已經購買過此票券，請勿重複購買")
+	}
+	return nil
+}
+
+// RecordPurchase 訂單建立後記錄購買；同一計算範圍（依活動計算時為同一活動）的多個票種只記錄一次。
+// 訂單已成立，超過上限時仍保留紀錄
+func (s *FingerprintService) RecordPurchase(ctx context.Context, fingerprint string, clientIP string, ticketTypeIDs []string, userID string) error {
+	if fingerprint == "" {
+		return errors.New("缺少 TLS 指紋識別")
+	}
+	if s.Exempt(clientIP) {
+		return nil
+	}
+
+	recorded := make(map[string]bool, len(ticketTypeIDs))
+	for _, ticketTypeID := range ticketTypeIDs {
+		policy, err := s.Policy(ctx, ticketTypeID)
+		if err != nil {
+			return err
+		}
+		key := policy.key(fingerprint)
+		if policy.MaxPurchases == 0 || recorded[key] {
+			continue
+		}
+		recorded[key] = true
+
+		if _, _, err := s.add(ctx, policy, fingerprint, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add 移除統計區間外的紀錄並新增一筆購買，返回新增的成員與區間內的購買次數
+func (s *FingerprintService) add(ctx context.Context, policy *FingerprintPolicy, fingerprint string, userID string) (string, int64, error) {
+	key := policy.key(fingerprint)
+	now := time.Now()
+	member := fmt.Sprintf("user_id=%s,ticket_type_id=%s,timestamp=%s", userID, policy.TicketTypeID, now.Format(time.RFC3339Nano))
+
+	pipe := s.RedisClient.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-policy.Window).UnixNano(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, policy.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", 0, err
+	}
+	return member, count.Val(), nil
+}
+
+// key 依政策範圍產生購買紀錄的 Redis key
+func (p *FingerprintPolicy) key(fingerprint string) string {
+	if p.Scope == models.FingerprintScopeEvent {
+		return fmt.Sprintf("fingerprint:event:%s:%s", p.EventID, fingerprint)
+	}
+	return fmt.Sprintf("fingerprint:ticket_type:%s:%s", p.TicketTypeID, fingerprint)
+}
+
+// response 轉換指紋政策回應
+func (p *FingerprintPolicy) response() vo.FingerprintPolicyResponse {
+	return vo.FingerprintPolicyResponse{
+		WindowMinutes: int(p.Window / time.Minute),
+		MaxPurchases:  p.MaxPurchases,
+		Scope:         p.Scope,
+	}
+}
+
+// fingerprintPolicyOf 由票種設定取得指紋政策，未設定的欄位採用預設值
+func fingerprintPolicyOf(ticketType *models.TicketType) *FingerprintPolicy {
+	window := ticketType.FingerprintWindowMinutes
+	if window <= 0 {
+		window = defaultFingerprintWindowMinutes
+	}
+	scope := ticketType.FingerprintScope
+	if scope != models.FingerprintScopeEvent {
+		scope = models.FingerprintScopeTicketType
+	}
+	return &FingerprintPolicy{
+		EventID:      ticketType.EventID,
+		TicketTypeID: ticketType.ID,
+		Window:       time.Duration(window) * time.Minute,
+		MaxPurchases: ticketType.FingerprintMaxPurchases,
+		Scope:        scope,
+	}
+}
+
+// toFingerprintPolicyResponse 轉換票種的指紋政策回應
+func toFingerprintPolicyResponse(ticketType *models.TicketType) vo.FingerprintPolicyResponse {
+	return fingerprintPolicyOf(ticketType).response()
+}
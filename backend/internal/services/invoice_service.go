@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/pdf"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 發票號碼前綴
+const (
+	invoiceNumberPrefix    = "INV"
+	creditNoteNumberPrefix = "CN"
+)
+
+// InvoiceService 處理發票與折讓單相關邏輯
+type InvoiceService struct {
+	DB     *gorm.DB
+	Config *config.Config
+}
+
+// NewInvoiceService 創建新的 InvoiceService 實例
+func NewInvoiceService(db *gorm.DB, config *config.Config) *InvoiceService {
+	return &InvoiceService{
+		DB:     db,
+		Config: config,
+	}
+}
+
+// GetInvoice 獲取訂單的發票；發票於訂單付款時開立
+func (s *InvoiceService) GetInvoice(orderID uuid.UUID) (*models.Invoice, error) {
+	invoice, err := s.findInvoice(s.DB, orderID, models.InvoiceTypeInvoice)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, errors.New("發票不存在")
+	}
+	return invoice, nil
+}
+
+// IssueInvoice 在訂單轉為已付款的事務中開立發票，號碼與年度以付款時間計算
+func (s *InvoiceService) IssueInvoice(tx *gorm.DB, orderID uuid.UUID, paidAt time.Time) (*models.Invoice, error) {
+	return s.issueInvoice(tx, orderID, paidAt)
+}
+
+// GetCreditNote 獲取訂單的折讓單
+func (s *InvoiceService) GetCreditNote(orderID uuid.UUID) (*models.Invoice, error) {
+	creditNote, err := s.findInvoice(s.DB, orderID, models.InvoiceTypeCreditNote)
+	if err != nil {
+		return nil, err
+	}
+	if creditNote == nil {
+		return nil, errors.New("折讓單不存在")
+	}
+	return creditNote, nil
+}
+
+// IssueCreditNote 在指定事務中為已退款訂單開立折讓單；付款時未開立發票的訂單會先補開原發票
+func (s *InvoiceService) IssueCreditNote(tx *gorm.DB, orderID uuid.UUID) (*models.Invoice, error) {
+	existing, err := s.findInvoice(tx, orderID, models.InvoiceTypeCreditNote)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	original, err := s.issueInvoice(tx, orderID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	year := time.Now().Year()
+	sequence, err := s.nextSequence(tx, creditNoteNumberPrefix, year)
+	if err != nil {
+		return nil, err
+	}
+
+	// 折讓單金額與原發票相同，明細一併複製
+	creditNote := models.Invoice{
+		InvoiceNumber:     formatInvoiceNumber(creditNoteNumberPrefix, year, sequence),
+		Type:              models.InvoiceTypeCreditNote,
+		OrderID:           original.OrderID,
+		UserID:            original.UserID,
+		OriginalInvoiceID: &original.ID,
+		Year:              year,
+		SequenceNumber:    sequence,
+		BuyerName:         original.BuyerName,
+		BuyerEmail:        original.BuyerEmail,
+		Subtotal:          original.Subtotal,
+		FeeAmount:         original.FeeAmount,
+		TaxRate:           original.TaxRate,
+		TaxAmount:         original.TaxAmount,
+		TotalAmount:       original.TotalAmount,
+		IssuedAt:          time.Now(),
+	}
+	for _, item := range original.InvoiceItems {
+		creditNote.InvoiceItems = append(creditNote.InvoiceItems, models.InvoiceItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      item.Amount,
+		})
+	}
+
+	if err := tx.Create(&creditNote).Error; err != nil {
+		return nil, err
+	}
+
+	return &creditNote, nil
+}
+
+// RenderPDF 將發票或折讓單輸出為 PDF
+func (s *InvoiceService) RenderPDF(invoice *models.Invoice) ([]byte, error) {
+	var original *models.Invoice
+	if invoice.OriginalInvoiceID != nil {
+		original = &models.Invoice{}
+		if err := s.DB.First(original, *invoice.OriginalInvoiceID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	issuer := pdf.Issuer{
+		Name:    s.Config.InvoiceIssuerName,
+		TaxID:   s.Config.InvoiceIssuerTaxID,
+		Address: s.Config.InvoiceIssuerAddress,
+	}
+
+	return pdf.RenderInvoice(s.Config.PDFFontPath, issuer, invoice, original)
+}
+
+// issueInvoice 在事務中以指定時間開立發票，已開立時直接返回既有發票
+func (s *InvoiceService) issueInvoice(tx *gorm.DB, orderID uuid.UUID, issuedAt time.Time) (*models.Invoice, error) {
+	existing, err := s.findInvoice(tx, orderID, models.InvoiceTypeInvoice)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	// 鎖定訂單，避免同時開立兩張發票
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("OrderItems").
+		First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("訂單不存在")
+		}
+		return nil, err
+	}
+
+	// 取得鎖後再確認一次，其他事務可能已開立
+	existing, err = s.findInvoice(tx, orderID, models.InvoiceTypeInvoice)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	if order.PaymentStatus != "paid" && order.PaymentStatus != "refunded" {
+		return nil, errors.New("訂單尚未付款，無法開立發票")
+	}
+
+	var user models.User
+	if err := tx.Unscoped().First(&user, order.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	items, quantity, err := s.buildInvoiceItems(tx, order.OrderItems)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtotal float64
+	for _, item := range items {
+		subtotal += item.Amount
+	}
+
+	// 服務費以張數計算，列為獨立明細
+	fee := roundAmount(s.Config.InvoiceServiceFee * float64(quantity))
+	if fee > 0 {
+		items = append(items, models.InvoiceItem{
+			Description: "Service fee",
+			Quantity:    quantity,
+			UnitPrice:   s.Config.InvoiceServiceFee,
+			Amount:      fee,
+		})
+	}
+
+	// 票價為含稅價，稅額自總額中拆出
+	total := roundAmount(subtotal + fee)
+	taxRate := s.Config.InvoiceTaxRate
+	tax := roundAmount(total * taxRate / (1 + taxRate))
+
+	year := issuedAt.Year()
+	sequence, err := s.nextSequence(tx, invoiceNumberPrefix, year)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := models.Invoice{
+		InvoiceNumber:  formatInvoiceNumber(invoiceNumberPrefix, year, sequence),
+		Type:           models.InvoiceTypeInvoice,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Year:           year,
+		SequenceNumber: sequence,
+		BuyerName:      user.Name,
+		BuyerEmail:     user.Email,
+		Subtotal:       roundAmount(subtotal),
+		FeeAmount:      fee,
+		TaxRate:        taxRate,
+		TaxAmount:      tax,
+		TotalAmount:    total,
+		IssuedAt:       issuedAt,
+		InvoiceItems:   items,
+	}
+
+	if err := tx.Create(&invoice).Error; err != nil {
+		return nil, err
+	}
+
+	return &invoice, nil
+}
+
+// buildInvoiceItems 將訂單項目轉換為發票明細，並返回總張數
+func (s *InvoiceService) buildInvoiceItems(tx *gorm.DB, orderItems []models.OrderItem) ([]models.InvoiceItem, int, error) {
+	items := make([]models.InvoiceItem, 0, len(orderItems)+1)
+	quantity := 0
+
+	for _, orderItem := range orderItems {
+		var ticketType models.TicketType
+		if err := tx.Unscoped().First(&ticketType, orderItem.TicketTypeID).Error; err != nil {
+			return nil, 0, err
+		}
+
+		var event models.Event
+		if err := tx.Unscoped().First(&event, ticketType.EventID).Error; err != nil {
+			return nil, 0, err
+		}
+
+		items = append(items, models.InvoiceItem{
+			Description: fmt.Sprintf("%s - %s", event.Title, ticketType.Name),
+			Quantity:    orderItem.Quantity,
+			UnitPrice:   orderItem.PricePerUnit,
+			Amount:      roundAmount(orderItem.PricePerUnit * float64(orderItem.Quantity)),
+		})
+		quantity += orderItem.Quantity
+	}
+
+	return items, quantity, nil
+}
+
+// findInvoice 查詢訂單指定類型的發票，不存在時返回 nil
+func (s *InvoiceService) findInvoice(tx *gorm.DB, orderID uuid.UUID, invoiceType string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := tx.Preload("InvoiceItems").
+		Where("order_id = ? AND type = ?", orderID, invoiceType).
+		First(&invoice).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// nextSequence 在事務中取得下一個發票序號
+// 序列列以 FOR UPDATE 鎖定，事務回滾時序號一併回滾，因此號碼不會出現跳號
+func (s *InvoiceService) nextSequence(tx *gorm.DB, prefix string, year int) (int, error) {
+	sequence := models.InvoiceSequence{Prefix: prefix, Year: year}
+
+	// 確保序列存在
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("prefix = ? AND year = ?", prefix, year).
+		First(&sequence).Error; err != nil {
+		return 0, err
+	}
+
+	sequence.LastNumber++
+	if err := tx.Model(&models.InvoiceSequence{}).
+		Where("prefix = ? AND year = ?", prefix, year).
+		Updates(map[string]interface{}{
+			"last_number": sequence.LastNumber,
+			"updated_at":  time.Now(),
+		}).Error; err != nil {
+		return 0, err
+	}
+
+	return sequence.LastNumber, nil
+}
+
+// formatInvoiceNumber 組合發票號碼，例如 INV-2024-000001
+func formatInvoiceNumber(prefix string, year, sequence int) string {
+	return fmt.Sprintf("%s-%d-%06d", prefix, year, sequence)
+}
+
+// roundAmount 將金額四捨五入至小數點後兩位
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
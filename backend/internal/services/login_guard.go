@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// 登入失敗次數的統計區間
+	loginFailureWindow = 15 * time.Minute
+
+	// 同一帳號失敗達此次數後，每次嘗試須等待遞增的延遲時間
+	loginDelayAfterFailures = 3
+
+	// 遞增延遲的上限
+	loginMaxDelay = 30 * time.Second
+
+	// 統計區間內同一帳號失敗達此次數即暫時鎖定
+	accountLockoutThreshold = 10
+
+	// 統計區間內同一 IP 失敗達此次數即暫時封鎖，門檻較高以容許共用 IP
+	ipLockoutThreshold = 50
+
+	// 帳號或 IP 的鎖定時間
+	loginLockoutDuration = 15 * time.Minute
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// AccountLockNotifier 帳號因登入失敗次數過多而被暫時鎖定時的通知掛鉤
+type AccountLockNotifier interface {
+	AccountLocked(ctx context.Context, user *models.User, until time.Time, client dto.ClientInfo) error
+}
+
+// MailAccountLockNotifier 以電子郵件通知帳號擁有者帳號已被鎖定
+type MailAccountLockNotifier struct {
+	Mailer mailer.Mailer
+}
+
+// NewMailAccountLockNotifier 創建新的 MailAccountLockNotifier 實例
+func NewMailAccountLockNotifier(mailer mailer.Mailer) *MailAccountLockNotifier {
+	return &MailAccountLockNotifier{
+		Mailer: mailer,
+	}
+}
+
+// AccountLocked 寄送帳號鎖定通知
+func (n *MailAccountLockNotifier) AccountLocked(ctx context.Context, user *models.User, until time.Time, client dto.ClientInfo) error {
+	return n.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "您的帳號已暫時鎖定",
+		Body: fmt.Sprintf("您好 %s，\n\n您的帳號因多次登入失敗（最近一次來源 IP：%s），已暫時鎖定至 %s。\n若非本人操作，建議於解鎖後立即重設密碼。",
+			user.Name, client.IPAddress, until.Format("2006-01-02 15:04")),
+	})
+}
+
+// loginAllowed 檢查帳號或 IP 是否被鎖定，或仍在遞增延遲的等待期間；failures 為帳號目前的失敗次數
+//
+// 鎖定、延遲與失敗次數皆經由 rateLimiter 計數，設定 RateLimits 時 Redis 故障會改用程序內計數，鎖定仍然有效。
+// 無法讀取鎖定狀態時回傳錯誤，由呼叫者拒絕登入，不可在故障期間停用鎖定。
+func (s *AuthService) loginAllowed(ctx context.Context, email string, ip string, failures int) (bool, error) {
+	locks := s.rateLimiter("login_lock")
+	keys := []string{"account:" + email}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	for _, key := range keys {
+		remaining, err := locks.Remaining(ctx, key, 1, loginLockoutDuration)
+		if err != nil {
+			return false, err
+		}
+		if remaining == 0 {
+			return false, nil
+		}
+	}
+
+	if failures < loginDelayAfterFailures {
+		return true, nil
+	}
+	remaining, err := s.rateLimiter("login_delay").Remaining(ctx, email, 1, loginDelay(failures))
+	if err != nil {
+		return false, err
+	}
+	return remaining > 0, nil
+}
+
+// recordLoginFailure 累計帳號與 IP 的失敗次數，依次數設定延遲或鎖定；user 為 nil 表示帳號不存在
+func (s *AuthService) recordLoginFailure(ctx context.Context, user *models.User, email string, client dto.ClientInfo) {
+	s.recordLoginAttempt(user, email, client, models.LoginResultInvalidCredentials)
+
+	failures := s.rateLimiter("login_failure")
+
+	// 不存在的帳號同樣計數與鎖定，避免以回應差異判斷帳號是否存在
+	_, remaining, _, err := failures.Allow(ctx, "account:"+email, accountLockoutThreshold, loginFailureWindow)
+	count := accountLockoutThreshold - remaining
+	switch {
+	case err != nil:
+		log.Printf("記錄登入失敗次數失敗: %v", err)
+	case remaining == 0:
+		until := time.Now().Add(loginLockoutDuration)
+		s.lockLogin(ctx, failures, "account", email)
+		if user != nil && s.LockNotifier != nil {
+			if err := s.LockNotifier.AccountLocked(ctx, user, until, client); err != nil {
+				log.Printf("寄送帳號鎖定通知失敗: %v", err)
+			}
+		}
+	case count >= loginDelayAfterFailures:
+		if _, _, _, err := s.rateLimiter("login_delay").Allow(ctx, email, 1, loginDelay(count)); err != nil {
+			log.Printf("設定登入延遲失敗: %v", err)
+		}
+	}
+
+	if client.IPAddress == "" {
+		return
+	}
+	_, remaining, _, err = failures.Allow(ctx, "ip:"+client.IPAddress, ipLockoutThreshold, loginFailureWindow)
+	if err != nil {
+		log.Printf("記錄登入失敗次數失敗: %v", err)
+	} else if remaining == 0 {
+		s.lockLogin(ctx, failures, "ip", client.IPAddress)
+	}
+}
+
+// loginFailureCount 帳號目前統計區間內的失敗次數
+//
+// 改用程序內計數時每台服務器只分得部分額度，次數會被高估，延遲與 CAPTCHA 因此較早觸發。
+func (s *AuthService) loginFailureCount(ctx context.Context, email string) (int, error) {
+	remaining, err := s.rateLimiter("login_failure").Remaining(ctx, "account:"+email, accountLockoutThreshold, loginFailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	return accountLockoutThreshold - remaining, nil
+}
+
+// recordLoginSuccess 密碼驗證成功後清除帳號的失敗次數
+func (s *AuthService) recordLoginSuccess(ctx context.Context, user *models.User, email string, client dto.ClientInfo) {
+	s.recordLoginAttempt(user, email, client, models.LoginResultSuccess)
+
+	if err := s.rateLimiter("login_failure").Reset(ctx, "account:"+email); err != nil {
+		log.Printf("重置登入失敗次數失敗: %v", err)
+	}
+}
+
+// lockLogin 暫時鎖定帳號或 IP，並重新開始計算失敗次數
+func (s *AuthService) lockLogin(ctx context.Context, failures limiter.Limiter, kind string, value string) {
+	if _, _, _, err := s.rateLimiter("login_lock").Allow(ctx, kind+":"+value, 1, loginLockoutDuration); err != nil {
+		log.Printf("鎖定登入失敗: %v", err)
+		return
+	}
+	if err := failures.Reset(ctx, kind+":"+value); err != nil {
+		log.Printf("重置登入失敗次數失敗: %v", err)
+	}
+	log.Printf("登入失敗次數過多，已暫時鎖定 %s: %s", kind, value)
+}
+
+// recordLoginAttempt 寫入登入嘗試稽核紀錄；寫入失敗不影響登入流程
+func (s *AuthService) recordLoginAttempt(user *models.User, email string, client dto.ClientInfo, result string) {
+	attempt := models.LoginAttempt{
+		Email:     email,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Result:    result,
+	}
+	if user != nil {
+		userID := user.ID
+		attempt.UserID = &userID
+	}
+
+	if err := s.DB.Create(&attempt).Error; err != nil {
+		log.Printf("寫入登入紀錄失敗: %v", err)
+	}
+}
+
+// loginDelay 依失敗次數計算遞增延遲：1 秒起每次加倍，最多 loginMaxDelay
+func loginDelay(failures int) time.Duration {
+	shift := failures - loginDelayAfterFailures
+	if shift > 5 {
+		return loginMaxDelay
+	}
+	delay := time.Second << uint(shift)
+	if delay > loginMaxDelay {
+		return loginMaxDelay
+	}
+	return delay
+}
+
+// dummyPasswordHash 帳號不存在時用於比對的雜湊，使回應時間與帳號存在時一致
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
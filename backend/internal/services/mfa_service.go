@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/lipeichen/ticket-getter/pkg/totp"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// 每組產生的復原碼數量
+	mfaRecoveryCodeCount = 10
+
+	// 每個帳號在時間窗口內允許的驗證碼嘗試次數
+	mfaAttemptLimit  = 5
+	mfaAttemptWindow = 15 * time.Minute
+
+	// 容許前後一個時間步的時鐘誤差
+	mfaClockSkew = 1
+)
+
+// MFAService 處理 TOTP 兩步驟驗證
+type MFAService struct {
+	DB          *gorm.DB
+	RedisClient *redis.Client
+	Config      *config.Config
+	RateLimits  *RateLimitService // 嘗試次數限制共用的斷路器，未設定時只使用 Redis
+}
+
+// NewMFAService 創建新的 MFAService 實例
+func NewMFAService(db *gorm.DB, redisClient *redis.Client, config *config.Config) *MFAService {
+	return &MFAService{
+		DB:          db,
+		RedisClient: redisClient,
+		Config:      config,
+	}
+}
+
+// Required 判斷用戶登入時是否必須通過兩步驟驗證
+func (s *MFAService) Required(user *models.User) bool {
+	return user.MFAEnabledAt != nil || (user.Role == models.RoleAdmin && s.Config.MFARequiredForAdmins)
+}
+
+// Setup 為用戶產生新的 TOTP 密鑰，需以驗證碼確認後才會啟用
+func (s *MFAService) Setup(userID string) (*vo.MFASetupResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("兩步驟驗證已啟用")
+	}
+
+	return s.setup(user)
+}
+
+// Enable 驗證第一組驗證碼後啟用兩步驟驗證，並返回復原碼
+func (s *MFAService) Enable(userID string, sessionID string, code string) ([]string, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("兩步驟驗證已啟用")
+	}
+
+	recoveryCodes, err := s.enable(user, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// 當前工作階段已證明持有驗證器，視為已完成兩步驟驗證
+	if err := s.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ?", sessionID, user.ID).
+		Update("mfa_verified", true).Error; err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable 停用兩步驟驗證，需同時提供密碼與驗證碼；未設定密碼的帳號改為要求近期登入
+func (s *MFAService) Disable(userID string, sessionID string, password string, code string) error {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.MFAEnabledAt == nil {
+		return errors.New("兩步驟驗證未啟用")
+	}
+	if user.Role == models.RoleAdmin && s.Config.MFARequiredForAdmins {
+		return errors.New("管理員帳號必須啟用兩步驟驗證")
+	}
+
+	if err := reauthenticate(s.DB, user, sessionID, password, "密碼不正確"); err != nil {
+		return err
+	}
+	if err := s.Verify(user, code, ""); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(user).Updates(map[string]interface{}{
+			"mfa_secret":         "",
+			"mfa_enabled_at":     nil,
+			"mfa_last_used_step": 0,
+			"updated_at":         time.Now(),
+		}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 以驗證碼確認後產生新的復原碼，舊復原碼全部失效
+func (s *MFAService) RegenerateRecoveryCodes(userID string, code string) ([]string, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabledAt == nil {
+		return nil, errors.New("兩步驟驗證未啟用")
+	}
+	if err := s.Verify(user, code, ""); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+
+	return recoveryCodes, err
+}
+
+// Verify 驗證 TOTP 驗證碼或復原碼，兩者擇一
+func (s *MFAService) Verify(user *models.User, code string, recoveryCode string) error {
+	if code == "" && recoveryCode == "" {
+		return errors.New("請輸入驗證碼")
+	}
+
+	// 限制嘗試次數，避免暴力破解六位數驗證碼
+	var rateLimiter limiter.Limiter = limiter.NewRateLimiter(s.RedisClient, "mfa_attempt")
+	if s.RateLimits != nil {
+		rateLimiter = s.RateLimits.Limiter("mfa_attempt")
+	}
+	allowed, _, _, err := rateLimiter.Allow(context.Background(), user.ID.String(), mfaAttemptLimit, mfaAttemptWindow)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("請求過於頻繁，請稍後再試")
+	}
+
+	if code != "" {
+		return s.verifyCode(user, code)
+	}
+	return s.useRecoveryCode(user, recoveryCode)
+}
+
+// setup 產生並保存加密後的 TOTP 密鑰
+func (s *MFAService) setup(user *models.User) (*vo.MFASetupResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Model(user).Updates(map[string]interface{}{
+		"mfa_secret":         encrypted,
+		"mfa_last_used_step": 0,
+		"updated_at":         time.Now(),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &vo.MFASetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.Config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// enable 以驗證碼確認待啟用的密鑰，標記啟用並產生復原碼
+func (s *MFAService) enable(user *models.User, code string) ([]string, error) {
+	if user.MFASecret == "" {
+		return nil, errors.New("請先設定兩步驟驗證")
+	}
+	if code == "" {
+		return nil, errors.New("請輸入驗證碼")
+	}
+	if err := s.Verify(user, code, ""); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled_at": now,
+			"updated_at":     now,
+		}).Error; err != nil {
+			return err
+		}
+		user.MFAEnabledAt = &now
+
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+
+	return recoveryCodes, err
+}
+
+// verifyCode 驗證 TOTP 驗證碼；同一時間步的驗證碼只能使用一次
+func (s *MFAService) verifyCode(user *models.User, code string) error {
+	secret, err := s.decryptSecret(user.MFASecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), mfaClockSkew)
+	if !ok {
+		return errors.New("驗證碼不正確")
+	}
+
+	// 以條件更新記錄時間步，拒絕已使用過的驗證碼
+	result := s.DB.Model(&models.User{}).
+		Where("id = ? AND mfa_last_used_step < ?", user.ID, step).
+		Update("mfa_last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("驗證碼不正確")
+	}
+	user.MFALastUsedStep = step
+
+	return nil
+}
+
+// useRecoveryCode 使用一次性復原碼
+func (s *MFAService) useRecoveryCode(user *models.User, recoveryCode string) error {
+	result := s.DB.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeRecoveryCode(recoveryCode))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("復原碼不正確")
+	}
+
+	return nil
+}
+
+// loadUser 依 ID 讀取用戶
+func (s *MFAService) loadUser(userID string) (*models.User, error) {
+	var user models.User
+	if err := s.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("使用者不存在")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// encryptSecret 以 AES-GCM 加密 TOTP 密鑰，資料庫外洩時密鑰仍受保護
+func (s *MFAService) encryptSecret(secret string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret 解密 TOTP 密鑰
+func (s *MFAService) decryptSecret(encrypted string) (string, error) {
+	gcm, err := s.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("無效的兩步驟驗證密鑰")
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("無效的兩步驟驗證密鑰")
+	}
+	return string(secret), nil
+}
+
+// secretCipher 建立加密密鑰用的 AES-GCM；未設定專用金鑰時由 JWT 密鑰衍生
+func (s *MFAService) secretCipher() (cipher.AEAD, error) {
+	key := s.Config.MFAEncryptionKey
+	if key == "" {
+		key = "mfa-secret:" + s.Config.JWTSecret
+	}
+	sum := sha256.Sum256([]byte(key))
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// replaceRecoveryCodes 刪除舊復原碼並產生新的一組，資料庫僅保存雜湊
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, mfaRecoveryCodeCount)
+	records := make([]models.MFARecoveryCode, mfaRecoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		records[i] = models.MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(raw),
+		}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略大小寫、空白與連字號
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/oidc"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 第三方登入請求（state）的有效期限
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState 授權流程開始時保存於 Redis 的狀態，回呼時以 state 取回且僅能使用一次
+type oidcState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	UserID       string `json:"user_id,omitempty"` // 綁定流程的發起用戶，登入流程為空
+}
+
+// OIDCService 處理第三方登入與身分綁定
+type OIDCService struct {
+	DB          *gorm.DB
+	RedisClient *redis.Client
+	AuthService *AuthService
+	Providers   map[string]oidc.Provider
+}
+
+// NewOIDCService 創建新的 OIDCService 實例
+func NewOIDCService(db *gorm.DB, redisClient *redis.Client, authService *AuthService, providers ...oidc.Provider) *OIDCService {
+	registry := make(map[string]oidc.Provider, len(providers))
+	for _, provider := range providers {
+		registry[provider.Name()] = provider
+	}
+
+	return &OIDCService{
+		DB:          db,
+		RedisClient: redisClient,
+		AuthService: authService,
+		Providers:   registry,
+	}
+}
+
+// AuthorizationURL 產生授權連結；userID 非空時為已登入用戶的綁定流程
+func (s *OIDCService) AuthorizationURL(ctx context.Context, providerName string, userID string) (*vo.OIDCAuthorizationResponse, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, errors.New("不支援的登入方式")
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(oidcState{
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		UserID:       userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.RedisClient.Set(ctx, oidcStateKey(state), payload, oidcStateTTL).Err(); err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("產生 %s 授權連結失敗: %v", providerName, err)
+		return nil, errors.New("第三方登入暫時無法使用")
+	}
+
+	return &vo.OIDCAuthorizationResponse{AuthorizationURL: authURL}, nil
+}
+
+// Login 完成第三方登入：已綁定的身分直接登入，否則依已驗證的電子郵件綁定既有帳號或建立新帳號
+func (s *OIDCService) Login(ctx context.Context, providerName string, req dto.OIDCCallbackRequest, client dto.ClientInfo) (*vo.LoginResponse, error) {
+	identity, err := s.authenticate(ctx, providerName, req, "")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findOrCreateUser(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第三方登入不能略過兩步驟驗證
+	if s.AuthService.MFAService.Required(user) {
+		return s.AuthService.mfaChallengeResponse(user)
+	}
+
+	token, refreshToken, err := s.AuthService.startSession(user, client, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vo.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         *toUserResponse(user),
+	}, nil
+}
+
+// Link 將第三方身分綁定到當前用戶
+func (s *OIDCService) Link(ctx context.Context, providerName string, userID string, req dto.OIDCCallbackRequest) (*vo.UserIdentityResponse, error) {
+	identity, err := s.authenticate(ctx, providerName, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.UserIdentity
+	err = s.DB.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID.String() != userID {
+			return nil, errors.New("此身分已綁定其他帳號")
+		}
+		return toUserIdentityResponse(&existing), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user, err := s.AuthService.MFAService.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.UserIdentity{}).
+		Where("user_id = ? AND provider = ?", user.ID, identity.Provider).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("已綁定其他同類型帳號，請先解除綁定")
+	}
+
+	link := models.UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := s.DB.Create(&link).Error; err != nil {
+		return nil, err
+	}
+
+	return toUserIdentityResponse(&link), nil
+}
+
+// Unlink 解除綁定；帳號未設定密碼時至少需保留一種登入方式
+func (s *OIDCService) Unlink(userID string, providerName string) error {
+	user, err := s.AuthService.MFAService.loadUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var identities []models.UserIdentity
+		if err := tx.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+			return err
+		}
+
+		found := false
+		for _, identity := range identities {
+			if identity.Provider == providerName {
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("尚未綁定此登入方式")
+		}
+		if user.PasswordHash == unusablePasswordHash && len(identities) == 1 {
+			return errors.New("請先設定密碼再解除綁定")
+		}
+
+		return tx.Where("user_id = ? AND provider = ?", user.ID, providerName).Delete(&models.UserIdentity{}).Error
+	})
+}
+
+// ListIdentities 列出當前用戶已綁定的第三方身分
+func (s *OIDCService) ListIdentities(userID string) ([]vo.UserIdentityResponse, error) {
+	var identities []models.UserIdentity
+	if err := s.DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+
+	responses := make([]vo.UserIdentityResponse, len(identities))
+	for i := range identities {
+		responses[i] = *toUserIdentityResponse(&identities[i])
+	}
+	return responses, nil
+}
+
+// authenticate 取回並作廢 state，再交由身分提供者驗證授權碼
+func (s *OIDCService) authenticate(ctx context.Context, providerName string, req dto.OIDCCallbackRequest, userID string) (*oidc.Identity, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return nil, errors.New("不支援的登入方式")
+	}
+
+	payload, err := s.RedisClient.GetDel(ctx, oidcStateKey(req.State)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errors.New("無效或已過期的登入請求")
+		}
+		return nil, err
+	}
+
+	var state oidcState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, errors.New("無效或已過期的登入請求")
+	}
+	// state 必須來自同一提供者與同一流程（登入或特定用戶的綁定）
+	if state.Provider != providerName || state.UserID != userID {
+		return nil, errors.New("無效或已過期的登入請求")
+	}
+
+	identity, err := provider.Authenticate(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Printf("%s 登入驗證失敗: %v", providerName, err)
+		return nil, errors.New("第三方登入驗證失敗")
+	}
+
+	return identity, nil
+}
+
+// findOrCreateUser 依第三方身分找出對應用戶，必要時綁定既有帳號或建立新帳號
+func (s *OIDCService) findOrCreateUser(identity *oidc.Identity) (*models.User, error) {
+	var user models.User
+
+	var link models.UserIdentity
+	err := s.DB.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&link).Error
+	if err == nil {
+		if err := s.DB.First(&user, link.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("使用者不存在")
+			}
+			return nil, err
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 僅信任身分提供者已驗證的電子郵件，避免以他人信箱接管帳號
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" {
+		return nil, errors.New("身分提供者未提供電子郵件")
+	}
+	if !identity.EmailVerified {
+		return nil, errors.New("身分提供者的電子郵件尚未驗證")
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = ?", email).First(&user).Error
+		switch {
+		case err == nil:
+			// 既有帳號的電子郵件未經驗證時，可能是他人預先以此信箱註冊，不自動綁定
+			if user.EmailVerifiedAt == nil {
+				return errors.New("此電子郵件已註冊，請以密碼登入後再綁定")
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			now := time.Now()
+			user = models.User{
+				Email:           email,
+				EmailVerifiedAt: &now,
+				PasswordHash:    unusablePasswordHash,
+				Name:            identityDisplayName(identity, email),
+				Role:            models.RoleUser,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// identityDisplayName 第三方身分未提供名稱時，以電子郵件帳號名稱代替
+func identityDisplayName(identity *oidc.Identity, email string) string {
+	if name := strings.TrimSpace(identity.Name); name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return local
+}
+
+// oidcStateKey 第三方登入 state 的 Redis key
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// toUserIdentityResponse 轉換第三方身分回應
+func toUserIdentityResponse(identity *models.UserIdentity) *vo.UserIdentityResponse {
+	return &vo.UserIdentityResponse{
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderService 處理訂單相關業務邏輯
+type OrderService struct {
+	DB             *gorm.DB
+	InvoiceService *InvoiceService
+	DeviceGraph    *DeviceGraphService // 記錄付款方式指紋以找出多帳號群組，未設定時不記錄
+}
+
+// NewOrderService 創建新的 OrderService 實例
+func NewOrderService(db *gorm.DB, invoiceService *InvoiceService) *OrderService {
+	return &OrderService{
+		DB:             db,
+		InvoiceService: invoiceService,
+	}
+}
+
+// CreateOrder 建立待付款訂單，在同一事務中檢查銷售時間並扣除庫存；票券於付款後產生
+func (s *OrderService) CreateOrder(userID string, req dto.CreateOrderRequest) (*vo.OrderResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("無效的用戶 ID")
+	}
+
+	order := models.Order{
+		UserID:        uid,
+		Status:        "pending",
+		PaymentStatus: "unpaid",
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, item := range req.Items {
+			ticketTypeID, err := uuid.Parse(item.TicketTypeID)
+			if err != nil {
+				return errors.New("無效的票券類型 ID")
+			}
+
+			// 鎖定票種，避免同時購買超賣
+			var ticketType models.TicketType
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticketType, ticketTypeID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("票券類型不存在")
+				}
+				return err
+			}
+
+			if now.Before(ticketType.SaleStart) {
+				return errors.New("票券銷售尚未開始")
+			}
+			if now.After(ticketType.SaleEnd) {
+				return errors.New("票券銷售已結束")
+			}
+			if ticketType.AvailableQuantity < item.Quantity {
+				return errors.New("票券數量不足")
+			}
+
+			if err := tx.Model(&ticketType).
+				Update("available_quantity", gorm.Expr("available_quantity - ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+
+			order.OrderItems = append(order.OrderItems, models.OrderItem{
+				TicketTypeID: ticketTypeID,
+				Quantity:     item.Quantity,
+				PricePerUnit: ticketType.Price,
+			})
+			order.TotalAmount += ticketType.Price * float64(item.Quantity)
+		}
+
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := toOrderResponse(&order)
+	return &response, nil
+}
+
+// GetOrderForUser 獲取訂單，並確認訂單屬於該使用者（具訂單查詢權限的角色可查看所有訂單）
+func (s *OrderService) GetOrderForUser(orderID uuid.UUID, userID string, role string) (*models.Order, error) {
+	var order models.Order
+	if err := s.DB.First(&order, orderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("訂單不存在")
+		}
+		return nil, err
+	}
+
+	if !models.HasPermission(role, models.PermissionOrderRead) && order.UserID.String() != userID {
+		return nil, errors.New("訂單不存在")
+	}
+
+	return &order, nil
+}
+
+// GetUserOrders 獲取用戶的訂單列表，可依狀態篩選
+func (s *OrderService) GetUserOrders(userID string, status string, page, limit int) ([]vo.OrderResponse, int64, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, 0, errors.New("無效的用戶 ID")
+	}
+
+	query := s.DB.Model(&models.Order{}).Where("user_id = ?", uid)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// 獲取總數
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 獲取分頁數據
+	var orders []models.Order
+	if err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset((page - 1) * limit).
+		Find(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+
+	orderResponses := make([]vo.OrderResponse, len(orders))
+	for i, order := range orders {
+		orderResponses[i] = toOrderResponse(&order)
+	}
+
+	return orderResponses, total, nil
+}
+
+// GetUserOrderDetail 獲取用戶的訂單詳情，包含訂單項目與票券
+func (s *OrderService) GetUserOrderDetail(orderID string, userID string) (*vo.OrderDetailResponse, error) {
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("無效的訂單 ID")
+	}
+
+	var order models.Order
+	if err := s.DB.
+		Preload("OrderItems.Tickets").
+		Where("user_id = ?", userID).
+		First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("訂單不存在")
+		}
+		return nil, err
+	}
+
+	detail := &vo.OrderDetailResponse{
+		OrderResponse: toOrderResponse(&order),
+		Items:         make([]vo.OrderItemResponse, len(order.OrderItems)),
+	}
+
+	for i, item := range order.OrderItems {
+		// 票種或活動下架後仍需顯示歷史訂單
+		var ticketType models.TicketType
+		if err := s.DB.Unscoped().First(&ticketType, item.TicketTypeID).Error; err != nil {
+			return nil, err
+		}
+		var event models.Event
+		if err := s.DB.Unscoped().First(&event, ticketType.EventID).Error; err != nil {
+			return nil, err
+		}
+
+		tickets := make([]vo.TicketResponse, len(item.Tickets))
+		for j, ticket := range item.Tickets {
+			tickets[j] = vo.TicketResponse{
+				ID:             ticket.ID,
+				OrderItemID:    ticket.OrderItemID,
+				TicketCode:     ticket.TicketCode,
+				IsUsed:         ticket.IsUsed,
+				UsedAt:         ticket.UsedAt,
+				CreatedAt:      ticket.CreatedAt,
+				UpdatedAt:      ticket.UpdatedAt,
+				EventTitle:     event.Title,
+				EventTime:      event.StartTime,
+				EventLocation:  event.Location,
+				TicketTypeName: ticketType.Name,
+			}
+		}
+
+		detail.Items[i] = vo.OrderItemResponse{
+			ID:             item.ID,
+			TicketTypeID:   item.TicketTypeID,
+			TicketTypeName: ticketType.Name,
+			EventID:        event.ID,
+			EventTitle:     event.Title,
+			EventTime:      event.StartTime,
+			Quantity:       item.Quantity,
+			PricePerUnit:   item.PricePerUnit,
+			Tickets:        tickets,
+		}
+	}
+
+	return detail, nil
+}
+
+// MarkPaid 將待付款訂單標記為已付款，在同一事務中產生票券並以付款時間開立發票
+//
+// paymentFingerprint 為金流服務提供的付款方式指紋，付款成功後記錄於裝置關聯；空白時不記錄。
+func (s *OrderService) MarkPaid(orderID uuid.UUID, paymentMethod string, paymentFingerprint string) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	var userID uuid.UUID
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("訂單不存在")
+			}
+			return err
+		}
+
+		if order.PaymentStatus == "paid" || order.PaymentStatus == "refunded" {
+			return errors.New("訂單已付款")
+		}
+		if order.Status == "cancelled" {
+			return errors.New("訂單已取消，無法付款")
+		}
+		userID = order.UserID
+
+		paidAt := time.Now()
+		if err := tx.Model(&order).Updates(map[string]interface{}{
+			"status":         "paid",
+			"payment_status": "paid",
+			"payment_method": paymentMethod,
+			"updated_at":     paidAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 產生票券
+		for _, item := range order.OrderItems {
+			tickets := make([]models.Ticket, item.Quantity)
+			for i := range tickets {
+				tickets[i] = models.Ticket{OrderItemID: item.ID}
+			}
+			if len(tickets) > 0 {
+				if err := tx.Create(&tickets).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var err error
+		invoice, err = s.InvoiceService.IssueInvoice(tx, order.ID, paidAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 付款已完成，關聯記錄失敗不影響收款
+	if s.DeviceGraph != nil {
+		if err := s.DeviceGraph.LinkPaymentMethod(context.Background(), userID, paymentFingerprint); err != nil {
+			log.Printf("記錄付款方式關聯失敗: %v", err)
+		}
+	}
+
+	return invoice, nil
+}
+
+// RefundOrder 將已付款訂單標記為退款，作廢票券、歸還庫存並開立折讓單
+func (s *OrderService) RefundOrder(orderID uuid.UUID) (*models.Invoice, error) {
+	var creditNote *models.Invoice
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("OrderItems").
+			First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("訂單不存在")
+			}
+			return err
+		}
+
+		if order.PaymentStatus == "refunded" {
+			return errors.New("訂單已退款")
+		}
+		if order.PaymentStatus != "paid" {
+			return errors.New("訂單尚未付款，無法退款")
+		}
+
+		// 更新訂單狀態
+		if err := tx.Model(&order).Updates(map[string]interface{}{
+			"status":         "cancelled",
+			"payment_status": "refunded",
+			"updated_at":     time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, item := range order.OrderItems {
+			// 作廢票券
+			if err := tx.Where("order_item_id = ?", item.ID).Delete(&models.Ticket{}).Error; err != nil {
+				return err
+			}
+
+			// 歸還庫存
+			if err := tx.Model(&models.TicketType{}).
+				Where("id = ?", item.TicketTypeID).
+				Update("available_quantity", gorm.Expr("available_quantity + ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+
+		// 開立折讓單
+		var err error
+		creditNote, err = s.InvoiceService.IssueCreditNote(tx, order.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return creditNote, nil
+}
+
+// toOrderResponse 將訂單模型轉換為 VO
+func toOrderResponse(order *models.Order) vo.OrderResponse {
+	return vo.OrderResponse{
+		ID:            order.ID,
+		TotalAmount:   order.TotalAmount,
+		Status:        order.Status,
+		PaymentMethod: order.PaymentMethod,
+		PaymentStatus: order.PaymentStatus,
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
+	}
+}
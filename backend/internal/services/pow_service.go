@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/pow"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// 挑戰的有效期限
+	powChallengeTTL = 2 * time.Minute
+
+	// 統計購票負載的區間
+	powLoadWindow = 10 * time.Second
+
+	// 風險分數每增加此值，難度提高 1 位元
+	powRiskScorePerBit = 10
+)
+
+// ProofOfWorkService 簽發與兌換購票用的工作量證明挑戰，難度隨負載與風險分數提高
+type ProofOfWorkService struct {
+	RedisClient    *redis.Client
+	Issuer         *pow.Issuer
+	BaseDifficulty int
+	MaxDifficulty  int
+	LoadThreshold  int
+}
+
+// NewProofOfWorkService 創建新的 ProofOfWorkService 實例；未設定 POW_SECRET 時使用臨時金鑰
+func NewProofOfWorkService(redisClient *redis.Client, cfg *config.Config) *ProofOfWorkService {
+	secret := []byte(cfg.POWSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("無法產生工作量證明金鑰: %v", err)
+		}
+		log.Println("未設定 POW_SECRET，使用臨時金鑰；多台伺服器時挑戰將無法互通")
+	}
+
+	maxDifficulty := cfg.POWMaxDifficulty
+	if maxDifficulty > pow.MaxDifficulty {
+		maxDifficulty = pow.MaxDifficulty
+	}
+
+	return &ProofOfWorkService{
+		RedisClient:    redisClient,
+		Issuer:         pow.NewIssuer(secret, powChallengeTTL),
+		BaseDifficulty: cfg.POWBaseDifficulty,
+		MaxDifficulty:  maxDifficulty,
+		LoadThreshold:  cfg.POWLoadThreshold,
+	}
+}
+
+// MinimumDifficulty 依風險分數計算解答至少須達到的難度
+func (s *ProofOfWorkService) MinimumDifficulty(riskScore int) int {
+	return s.clamp(s.BaseDifficulty + riskScore/powRiskScorePerBit)
+}
+
+// ChallengeDifficulty 計算新挑戰的難度：負載每超過門檻一倍再提高 1 位元；同時累計目前負載
+func (s *ProofOfWorkService) ChallengeDifficulty(ctx context.Context, riskScore int) int {
+	difficulty := s.BaseDifficulty + riskScore/powRiskScorePerBit
+
+	key := fmt.Sprintf("pow:load:%d", time.Now().Unix()/int64(powLoadWindow.Seconds()))
+	pipe := s.RedisClient.TxPipeline()
+	load := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 2*powLoadWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Redis 錯誤時不依負載調整難度
+		log.Printf("統計購票負載失敗: %v", err)
+		return s.clamp(difficulty)
+	}
+
+	if s.LoadThreshold > 0 {
+		for n := load.Val(); n > int64(s.LoadThreshold); n /= 2 {
+			difficulty++
+		}
+	}
+	return s.clamp(difficulty)
+}
+
+// IssueChallenge 為用戶簽發挑戰
+func (s *ProofOfWorkService) IssueChallenge(userID string, difficulty int) (*vo.PoWChallengeResponse, error) {
+	challenge, err := s.Issuer.Issue(userID, difficulty)
+	if err != nil {
+		return nil, err
+	}
+	return &vo.PoWChallengeResponse{
+		Challenge:  challenge.Token,
+		Algorithm:  "sha256",
+		Difficulty: challenge.Difficulty,
+		ExpiresAt:  challenge.ExpiresAt,
+	}, nil
+}
+
+// Redeem 驗證並兌換解答；每個挑戰只能兌換一次，兌換紀錄保存至挑戰過期
+func (s *ProofOfWorkService) Redeem(ctx context.Context, userID string, solution string, minDifficulty int) error {
+	challenge, err := s.Issuer.Verify(solution, userID)
+	if err != nil {
+		return err
+	}
+	if challenge.Difficulty < minDifficulty {
+		return errors.New("工作量證明難度不足")
+	}
+
+	ttl := time.Until(challenge.ExpiresAt) + time.Second
+	redeemed, err := s.RedisClient.SetNX(ctx, "pow:used:"+challenge.Nonce, userID, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return errors.New("工作量證明已使用")
+	}
+	return nil
+}
+
+func (s *ProofOfWorkService) clamp(difficulty int) int {
+	if difficulty > s.MaxDifficulty {
+		return s.MaxDifficulty
+	}
+	if difficulty < 0 {
+		return 0
+	}
+	return difficulty
+}
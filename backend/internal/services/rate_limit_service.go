@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+// 限流計數在 Redis 中的前綴
+const rateLimitPrefix = "rate_limit"
+
+// 政策未指定訊息時的拒絕訊息
+const defaultRateLimitMessage = "請求頻率過高，請稍後再試"
+
+// 限流計數的來源
+const (
+	RateLimitModeRedis  = "redis"
+	RateLimitModeMemory = "memory" // Redis 故障，改用程序內計數
+)
+
+// RateLimitDecision 一次請求的限流結果
+type RateLimitDecision struct {
+	Allowed    bool
+	Policy     string // 拒絕請求的政策
+	Message    string
+	Limit      int // 剩餘數量最少的政策，供回應標頭使用；0 表示沒有套用任何政策
+	Remaining  int
+	RetryAfter time.Duration
+	Evaluated  []string // 本次已計數的政策
+}
+
+// RateLimitService 依政策檔對請求計數與限流
+type RateLimitService struct {
+	RedisClient *redis.Client
+	Policies    *limiter.PolicyStore
+	DeviceGraph *DeviceGraphService // cluster 計數對象使用，未設定時以用戶計算
+	DryRun      bool                // 所有政策只記錄不拒絕
+	Breaker     *limiter.Breaker    // Redis 連續失敗時切換至程序內計數
+	limiters    map[string]limiter.Limiter
+	fallback    *limiter.MemoryLimiter
+}
+
+// NewRateLimitService 創建新的 RateLimitService 實例；設定 RATE_LIMIT_POLICY_FILE 時啟動即載入政策檔
+func NewRateLimitService(redisClient *redis.Client, cfg *config.Config) (*RateLimitService, error) {
+	policies, err := limiter.NewPolicyStore(cfg.RateLimitPolicyFile, DefaultRateLimitPolicies(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	// 所有演算法共用同一個斷路器與程序內限制器，Redis 故障時一起切換
+	breaker := limiter.NewBreaker(cfg.RateLimitBreakerThreshold, time.Duration(cfg.RateLimitBreakerCooldownSeconds)*time.Second)
+	fallback := limiter.NewMemoryLimiter(cfg.RateLimitFallbackInstances)
+
+	limiters := make(map[string]limiter.Limiter)
+	for _, strategy := range []string{limiter.StrategyFixedWindow, limiter.StrategySlidingWindow, limiter.StrategyTokenBucket} {
+		l, err := limiter.New(strategy, redisClient, rateLimitPrefix)
+		if err != nil {
+			return nil, err
+		}
+		limiters[strategy] = limiter.NewFallbackLimiter(l, fallback, breaker)
+	}
+
+	return &RateLimitService{
+		RedisClient: redisClient,
+		Policies:    policies,
+		DryRun:      cfg.RateLimitDryRun,
+		Breaker:     breaker,
+		limiters:    limiters,
+		fallback:    fallback,
+	}, nil
+}
+
+// Limiter 返回以 prefix 為 Redis key 前綴的固定窗口限制器，供其他服務的計數使用；
+// 與限流政策共用斷路器與程序內限制器，Redis 故障時一起切換
+func (s *RateLimitService) Limiter(prefix string) limiter.Limiter {
+	return limiter.NewFallbackLimiter(limiter.NewRateLimiter(s.RedisClient, prefix), limiter.WithPrefix(s.fallback, prefix), s.Breaker)
+}
+
+// DefaultRateLimitPolicies 未設定政策檔時使用的內建政策：每個 IP 每個路由每分鐘 100 次，
+// 購票每個 IP 每分鐘 10 次，訂單操作每個用戶（或帳號群組）每分鐘 5 次
+func DefaultRateLimitPolicies(cfg *config.Config) *limiter.PolicyFile {
+	purchaseKey := limiter.KeyUser
+	if cfg.PurchaseLimitPerCluster {
+		purchaseKey = limiter.KeyCluster
+	}
+
+	return &limiter.PolicyFile{
+		Policies: []limiter.Policy{
+			{
+				Name:     "default",
+				Key:      limiter.KeyIP,
+				PerRoute: true,
+				Limit:    100,
+				Window:   "60s",
+			},
+			{
+				Name:     "purchase_ip",
+				Routes:   []string{"/api/v1/tickets/purchase", "/api/v1/orders"},
+				Key:      limiter.KeyIP,
+				PerRoute: true,
+				Limit:    10,
+				Window:   "60s",
+			},
+			{
+				Name:     "purchase_user",
+				Routes:   []string{"/api/v1/orders/**"},
+				Key:      purchaseKey,
+				PerRoute: true,
+				Limit:    5,
+				Window:   "60s",
+				Message:  "購買操作頻率過高，請稍後再試",
+			},
+		},
+	}
+}
+
+// Check 對符合的政策計數；skip 中的政策已在同一請求的前一階段計數，不重複計算。
+// 無法取得計數對象的政策（如尚未登入時的 user）留待後續階段，不列入 Evaluated
+func (s *RateLimitService) Check(ctx context.Context, req dto.RateLimitRequest, skip map[string]bool) *RateLimitDecision {
+	file := s.Policies.Current()
+	decision := &RateLimitDecision{Allowed: true}
+
+	for i := range file.Policies {
+		policy := &file.Policies[i]
+		if skip[policy.Name] || !policy.Matches(req.Route, req.Method, req.Role) {
+			continue
+		}
+		subject := s.subject(ctx, policy.Key, req)
+		if subject == "" {
+			continue
+		}
+		decision.Evaluated = append(decision.Evaluated, policy.Name)
+
+		key := policy.Name + ":" + subject
+		if policy.PerRoute {
+			key += ":" + req.Route
+		}
+
+		l := s.limiters[policy.Strategy]
+		if l == nil {
+			l = s.limiters[limiter.StrategyFixedWindow]
+		}
+		allowed, remaining, retryAfter, err := l.Allow(ctx, key, policy.Limit, policy.WindowDuration())
+		if err != nil {
+			// 政策設定錯誤，繼續處理請求
+			log.Printf("限流政策 %s 計數失敗: %v", policy.Name, err)
+			continue
+		}
+
+		if decision.Limit == 0 || remaining < decision.Remaining {
+			decision.Limit = policy.Limit
+			decision.Remaining = remaining
+		}
+		if allowed {
+			continue
+		}
+
+		if s.DryRun || file.DryRun || policy.DryRun {
+			log.Printf("限流 dry-run：政策 %s 將拒絕 %s %s（%s）", policy.Name, req.Method, req.Route, subject)
+			continue
+		}
+		if decision.Allowed || retryAfter > decision.RetryAfter {
+			decision.Allowed = false
+			decision.Policy = policy.Name
+			decision.Message = policy.Message
+			if decision.Message == "" {
+				decision.Message = defaultRateLimitMessage
+			}
+			decision.RetryAfter = retryAfter
+		}
+	}
+	return decision
+}
+
+// Mode 目前的計數來源；斷路器未閉合時為程序內計數
+func (s *RateLimitService) Mode() string {
+	if s.Breaker.State() == limiter.CircuitClosed {
+		return RateLimitModeRedis
+	}
+	return RateLimitModeMemory
+}
+
+// subject 取得政策的計數對象，無法取得時返回空字串
+func (s *RateLimitService) subject(ctx context.Context, key string, req dto.RateLimitRequest) string {
+	var value string
+	switch key {
+	case limiter.KeyIP:
+		value = req.IPAddress
+	case limiter.KeyUser:
+		value = req.UserID
+	case limiter.KeyAPIKey:
+		value = req.APIKeyID
+	case limiter.KeyFingerprint:
+		value = req.Fingerprint
+	case limiter.KeyCluster:
+		if req.UserID == "" {
+			return ""
+		}
+		if s.DeviceGraph != nil {
+			// 同群組的帳號共用額度；查詢失敗時退回以帳號計算
+			if clusterID, err := s.DeviceGraph.ClusterID(ctx, req.UserID); err == nil {
+				return fmt.Sprintf("%s:%s", key, clusterID)
+			} else {
+				log.Printf("查詢帳號群組失敗: %v", err)
+			}
+		}
+		return fmt.Sprintf("%s:%s", limiter.KeyUser, req.UserID)
+	}
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", key, value)
+}
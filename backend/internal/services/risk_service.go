@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/pkg/ipreputation"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// RiskDecision 風險評估結果
+type RiskDecision string
+
+// 風險評估結果
+const (
+	RiskAllow     RiskDecision = "allow"
+	RiskChallenge RiskDecision = "challenge" // 須完成額外驗證後才能繼續
+	RiskBlock     RiskDecision = "block"
+)
+
+// RiskSignal 單一風險訊號及其說明
+type RiskSignal struct {
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// RiskAssessment 一次請求的風險評估，分數為所有訊號的加總
+type RiskAssessment struct {
+	Score    int          `json:"score"`
+	Decision RiskDecision `json:"decision"`
+	Signals  []RiskSignal `json:"signals"`
+}
+
+// RiskSignalSource 風險訊號來源；未發現風險時返回空切片
+type RiskSignalSource interface {
+	Name() string
+	Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error)
+}
+
+// RiskService 綜合多個訊號計算購票請求的風險分數
+type RiskService struct {
+	Sources        []RiskSignalSource
+	ChallengeScore int
+	BlockScore     int
+}
+
+// NewRiskService 創建新的 RiskService 實例，啟用所有內建訊號
+func NewRiskService(db *gorm.DB, redisClient *redis.Client, cfg *config.Config, reputation *ipreputation.List) *RiskService {
+	return &RiskService{
+		Sources: []RiskSignalSource{
+			NewFingerprintReuseSignal(redisClient),
+			NewAccountSignal(db),
+			NewVelocitySignal(redisClient),
+			NewHeadlessSignal(),
+			NewIPReputationSignal(reputation),
+		},
+		ChallengeScore: cfg.RiskChallengeScore,
+		BlockScore:     cfg.RiskBlockScore,
+	}
+}
+
+// Assess 評估請求風險；個別訊號失敗時略過該訊號，不影響其他訊號
+func (s *RiskService) Assess(ctx context.Context, req dto.RiskRequest) *RiskAssessment {
+	assessment := &RiskAssessment{
+		Decision: RiskAllow,
+		Signals:  []RiskSignal{},
+	}
+
+	for _, source := range s.Sources {
+		signals, err := source.Evaluate(ctx, req)
+		if err != nil {
+			log.Printf("風險訊號 %s 評估失敗: %v", source.Name(), err)
+			continue
+		}
+		for _, signal := range signals {
+			assessment.Score += signal.Score
+			assessment.Signals = append(assessment.Signals, signal)
+		}
+	}
+
+	switch {
+	case s.BlockScore > 0 && assessment.Score >= s.BlockScore:
+		assessment.Decision = RiskBlock
+	case s.ChallengeScore > 0 && assessment.Score >= s.ChallengeScore:
+		assessment.Decision = RiskChallenge
+	}
+
+	if len(assessment.Signals) > 0 {
+		log.Printf("購票風險評估: user=%s ip=%s score=%d decision=%s signals=[%s]",
+			req.UserID, req.Client.IPAddress, assessment.Score, assessment.Decision, assessment.Explain())
+	}
+
+	return assessment
+}
+
+// Explain 將各訊號說明組成單行文字，供日誌稽核
+func (a *RiskAssessment) Explain() string {
+	parts := make([]string, len(a.Signals))
+	for i, signal := range a.Signals {
+		parts[i] = fmt.Sprintf("%s(+%d): %s", signal.Name, signal.Score, signal.Reason)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/ipreputation"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// 統計同一裝置指紋被多少帳號使用的區間
+	fingerprintReuseWindow = 24 * time.Hour
+
+	// 統計購票請求頻率的區間
+	riskVelocityWindow = time.Minute
+)
+
+// FingerprintReuseSignal 同一裝置指紋被多個帳號使用
+type FingerprintReuseSignal struct {
+	RedisClient *redis.Client
+}
+
+// NewFingerprintReuseSignal 創建新的 FingerprintReuseSignal 實例
+func NewFingerprintReuseSignal(redisClient *redis.Client) *FingerprintReuseSignal {
+	return &FingerprintReuseSignal{
+		RedisClient: redisClient,
+	}
+}
+
+// Name 訊號名稱
+func (s *FingerprintReuseSignal) Name() string {
+	return "fingerprint_reuse"
+}
+
+// Evaluate 記錄指紋與帳號的對應，並計算區間內使用此指紋的帳號數
+func (s *FingerprintReuseSignal) Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error) {
+	if req.Client.TLSFingerprint == "" || req.UserID == "" {
+		return nil, nil
+	}
+
+	key := "risk:fingerprint_accounts:" + req.Client.TLSFingerprint
+	now := time.Now()
+
+	pipe := s.RedisClient.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Unix()), Member: req.UserID})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-fingerprintReuseWindow).Unix(), 10))
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, fingerprintReuseWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	accounts := count.Val()
+	var score int
+	switch {
+	case accounts >= 3:
+		score = 40
+	case accounts == 2:
+		score = 20
+	default:
+		return nil, nil
+	}
+
+	return []RiskSignal{{
+		Name:   s.Name(),
+		Score:  score,
+		Reason: fmt.Sprintf("此裝置指紋 24 小時內被 %d 個帳號使用", accounts),
+	}}, nil
+}
+
+// AccountSignal 新註冊或未驗證電子郵件的帳號
+type AccountSignal struct {
+	DB *gorm.DB
+}
+
+// NewAccountSignal 創建新的 AccountSignal 實例
+func NewAccountSignal(db *gorm.DB) *AccountSignal {
+	return &AccountSignal{
+		DB: db,
+	}
+}
+
+// Name 訊號名稱
+func (s *AccountSignal) Name() string {
+	return "account"
+}
+
+// Evaluate 依帳號年齡與電子郵件驗證狀態評分
+func (s *AccountSignal) Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error) {
+	if req.UserID == "" {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := s.DB.WithContext(ctx).Select("id", "email_verified_at", "created_at").Where("id = ?", req.UserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var signals []RiskSignal
+	age := time.Since(user.CreatedAt)
+	switch {
+	case age < time.Hour:
+		signals = append(signals, RiskSignal{Name: "account_age", Score: 25, Reason: "帳號註冊未滿 1 小時"})
+	case age < 24*time.Hour:
+		signals = append(signals, RiskSignal{Name: "account_age", Score: 10, Reason: "帳號註冊未滿 24 小時"})
+	}
+	if user.EmailVerifiedAt == nil {
+		signals = append(signals, RiskSignal{Name: "email_unverified", Score: 30, Reason: "電子郵件尚未驗證"})
+	}
+	return signals, nil
+}
+
+// VelocitySignal 短時間內大量購票請求
+type VelocitySignal struct {
+	RedisClient *redis.Client
+}
+
+// NewVelocitySignal 創建新的 VelocitySignal 實例
+func NewVelocitySignal(redisClient *redis.Client) *VelocitySignal {
+	return &VelocitySignal{
+		RedisClient: redisClient,
+	}
+}
+
+// Name 訊號名稱
+func (s *VelocitySignal) Name() string {
+	return "velocity"
+}
+
+// Evaluate 分別累計帳號與 IP 每分鐘的購票請求數
+func (s *VelocitySignal) Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error) {
+	var signals []RiskSignal
+
+	if req.UserID != "" {
+		count, err := s.count(ctx, "user:"+req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case count >= 10:
+			signals = append(signals, RiskSignal{Name: "user_velocity", Score: 30, Reason: fmt.Sprintf("帳號 1 分鐘內送出 %d 次購票請求", count)})
+		case count >= 5:
+			signals = append(signals, RiskSignal{Name: "user_velocity", Score: 15, Reason: fmt.Sprintf("帳號 1 分鐘內送出 %d 次購票請求", count)})
+		}
+	}
+
+	if req.Client.IPAddress != "" {
+		count, err := s.count(ctx, "ip:"+req.Client.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		// 門檻較高以容許共用 IP
+		if count >= 30 {
+			signals = append(signals, RiskSignal{Name: "ip_velocity", Score: 20, Reason: fmt.Sprintf("IP 1 分鐘內送出 %d 次購票請求", count)})
+		}
+	}
+
+	return signals, nil
+}
+
+// count 累加並返回區間內的請求數
+func (s *VelocitySignal) count(ctx context.Context, key string) (int64, error) {
+	key = "risk:velocity:" + key
+	pipe := s.RedisClient.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, riskVelocityWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// headlessUserAgents 無頭瀏覽器與自動化工具的 User-Agent 特徵
+var headlessUserAgents = []string{
+	"headlesschrome",
+	"phantomjs",
+	"puppeteer",
+	"playwright",
+	"selenium",
+	"webdriver",
+	"python-requests",
+	"python-urllib",
+	"aiohttp",
+	"go-http-client",
+	"curl/",
+	"wget/",
+	"scrapy",
+}
+
+// HeadlessSignal 無頭瀏覽器或自動化工具的請求特徵
+type HeadlessSignal struct{}
+
+// NewHeadlessSignal 創建新的 HeadlessSignal 實例
+func NewHeadlessSignal() *HeadlessSignal {
+	return &HeadlessSignal{}
+}
+
+// Name 訊號名稱
+func (s *HeadlessSignal) Name() string {
+	return "headless"
+}
+
+// Evaluate 檢查 User-Agent 與瀏覽器通常會送出的標頭
+func (s *HeadlessSignal) Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error) {
+	userAgent := strings.ToLower(req.Client.UserAgent)
+	if userAgent == "" {
+		return []RiskSignal{{Name: "headless_user_agent", Score: 40, Reason: "缺少 User-Agent"}}, nil
+	}
+	for _, marker := range headlessUserAgents {
+		if strings.Contains(userAgent, marker) {
+			return []RiskSignal{{Name: "headless_user_agent", Score: 40, Reason: "User-Agent 含自動化工具特徵: " + marker}}, nil
+		}
+	}
+
+	var signals []RiskSignal
+	if req.Header != nil {
+		if strings.Contains(strings.ToLower(req.Header.Get("Sec-Ch-Ua")), "headless") {
+			signals = append(signals, RiskSignal{Name: "headless_client_hints", Score: 40, Reason: "Sec-CH-UA 標示為無頭瀏覽器"})
+		}
+		if req.Header.Get("Accept-Language") == "" {
+			signals = append(signals, RiskSignal{Name: "missing_accept_language", Score: 10, Reason: "缺少 Accept-Language"})
+		}
+	}
+	return signals, nil
+}
+
+// IPReputationSignal 來源 IP 位於本地信譽名單
+type IPReputationSignal struct {
+	List *ipreputation.List
+}
+
+// NewIPReputationSignal 創建新的 IPReputationSignal 實例
+func NewIPReputationSignal(list *ipreputation.List) *IPReputationSignal {
+	return &IPReputationSignal{
+		List: list,
+	}
+}
+
+// Name 訊號名稱
+func (s *IPReputationSignal) Name() string {
+	return "ip_reputation"
+}
+
+// Evaluate 查詢 IP 是否在任一信譽名單中
+func (s *IPReputationSignal) Evaluate(ctx context.Context, req dto.RiskRequest) ([]RiskSignal, error) {
+	source, listed := s.List.Lookup(req.Client.IPAddress)
+	if !listed {
+		return nil, nil
+	}
+	return []RiskSignal{{
+		Name:   s.Name(),
+		Score:  50,
+		Reason: fmt.Sprintf("IP %s 位於信譽名單 %s", req.Client.IPAddress, source),
+	}}, nil
+}
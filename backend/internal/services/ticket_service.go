@@ -1,13 +1,14 @@
 package services
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
 	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/pdf"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -16,43 +17,18 @@ import (
 type TicketService struct {
 	DB          *gorm.DB
 	RedisClient *redis.Client
+	Config      *config.Config
 }
 
 // NewTicketService 創建新的 TicketService 實例
-func NewTicketService(db *gorm.DB, redisClient *redis.Client) *TicketService {
+func NewTicketService(db *gorm.DB, redisClient *redis.Client, config *config.Config) *TicketService {
 	return &TicketService{
 		DB:          db,
 		RedisClient: redisClient,
+		Config:      config,
 	}
 }
 
-// CheckFingerprint 檢查指紋是否已經購買過特定票券
-func (s *TicketService) CheckFingerprint(ctx context.Context, fingerprint string, ticketTypeID string) (bool, error) {
-	// 建立 Redis key
-	key := fmt.Sprintf("fingerprint:%s:%s", fingerprint, ticketTypeID)
-	
-	// 檢查是否存在
-	exists, err := s.RedisClient.Exists(ctx, key).Result()
-	if err != nil {
-		return false, err
-	}
-	
-	return exists > 0, nil
-}
-
-// RecordFingerprint 記錄指紋購買了特定票券
-func (s *TicketService) RecordFingerprint(ctx context.Context, fingerprint string, ticketTypeID string, userID string) error {
-	// 建立 Redis key
-	key := fmt.Sprintf("fingerprint:%s:%s", fingerprint, ticketTypeID)
-	
-	// 存儲信息
-	info := fmt.Sprintf("user_id=%s,timestamp=%s", userID, time.Now().Format(time.RFC3339))
-	
-	// 設置過期時間為 24 小時
-	_, err := s.RedisClient.Set(ctx, key, info, 24*time.Hour).Result()
-	return err
-}
-
 // CheckAvailability 檢查票券是否可用
 func (s *TicketService) CheckAvailability(ticketTypeID string, quantity int) (bool, error) {
 	var ticketType models.TicketType
@@ -152,3 +128,175 @@ func (s *TicketService) GenerateTickets(orderItemID string, quantity int) error
 		return tx.Create(&tickets).Error
 	})
 }
+
+// TicketDetail 票券及其所屬訂單、票種與活動
+type TicketDetail struct {
+	Ticket     models.Ticket
+	OrderItem  models.OrderItem
+	Order      models.Order
+	TicketType models.TicketType
+	Event      models.Event
+}
+
+// GetTicketDetail 獲取票券詳細資料，並確認票券屬於該使用者（具訂單查詢權限的角色可查看所有票券）
+func (s *TicketService) GetTicketDetail(ticketID string, userID string, role string) (*TicketDetail, error) {
+	id, err := uuid.Parse(ticketID)
+	if err != nil {
+		return nil, errors.New("無效的票券 ID")
+	}
+
+	detail, err := s.loadTicketDetail(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 非本人的票券一律視為不存在，避免洩漏票券資訊
+	if !models.HasPermission(role, models.PermissionOrderRead) && detail.Order.UserID.String() != userID {
+		return nil, errors.New("票券不存在")
+	}
+
+	return detail, nil
+}
+
+// loadTicketDetail 載入票券及其所屬訂單、票種與活動
+func (s *TicketService) loadTicketDetail(id uuid.UUID) (*TicketDetail, error) {
+	var detail TicketDetail
+	if err := s.DB.First(&detail.Ticket, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("票券不存在")
+		}
+		return nil, err
+	}
+
+	if err := s.DB.First(&detail.OrderItem, detail.Ticket.OrderItemID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.First(&detail.Order, detail.OrderItem.OrderID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Unscoped().First(&detail.TicketType, detail.OrderItem.TicketTypeID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Unscoped().First(&detail.Event, detail.TicketType.EventID).Error; err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
+}
+
+// GetUserTickets 獲取用戶持有的所有票券，並依活動是否已結束分為即將到來與過往
+func (s *TicketService) GetUserTickets(userID string) (*vo.UserTicketsResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("無效的用戶 ID")
+	}
+
+	var rows []struct {
+		vo.TicketResponse
+		EventEndTime time.Time
+	}
+	if err := s.DB.Model(&models.Ticket{}).
+		Select("tickets.*, events.title AS event_title, events.start_time AS event_time, "+
+			"events.end_time AS event_end_time, events.location AS event_location, ticket_types.name AS ticket_type_name").
+		Joins("JOIN order_items ON order_items.id = tickets.order_item_id").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN ticket_types ON ticket_types.id = order_items.ticket_type_id").
+		Joins("JOIN events ON events.id = ticket_types.event_id").
+		Where("orders.user_id = ?", uid).
+		Order("events.start_time ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	response := &vo.UserTicketsResponse{
+		Upcoming: []vo.TicketResponse{},
+		Past:     []vo.TicketResponse{},
+	}
+	now := time.Now()
+	for _, row := range rows {
+		if row.EventEndTime.Before(now) {
+			response.Past = append(response.Past, row.TicketResponse)
+		} else {
+			response.Upcoming = append(response.Upcoming, row.TicketResponse)
+		}
+	}
+
+	// 過往活動以最近的排在前面
+	for i, j := 0, len(response.Past)-1; i < j; i, j = i+1, j-1 {
+		response.Past[i], response.Past[j] = response.Past[j], response.Past[i]
+	}
+
+	return response, nil
+}
+
+// GetTicketPDF 產生單張電子票券 PDF
+func (s *TicketService) GetTicketPDF(ticketID string, userID string, role string) ([]byte, error) {
+	detail, err := s.GetTicketDetail(ticketID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	page := pdf.TicketPage{
+		Ticket:     &detail.Ticket,
+		TicketType: &detail.TicketType,
+		Event:      &detail.Event,
+	}
+
+	return pdf.RenderTickets(s.Config.PDFFontPath, s.Config.TicketBrandName, []pdf.TicketPage{page})
+}
+
+// GetOrderTicketsPDF 將訂單的所有票券匯出為多頁 PDF
+func (s *TicketService) GetOrderTicketsPDF(orderID string) ([]byte, error) {
+	id, err := uuid.Parse(orderID)
+	if err != nil {
+		return nil, errors.New("無效的訂單 ID")
+	}
+
+	var order models.Order
+	if err := s.DB.Preload("OrderItems.Tickets").First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("訂單不存在")
+		}
+		return nil, err
+	}
+
+	var pages []pdf.TicketPage
+	for i := range order.OrderItems {
+		orderItem := &order.OrderItems[i]
+		for j := range orderItem.Tickets {
+			page, err := s.buildTicketPage(&orderItem.Tickets[j], orderItem)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, *page)
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, errors.New("訂單沒有票券")
+	}
+
+	return pdf.RenderTickets(s.Config.PDFFontPath, s.Config.TicketBrandName, pages)
+}
+
+// buildTicketPage 載入票券對應的票種與活動
+func (s *TicketService) buildTicketPage(ticket *models.Ticket, orderItem *models.OrderItem) (*pdf.TicketPage, error) {
+	var ticketType models.TicketType
+	if err := s.DB.Unscoped().First(&ticketType, orderItem.TicketTypeID).Error; err != nil {
+		return nil, err
+	}
+
+	var event models.Event
+	if err := s.DB.Unscoped().First(&event, ticketType.EventID).Error; err != nil {
+		return nil, err
+	}
+
+	return &pdf.TicketPage{
+		Ticket:     ticket,
+		TicketType: &ticketType,
+		Event:      &event,
+	}, nil
+}
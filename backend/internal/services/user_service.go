@@ -0,0 +1,519 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emailChangeTokenTTL 變更電子郵件確認連結的有效期限
+const emailChangeTokenTTL = 24 * time.Hour
+
+// unusablePasswordHash 無法通過驗證的密碼雜湊，用於已刪除及僅以第三方登入的帳號
+const unusablePasswordHash = "!"
+
+// reauthWindow 未設定密碼的帳號執行敏感操作時，目前工作階段須在此期間內登入
+const reauthWindow = 10 * time.Minute
+
+// UserService 處理用戶個人資料相關業務邏輯
+type UserService struct {
+	DB            *gorm.DB
+	Config        *config.Config
+	Mailer        mailer.Mailer
+	OrderService  *OrderService
+	TicketService *TicketService
+}
+
+// NewUserService 創建新的 UserService 實例
+func NewUserService(db *gorm.DB, config *config.Config, mailer mailer.Mailer, orderService *OrderService, ticketService *TicketService) *UserService {
+	return &UserService{
+		DB:            db,
+		Config:        config,
+		Mailer:        mailer,
+		OrderService:  orderService,
+		TicketService: ticketService,
+	}
+}
+
+// GetProfile 獲取用戶個人資料
+func (s *UserService) GetProfile(userID string) (*vo.UserResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+// UpdateProfile 更新用戶姓名與電話
+func (s *UserService) UpdateProfile(userID string, req dto.UpdateProfileRequest) (*vo.UserResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Phone != "" {
+		updates["phone"] = req.Phone
+	}
+
+	if err := s.DB.Model(user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+// RequestEmailChange 驗證密碼後寄送確認連結至新電子郵件，確認後才會變更
+func (s *UserService) RequestEmailChange(ctx context.Context, userID string, sessionID string, req dto.ChangeEmailRequest) error {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := reauthenticate(s.DB, user, sessionID, req.Password, "密碼不正確"); err != nil {
+		return err
+	}
+
+	newEmail := strings.ToLower(strings.TrimSpace(req.Email))
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.New("新電子郵件與目前相同")
+	}
+	if taken, err := s.emailTaken(s.DB, newEmail, user.ID); err != nil {
+		return err
+	} else if taken {
+		return errors.New("電子郵件已被使用")
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// 同一時間只保留最新的變更請求
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, models.UserTokenPurposeEmailChange).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   models.UserTokenPurposeEmailChange,
+			TokenHash: tokenHash,
+			NewEmail:  newEmail,
+			ExpiresAt: time.Now().Add(emailChangeTokenTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/confirm-email-change?token=%s", s.Config.FrontendURL, token)
+	if err := s.Mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "請確認您的新電子郵件",
+		Body:    fmt.Sprintf("您好 %s，\n\n請於 24 小時內點擊以下連結確認變更電子郵件：\n%s\n\n若您沒有提出此請求，請忽略此郵件。", user.Name, link),
+	}); err != nil {
+		return err
+	}
+
+	// 通知原電子郵件，讓帳號被盜用時用戶能及早發現
+	if err := s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "帳號電子郵件變更通知",
+		Body:    fmt.Sprintf("您好 %s，\n\n我們收到將帳號電子郵件變更為 %s 的請求。若這不是您本人的操作，請立即變更密碼。", user.Name, newEmail),
+	}); err != nil {
+		log.Printf("寄送電子郵件變更通知失敗: %v", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange 使用確認令牌完成電子郵件變更
+func (s *UserService) ConfirmEmailChange(token string) (*vo.UserResponse, error) {
+	var user models.User
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var userToken models.UserToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND purpose = ?", hashToken(token), models.UserTokenPurposeEmailChange).
+			First(&userToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("無效或已過期的令牌")
+			}
+			return err
+		}
+		if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+			return errors.New("無效或已過期的令牌")
+		}
+
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("無效或已過期的令牌")
+			}
+			return err
+		}
+
+		// 發出請求後新電子郵件可能已被其他帳號註冊
+		if taken, err := s.emailTaken(tx, userToken.NewEmail, user.ID); err != nil {
+			return err
+		} else if taken {
+			return errors.New("電子郵件已被使用")
+		}
+
+		now := time.Now()
+		if err := tx.Model(&userToken).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		// 點擊確認連結即證明擁有新電子郵件
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"email":             userToken.NewEmail,
+			"email_verified_at": now,
+			"updated_at":        now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(&user), nil
+}
+
+// ChangePassword 驗證目前密碼後變更密碼，並撤銷目前工作階段以外的所有工作階段；未設定密碼的帳號藉此設定密碼
+func (s *UserService) ChangePassword(userID string, sessionID string, req dto.ChangePasswordRequest) error {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := reauthenticate(s.DB, user, sessionID, req.CurrentPassword, "目前密碼不正確"); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// 變更密碼並登出其他裝置，目前的工作階段保持登入
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"password_hash": string(hashedPassword),
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return revokeOtherSessions(tx, user.ID, sessionID)
+	})
+}
+
+// DeleteAccount 刪除帳號：匿名化個人資料並停用帳號，訂單與發票因會計需求保留
+func (s *UserService) DeleteAccount(userID string, sessionID string, password string) error {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := reauthenticate(s.DB, user, sessionID, password, "密碼不正確"); err != nil {
+		return err
+	}
+	if user.Role == models.RoleAdmin {
+		return errors.New("管理員帳號無法自行刪除")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		// 登入嘗試紀錄以帳號或電子郵件記錄，兩者都須刪除
+		if err := tx.Where("user_id = ? OR LOWER(email) = ?", user.ID, strings.ToLower(user.Email)).Delete(&models.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DeviceLink{}).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+		// 工作階段保留撤銷紀錄，清除其中的 IP、瀏覽器與 TLS 指紋
+		if err := tx.Model(&models.Session{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"user_agent":      "",
+			"ip_address":      "",
+			"tls_fingerprint": "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := revokeUserAPIKeys(tx, user.ID); err != nil {
+			return err
+		}
+
+		// 以無法登入的密碼雜湊及保留網域的電子郵件取代原資料，釋放原電子郵件供重新註冊
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"email":           fmt.Sprintf("deleted-%s@deleted.invalid", user.ID),
+			"password_hash":   unusablePasswordHash,
+			"name":            "已刪除用戶",
+			"phone":           "",
+			"tls_fingerprint": "",
+			"mfa_secret":      "",
+			"updated_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(user).Error
+	})
+}
+
+// ExportUserData 匯出我們保存的所有用戶資料
+func (s *UserService) ExportUserData(userID string) (*vo.UserDataExport, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &vo.UserDataExport{
+		ExportedAt:     time.Now(),
+		User:           *toUserResponse(user),
+		TLSFingerprint: user.TLSFingerprint,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+		Orders:         []vo.OrderDetailResponse{},
+		Invoices:       []vo.InvoiceResponse{},
+		Identities:     []vo.UserIdentityResponse{},
+		DeviceLinks:    []vo.DeviceLinkResponse{},
+		Sessions:       []vo.SessionExport{},
+		LoginAttempts:  []vo.LoginAttemptExport{},
+	}
+
+	// 第三方登入身分
+	var identities []models.UserIdentity
+	if err := s.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	for i := range identities {
+		export.Identities = append(export.Identities, *toUserIdentityResponse(&identities[i]))
+	}
+
+	// 裝置與 IP 關聯
+	var deviceLinks []models.DeviceLink
+	if err := s.DB.Where("user_id = ?", user.ID).Order("kind, first_seen_at").Find(&deviceLinks).Error; err != nil {
+		return nil, err
+	}
+	for i := range deviceLinks {
+		export.DeviceLinks = append(export.DeviceLinks, toDeviceLinkResponse(&deviceLinks[i]))
+	}
+
+	// 工作階段，包含已撤銷的工作階段
+	var sessions []models.Session
+	if err := s.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, vo.SessionExport{
+			ID:             session.ID,
+			UserAgent:      session.UserAgent,
+			IPAddress:      session.IPAddress,
+			TLSFingerprint: session.TLSFingerprint,
+			CreatedAt:      session.CreatedAt,
+			LastSeenAt:     session.LastSeenAt,
+			RevokedAt:      session.RevokedAt,
+		})
+	}
+
+	// 登入嘗試紀錄
+	var attempts []models.LoginAttempt
+	if err := s.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	for _, attempt := range attempts {
+		export.LoginAttempts = append(export.LoginAttempts, vo.LoginAttemptExport{
+			IPAddress: attempt.IPAddress,
+			UserAgent: attempt.UserAgent,
+			Result:    attempt.Result,
+			CreatedAt: attempt.CreatedAt,
+		})
+	}
+
+	// 訂單
+	var orderIDs []uuid.UUID
+	if err := s.DB.Model(&models.Order{}).
+		Where("user_id = ?", user.ID).
+		Order("created_at ASC").
+		Pluck("id", &orderIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, orderID := range orderIDs {
+		order, err := s.OrderService.GetUserOrderDetail(orderID.String(), userID)
+		if err != nil {
+			return nil, err
+		}
+		export.Orders = append(export.Orders, *order)
+	}
+
+	// 票券
+	tickets, err := s.TicketService.GetUserTickets(userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Tickets = *tickets
+
+	// 發票與折讓單
+	var invoices []models.Invoice
+	if err := s.DB.Where("user_id = ?", user.ID).Order("issued_at ASC").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	for _, invoice := range invoices {
+		export.Invoices = append(export.Invoices, vo.InvoiceResponse{
+			ID:            invoice.ID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			Type:          invoice.Type,
+			OrderID:       invoice.OrderID,
+			Subtotal:      invoice.Subtotal,
+			FeeAmount:     invoice.FeeAmount,
+			TaxAmount:     invoice.TaxAmount,
+			TotalAmount:   invoice.TotalAmount,
+			IssuedAt:      invoice.IssuedAt,
+		})
+	}
+
+	return export, nil
+}
+
+// UpdateRole 變更用戶角色，並撤銷其所有工作階段，使既有令牌中的角色聲明立即失效
+func (s *UserService) UpdateRole(operatorID string, userID string, role string) (*vo.UserResponse, error) {
+	if !models.ValidRole(role) {
+		return nil, errors.New("無效的角色")
+	}
+	if operatorID == userID {
+		return nil, errors.New("無法變更自己的角色")
+	}
+
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return toUserResponse(user), nil
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"role":       role,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+// loadUser 根據 ID 載入用戶
+func (s *UserService) loadUser(userID string) (*models.User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("無效的使用者 ID")
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("使用者不存在")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// reauthenticate 敏感操作前再次確認身分；密碼不符時回傳 wrongPassword
+//
+// 僅以第三方登入的帳號沒有密碼可比對，改為要求目前的工作階段於 reauthWindow 內登入，
+// 超過時回傳「請重新登入後再試」，用戶重新以第三方登入後即可操作。
+func reauthenticate(db *gorm.DB, user *models.User, sessionID string, password string, wrongPassword string) error {
+	if user.PasswordHash != unusablePasswordHash {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			return errors.New(wrongPassword)
+		}
+		return nil
+	}
+
+	var session models.Session
+	err := db.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, user.ID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && time.Since(session.CreatedAt) > reauthWindow) {
+		return errors.New("請重新登入後再試")
+	}
+	return err
+}
+
+// emailTaken 檢查電子郵件是否已被其他帳號使用（包含已停用帳號）
+func (s *UserService) emailTaken(tx *gorm.DB, email string, exceptUserID uuid.UUID) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&models.User{}).
+		Where("LOWER(email) = ? AND id <> ?", strings.ToLower(email), exceptUserID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// toUserResponse 將用戶模型轉換為 VO
+func toUserResponse(user *models.User) *vo.UserResponse {
+	return &vo.UserResponse{
+		ID:             user.ID.String(),
+		Name:           user.Name,
+		Email:          user.Email,
+		Phone:          user.Phone,
+		Role:           user.Role,
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		MFAEnabled:     user.MFAEnabledAt != nil,
+		ServiceAccount: user.IsServiceAccount,
+	}
+}
+
+// newOpaqueToken 產生隨機令牌及其 SHA-256 雜湊，資料庫僅保存雜湊
+func newOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken 計算令牌的 SHA-256 雜湊
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
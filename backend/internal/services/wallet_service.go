@@ -0,0 +1,344 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/wallet"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WalletService 處理 Apple Wallet 與 Google Wallet 票券
+type WalletService struct {
+	DB            *gorm.DB
+	Config        *config.Config
+	TicketService *TicketService
+	ApplePasses   *wallet.ApplePassBuilder
+	APNs          *wallet.APNsClient
+	GooglePasses  *wallet.GooglePassBuilder
+}
+
+// NewWalletService 創建新的 WalletService 實例，未設定憑證的錢包類型會停用
+func NewWalletService(db *gorm.DB, config *config.Config, ticketService *TicketService) *WalletService {
+	service := &WalletService{
+		DB:            db,
+		Config:        config,
+		TicketService: ticketService,
+	}
+
+	if config.AppleWalletPassTypeID != "" {
+		applePasses, err := wallet.NewApplePassBuilder(wallet.AppleConfig{
+			PassTypeIdentifier: config.AppleWalletPassTypeID,
+			TeamIdentifier:     config.AppleWalletTeamID,
+			OrganizationName:   config.TicketBrandName,
+			WebServiceURL:      config.AppleWalletWebServiceURL,
+			CertPath:           config.AppleWalletCertPath,
+			KeyPath:            config.AppleWalletKeyPath,
+			WWDRPath:           config.AppleWalletWWDRPath,
+			AssetsDir:          config.AppleWalletAssetsDir,
+		})
+		if err != nil {
+			log.Printf("Apple Wallet 初始化失敗: %v", err)
+		} else {
+			service.ApplePasses = applePasses
+			service.APNs = wallet.NewAPNsClient(config.APNsURL, applePasses.PassTypeIdentifier(), applePasses.TLSCertificate())
+		}
+	}
+
+	if config.GoogleWalletIssuerID != "" {
+		googlePasses, err := wallet.NewGooglePassBuilder(wallet.GoogleConfig{
+			IssuerID:           config.GoogleWalletIssuerID,
+			ServiceAccountPath: config.GoogleWalletKeyPath,
+			Origins:            []string{config.FrontendURL},
+		})
+		if err != nil {
+			log.Printf("Google Wallet 初始化失敗: %v", err)
+		} else {
+			service.GooglePasses = googlePasses
+		}
+	}
+
+	return service
+}
+
+// GenerateApplePass 產生使用者票券的 .pkpass 檔案
+func (s *WalletService) GenerateApplePass(ticketID string, userID string, role string) ([]byte, error) {
+	if s.ApplePasses == nil {
+		return nil, errors.New("未啟用 Apple Wallet")
+	}
+
+	detail, err := s.TicketService.GetTicketDetail(ticketID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	pass, err := s.ensurePass(detail.Ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.ticketInfo(detail, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ApplePasses.Build(*info, pass.AuthenticationToken)
+}
+
+// GoogleSaveLink 產生使用者票券的「加入 Google 錢包」連結
+func (s *WalletService) GoogleSaveLink(ticketID string, userID string, role string) (string, error) {
+	if s.GooglePasses == nil {
+		return "", errors.New("未啟用 Google Wallet")
+	}
+
+	detail, err := s.TicketService.GetTicketDetail(ticketID, userID, role)
+	if err != nil {
+		return "", err
+	}
+
+	pass, err := s.ensurePass(detail.Ticket.ID)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := s.ticketInfo(detail, pass)
+	if err != nil {
+		return "", err
+	}
+
+	return s.GooglePasses.SaveLink(*info)
+}
+
+// RegisterDevice 註冊 Apple Wallet 裝置以接收票券更新推播，返回是否為新註冊
+func (s *WalletService) RegisterDevice(deviceID, passTypeID, serialNumber, authToken, pushToken string) (bool, error) {
+	if _, err := s.authenticatePass(passTypeID, serialNumber, authToken); err != nil {
+		return false, err
+	}
+
+	var registration models.WalletRegistration
+	err := s.DB.Where("device_library_identifier = ? AND serial_number = ?", deviceID, serialNumber).
+		First(&registration).Error
+	if err == nil {
+		// 已註冊則更新推播令牌
+		return false, s.DB.Model(&registration).Updates(map[string]interface{}{
+			"push_token": pushToken,
+			"updated_at": time.Now(),
+		}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	registration = models.WalletRegistration{
+		DeviceLibraryIdentifier: deviceID,
+		SerialNumber:            serialNumber,
+		PushToken:               pushToken,
+	}
+	if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&registration).Error; err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UnregisterDevice 取消 Apple Wallet 裝置註冊
+func (s *WalletService) UnregisterDevice(deviceID, passTypeID, serialNumber, authToken string) error {
+	if _, err := s.authenticatePass(passTypeID, serialNumber, authToken); err != nil {
+		return err
+	}
+
+	return s.DB.Where("device_library_identifier = ? AND serial_number = ?", deviceID, serialNumber).
+		Delete(&models.WalletRegistration{}).Error
+}
+
+// GetUpdatedSerialNumbers 獲取裝置上自指定時間後有更新的票券序號，並返回新的更新標記
+func (s *WalletService) GetUpdatedSerialNumbers(deviceID, passTypeID, updatedSince string) ([]string, string, error) {
+	if s.ApplePasses == nil || passTypeID != s.ApplePasses.PassTypeIdentifier() {
+		return nil, "", errors.New("票券類型不存在")
+	}
+
+	query := s.DB.Model(&models.WalletPass{}).
+		Joins("JOIN wallet_registrations ON wallet_registrations.serial_number = wallet_passes.serial_number").
+		Where("wallet_registrations.device_library_identifier = ?", deviceID)
+
+	if updatedSince != "" {
+		since, err := strconv.ParseInt(updatedSince, 10, 64)
+		if err == nil {
+			query = query.Where("wallet_passes.updated_at > ?", time.Unix(since, 0))
+		}
+	}
+
+	var passes []models.WalletPass
+	if err := query.Find(&passes).Error; err != nil {
+		return nil, "", err
+	}
+
+	serialNumbers := make([]string, 0, len(passes))
+	var lastUpdated time.Time
+	for _, pass := range passes {
+		serialNumbers = append(serialNumbers, pass.SerialNumber)
+		if pass.UpdatedAt.After(lastUpdated) {
+			lastUpdated = pass.UpdatedAt
+		}
+	}
+
+	return serialNumbers, strconv.FormatInt(lastUpdated.Unix(), 10), nil
+}
+
+// GetLatestApplePass 供 Wallet 取得最新版本的票券
+func (s *WalletService) GetLatestApplePass(passTypeID, serialNumber, authToken string) ([]byte, time.Time, error) {
+	pass, err := s.authenticatePass(passTypeID, serialNumber, authToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	detail, err := s.TicketService.loadTicketDetail(pass.TicketID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	info, err := s.ticketInfo(detail, pass)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	content, err := s.ApplePasses.Build(*info, pass.AuthenticationToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return content, pass.UpdatedAt, nil
+}
+
+// NotifyEventUpdated 活動資訊變更後更新所有相關的錢包票券
+func (s *WalletService) NotifyEventUpdated(eventID uuid.UUID) {
+	var event models.Event
+	if err := s.DB.First(&event, eventID).Error; err != nil {
+		log.Printf("更新錢包票券失敗，無法載入活動 %s: %v", eventID, err)
+		return
+	}
+
+	ticketIDs := s.DB.Model(&models.Ticket{}).
+		Select("tickets.id").
+		Joins("JOIN order_items ON order_items.id = tickets.order_item_id").
+		Joins("JOIN ticket_types ON ticket_types.id = order_items.ticket_type_id").
+		Where("ticket_types.event_id = ?", eventID)
+
+	// 標記票券已更新，Wallet 取得更新序號時會包含這些票券
+	if err := s.DB.Model(&models.WalletPass{}).
+		Where("ticket_id IN (?)", ticketIDs).
+		Update("updated_at", time.Now()).Error; err != nil {
+		log.Printf("更新錢包票券失敗: %v", err)
+		return
+	}
+
+	if s.APNs != nil {
+		var pushTokens []string
+		if err := s.DB.Model(&models.WalletRegistration{}).
+			Distinct("wallet_registrations.push_token").
+			Joins("JOIN wallet_passes ON wallet_passes.serial_number = wallet_registrations.serial_number").
+			Where("wallet_passes.ticket_id IN (?)", ticketIDs).
+			Pluck("wallet_registrations.push_token", &pushTokens).Error; err != nil {
+			log.Printf("查詢錢包裝置失敗: %v", err)
+		}
+
+		for _, pushToken := range pushTokens {
+			if err := s.APNs.NotifyPassUpdated(pushToken); err != nil {
+				log.Printf("推播票券更新失敗: %v", err)
+			}
+		}
+	}
+
+	if s.GooglePasses != nil {
+		info := wallet.TicketInfo{
+			EventID:    event.ID.String(),
+			EventTitle: event.Title,
+			Location:   event.Location,
+			StartTime:  event.StartTime,
+			EndTime:    event.EndTime,
+		}
+		if err := s.GooglePasses.UpdateEventClass(info); err != nil {
+			log.Printf("更新 Google Wallet 活動失敗: %v", err)
+		}
+	}
+}
+
+// ensurePass 獲取票券的錢包紀錄，不存在時建立
+func (s *WalletService) ensurePass(ticketID uuid.UUID) (*models.WalletPass, error) {
+	var pass models.WalletPass
+	err := s.DB.Where("ticket_id = ?", ticketID).First(&pass).Error
+	if err == nil {
+		return &pass, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	pass = models.WalletPass{
+		TicketID:            ticketID,
+		SerialNumber:        ticketID.String(),
+		AuthenticationToken: hex.EncodeToString(token),
+	}
+	if err := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&pass).Error; err != nil {
+		return nil, err
+	}
+
+	// 併發建立時以資料庫中的紀錄為準
+	if err := s.DB.Where("ticket_id = ?", ticketID).First(&pass).Error; err != nil {
+		return nil, err
+	}
+
+	return &pass, nil
+}
+
+// authenticatePass 驗證 Wallet 請求帶入的票券驗證令牌
+func (s *WalletService) authenticatePass(passTypeID, serialNumber, authToken string) (*models.WalletPass, error) {
+	if s.ApplePasses == nil || passTypeID != s.ApplePasses.PassTypeIdentifier() {
+		return nil, errors.New("票券驗證失敗")
+	}
+
+	var pass models.WalletPass
+	if err := s.DB.Where("serial_number = ?", serialNumber).First(&pass).Error; err != nil {
+		return nil, errors.New("票券驗證失敗")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pass.AuthenticationToken), []byte(authToken)) != 1 {
+		return nil, errors.New("票券驗證失敗")
+	}
+
+	return &pass, nil
+}
+
+// ticketInfo 將票券資料轉換為錢包票券內容
+func (s *WalletService) ticketInfo(detail *TicketDetail, pass *models.WalletPass) (*wallet.TicketInfo, error) {
+	var user models.User
+	if err := s.DB.Unscoped().First(&user, detail.Order.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	return &wallet.TicketInfo{
+		SerialNumber:   pass.SerialNumber,
+		TicketCode:     detail.Ticket.TicketCode,
+		EventID:        detail.Event.ID.String(),
+		EventTitle:     detail.Event.Title,
+		Location:       detail.Event.Location,
+		StartTime:      detail.Event.StartTime,
+		EndTime:        detail.Event.EndTime,
+		TicketTypeName: detail.TicketType.Name,
+		HolderName:     user.Name,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyResponse API 金鑰回應，不含金鑰本身
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserID     uuid.UUID  `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Name       string     `json:"name" example:"經銷商 A 正式環境"`
+	Prefix     string     `json:"prefix" example:"tg_3f9a1c2e"`
+	Scopes     []string   `json:"scopes" example:"ticket:use"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59+08:00"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-06-02T09:15:00+08:00"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+}
+
+// APIKeyCreatedResponse 簽發 API 金鑰回應，金鑰明文僅在此時提供一次
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"tg_3f9a1c2e5b7d4a6c8e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f"`
+}
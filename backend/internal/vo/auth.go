@@ -2,18 +2,25 @@ package vo
 
 // 使用者資訊 VO
 type UserResponse struct {
-	ID    string `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name  string `json:"name" example:"張三"`
-	Email string `json:"email" example:"user@example.com"`
-	Phone string `json:"phone" example:"0912345678"`
-	Role  string `json:"role" example:"user"`
+	ID             string `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name           string `json:"name" example:"張三"`
+	Email          string `json:"email" example:"user@example.com"`
+	Phone          string `json:"phone" example:"0912345678"`
+	Role           string `json:"role" example:"user"`
+	EmailVerified  bool   `json:"email_verified" example:"true"`
+	MFAEnabled     bool   `json:"mfa_enabled" example:"false"`
+	ServiceAccount bool   `json:"service_account,omitempty" example:"false"`
 }
 
-// 登入響應 VO
+// 登入響應 VO；需要兩步驟驗證時僅返回 MFAToken，完成驗證後才簽發令牌
 type LoginResponse struct {
-	Token        string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	RefreshToken string       `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	User         UserResponse `json:"user"`
+	Token                 string       `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken          string       `json:"refresh_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	User                  UserResponse `json:"user"`
+	MFARequired           bool         `json:"mfa_required,omitempty" example:"false"`
+	MFAEnrollmentRequired bool         `json:"mfa_enrollment_required,omitempty" example:"false"`
+	MFAToken              string       `json:"mfa_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RecoveryCodes         []string     `json:"recovery_codes,omitempty" example:"k7m2-9xqa,p3vd-w8ne"`
 }
 
 // 註冊響應 VO
@@ -0,0 +1,38 @@
+package vo
+
+import "time"
+
+// DeviceLinkResponse 用戶與裝置、IP 或付款方式的關聯
+type DeviceLinkResponse struct {
+	UserID      string    `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Kind        string    `json:"kind" example:"device"`
+	Value       string    `json:"value" example:"t13d1516h2_8daaf6152771_e5627efa2ab1"`
+	SeenCount   int       `json:"seen_count" example:"3"`
+	FirstSeenAt time.Time `json:"first_seen_at" example:"2024-06-01T10:30:00+08:00"`
+	LastSeenAt  time.Time `json:"last_seen_at" example:"2024-06-02T09:15:00+08:00"`
+}
+
+// DeviceClusterUser 群組中的帳號
+type DeviceClusterUser struct {
+	ID             string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Email          string    `json:"email" example:"user@example.com"`
+	Name           string    `json:"name" example:"張三"`
+	TLSFingerprint string    `json:"tls_fingerprint,omitempty" example:"t13d1516h2_8daaf6152771_e5627efa2ab1"`
+	CreatedAt      time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+}
+
+// DeviceClusterResponse 共用裝置、IP 或付款方式的帳號群組
+type DeviceClusterResponse struct {
+	ClusterID string               `json:"cluster_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Users     []DeviceClusterUser  `json:"users"`
+	Links     []DeviceLinkResponse `json:"links"`
+	Truncated bool                 `json:"truncated"` // 群組過大，僅列出部分帳號
+}
+
+// SharedIdentifierResponse 被多個帳號共用的識別值
+type SharedIdentifierResponse struct {
+	Kind       string    `json:"kind" example:"device"`
+	Value      string    `json:"value" example:"t13d1516h2_8daaf6152771_e5627efa2ab1"`
+	Accounts   int64     `json:"accounts" example:"4"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2024-06-02T09:15:00+08:00"`
+}
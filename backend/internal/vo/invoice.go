@@ -0,0 +1,20 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceResponse 發票回應
+type InvoiceResponse struct {
+	ID            uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	InvoiceNumber string    `json:"invoice_number" example:"INV-2024-000001"`
+	Type          string    `json:"type" example:"invoice"`
+	OrderID       uuid.UUID `json:"order_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Subtotal      float64   `json:"subtotal" example:"4000"`
+	FeeAmount     float64   `json:"fee_amount" example:"40"`
+	TaxAmount     float64   `json:"tax_amount" example:"192.38"`
+	TotalAmount   float64   `json:"total_amount" example:"4040"`
+	IssuedAt      time.Time `json:"issued_at" example:"2024-06-01T10:30:00+08:00"`
+}
@@ -0,0 +1,12 @@
+package vo
+
+// MFASetupResponse 兩步驟驗證設定資訊，供驗證器 App 掃描
+type MFASetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Ticket-Getter:user@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Ticket-Getter"`
+}
+
+// MFARecoveryCodesResponse 復原碼，僅在產生時顯示一次
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7m2-9xqa,p3vd-w8ne"`
+}
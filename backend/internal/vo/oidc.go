@@ -0,0 +1,15 @@
+package vo
+
+import "time"
+
+// OIDCAuthorizationResponse 第三方登入授權連結
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=..."`
+}
+
+// UserIdentityResponse 已綁定的第三方登入身分
+type UserIdentityResponse struct {
+	Provider  string    `json:"provider" example:"google"`
+	Email     string    `json:"email" example:"user@gmail.com"`
+	CreatedAt time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+}
@@ -0,0 +1,45 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderResponse 訂單回應
+type OrderResponse struct {
+	ID            uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TotalAmount   float64   `json:"total_amount" example:"4000"`
+	Status        string    `json:"status" example:"paid"`
+	PaymentMethod string    `json:"payment_method" example:"credit_card"`
+	PaymentStatus string    `json:"payment_status" example:"paid"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+	UpdatedAt     time.Time `json:"updated_at" example:"2024-06-01T10:30:00+08:00"`
+}
+
+// OrderItemResponse 訂單項目回應
+type OrderItemResponse struct {
+	ID             uuid.UUID        `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TicketTypeID   uuid.UUID        `json:"ticket_type_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TicketTypeName string           `json:"ticket_type_name" example:"VIP票"`
+	EventID        uuid.UUID        `json:"event_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	EventTitle     string           `json:"event_title" example:"2024 台北音樂節"`
+	EventTime      time.Time        `json:"event_time" example:"2024-08-15T18:00:00+08:00"`
+	Quantity       int              `json:"quantity" example:"2"`
+	PricePerUnit   float64          `json:"price_per_unit" example:"2000"`
+	Tickets        []TicketResponse `json:"tickets"`
+}
+
+// OrderDetailResponse 訂單詳情回應
+type OrderDetailResponse struct {
+	OrderResponse
+	Items []OrderItemResponse `json:"items"`
+}
+
+// OrderListResponse 訂單列表回應
+type OrderListResponse struct {
+	Orders []OrderResponse `json:"orders"`
+	Total  int64           `json:"total" example:"42"`
+	Page   int             `json:"page" example:"1"`
+	Limit  int             `json:"limit" example:"10"`
+}
@@ -0,0 +1,14 @@
+package vo
+
+import "time"
+
+// PoWChallengeResponse 工作量證明挑戰回應
+//
+// 客戶端須找出計數器 n，使 SHA-256(challenge + ":" + n) 至少有 difficulty 個前導零位元，
+// 再以 `X-PoW-Solution: <challenge>:<n>` 重送請求；每個解答僅能使用一次。
+type PoWChallengeResponse struct {
+	Challenge  string    `json:"challenge" example:"YTNmOWExYzJlLjE2LjE3MTcyMzAwMDA.kP0v..."`
+	Algorithm  string    `json:"algorithm" example:"sha256"`
+	Difficulty int       `json:"difficulty" example:"16"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2024-06-01T10:32:00+08:00"`
+}
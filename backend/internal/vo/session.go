@@ -0,0 +1,17 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionResponse 登入工作階段回應
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.10"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2024-06-02T09:15:00+08:00"`
+	Current    bool      `json:"current" example:"true"`
+}
@@ -16,10 +16,27 @@ type TicketTypeResponse struct {
 	AvailableQuantity int      `json:"available_quantity" example:"75"`
 	SaleStart        time.Time `json:"sale_start" example:"2024-07-01T10:00:00+08:00"`
 	SaleEnd          time.Time `json:"sale_end" example:"2024-08-14T23:59:59+08:00"`
+	FingerprintPolicy FingerprintPolicyResponse `json:"fingerprint_policy"`
 	CreatedAt        time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
 	UpdatedAt        time.Time `json:"updated_at" example:"2024-06-01T10:30:00+08:00"`
 }
 
+// FingerprintPolicyResponse 票種的裝置指紋防重複購買政策
+type FingerprintPolicyResponse struct {
+	WindowMinutes int    `json:"window_minutes" example:"1440"`
+	MaxPurchases  int    `json:"max_purchases" example:"1"`
+	Scope         string `json:"scope" example:"ticket_type"`
+}
+
+// FingerprintCheckResponse 裝置指紋購買狀態
+type FingerprintCheckResponse struct {
+	AlreadyPurchased bool                      `json:"already_purchased" example:"false"`
+	Purchases        int                       `json:"purchases" example:"0"`
+	Remaining        int                       `json:"remaining" example:"1"` // 不限制時為 -1
+	Exempt           bool                      `json:"exempt" example:"false"`
+	Policy           FingerprintPolicyResponse `json:"policy"`
+}
+
 // TicketResponse 票券回應
 type TicketResponse struct {
 	ID           uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
@@ -50,3 +67,9 @@ type TicketAvailabilityResponse struct {
 type TicketFingerprintResponse struct {
 	AlreadyPurchased bool `json:"already_purchased" example:"false"`
 }
+
+// UserTicketsResponse 用戶票券回應，依活動是否結束分組
+type UserTicketsResponse struct {
+	Upcoming []TicketResponse `json:"upcoming"`
+	Past     []TicketResponse `json:"past"`
+}
@@ -0,0 +1,42 @@
+package vo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserDataExport 用戶個人資料匯出
+type UserDataExport struct {
+	ExportedAt     time.Time              `json:"exported_at" example:"2024-06-01T10:30:00+08:00"`
+	User           UserResponse           `json:"user"`
+	TLSFingerprint string                 `json:"tls_fingerprint,omitempty" example:"771,4865-4866-4867"`
+	CreatedAt      time.Time              `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+	UpdatedAt      time.Time              `json:"updated_at" example:"2024-06-01T10:30:00+08:00"`
+	Orders         []OrderDetailResponse  `json:"orders"`
+	Tickets        UserTicketsResponse    `json:"tickets"`
+	Invoices       []InvoiceResponse      `json:"invoices"`
+	Identities     []UserIdentityResponse `json:"identities"`
+	DeviceLinks    []DeviceLinkResponse   `json:"device_links"`
+	Sessions       []SessionExport        `json:"sessions"`
+	LoginAttempts  []LoginAttemptExport   `json:"login_attempts"`
+}
+
+// SessionExport 匯出的工作階段，包含已撤銷的工作階段
+type SessionExport struct {
+	ID             uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserAgent      string     `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	IPAddress      string     `json:"ip_address" example:"203.0.113.10"`
+	TLSFingerprint string     `json:"tls_fingerprint,omitempty" example:"771,4865-4866-4867"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+	LastSeenAt     time.Time  `json:"last_seen_at" example:"2024-06-02T09:15:00+08:00"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" example:"2024-06-03T08:00:00+08:00"`
+}
+
+// LoginAttemptExport 匯出的登入嘗試紀錄
+type LoginAttemptExport struct {
+	IPAddress string    `json:"ip_address" example:"203.0.113.10"`
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	Result    string    `json:"result" example:"success"`
+	CreatedAt time.Time `json:"created_at" example:"2024-06-01T10:30:00+08:00"`
+}
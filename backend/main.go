@@ -25,7 +25,7 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description 在 Authorization header 提供 Bearer token
+// @description 在 Authorization header 提供 Bearer token，系統整合亦可使用 `ApiKey <金鑰>`
 func main() {
 	// 載入環境變數
 	err := godotenv.Load()
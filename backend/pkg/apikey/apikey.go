@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// KeyPrefix API 金鑰的固定前綴，方便辨識與秘密掃描
+const KeyPrefix = "tg_"
+
+// displayLength 保存於資料庫、供列表辨識金鑰的前綴長度
+const displayLength = len(KeyPrefix) + 8
+
+// Generate 產生新的 API 金鑰，返回明文金鑰、顯示用前綴與雜湊；明文僅在建立時提供一次
+func Generate() (string, string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key := KeyPrefix + hex.EncodeToString(buf)
+	return key, key[:displayLength], Hash(key), nil
+}
+
+// Hash 計算 API 金鑰的 SHA-256 雜湊；金鑰本身具足夠熵，不需加鹽
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Resolver 依可信任的反向代理清單解析請求的真實客戶端 IP
+//
+// 只有直接連線的位址屬於可信任代理時才採用轉發標頭，優先順序為 Forwarded、X-Forwarded-For、X-Real-IP。
+// 轉發鏈由右至左檢查，略過可信任代理，第一個不受信任的位址即為客戶端，避免客戶端自行附加標頭偽造 IP。
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver 創建新的 Resolver 實例，proxies 可為 CIDR 或單一 IP；未設定時不信任任何轉發標頭
+func NewResolver(proxies []string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("無效的代理位址: %s", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("無效的代理位址: %s", proxy)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+	return resolver, nil
+}
+
+// Resolve 解析請求的真實客戶端 IP
+func (r *Resolver) Resolve(req *http.Request) string {
+	remote := parseIP(req.RemoteAddr)
+	if remote == nil {
+		return ""
+	}
+	if !r.Trusted(remote) {
+		return remote.String()
+	}
+
+	chain, ok := forwardedChain(req.Header.Values("Forwarded"))
+	if !ok {
+		chain, ok = forwardedForChain(req.Header.Values("X-Forwarded-For"))
+	}
+	if !ok {
+		if ip := parseIP(req.Header.Get("X-Real-IP")); ip != nil {
+			return ip.String()
+		}
+		return remote.String()
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseIP(chain[i])
+		if ip == nil {
+			// 可信任代理寫入了無法辨識的位址（如 unknown），以最近一個已知位址為準
+			break
+		}
+		client = ip
+		if !r.Trusted(ip) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// Trusted 檢查位址是否屬於可信任的反向代理
+func (r *Resolver) Trusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithIP 將解析後的客戶端 IP 存入 context
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ip)
+}
+
+// FromRequest 取得請求的客戶端 IP；未經 Resolver 解析時使用直接連線的位址，不採用任何轉發標頭
+func FromRequest(req *http.Request) string {
+	if ip, ok := req.Context().Value(contextKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	if ip := parseIP(req.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return req.RemoteAddr
+}
+
+// forwardedChain 解析 RFC 7239 Forwarded 標頭中的 for 參數
+func forwardedChain(values []string) ([]string, bool) {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					chain = append(chain, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return chain, len(chain) > 0
+}
+
+// forwardedForChain 解析 X-Forwarded-For 標頭，多個標頭依序串接
+func forwardedForChain(values []string) ([]string, bool) {
+	var chain []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(entry))
+		}
+	}
+	return chain, len(chain) > 0
+}
+
+// parseIP 解析 IP，接受 IPv6 方括號與埠號格式
+func parseIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.Trim(value, "[]"))
+}
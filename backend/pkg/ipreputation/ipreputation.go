@@ -0,0 +1,107 @@
+package ipreputation
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// entry 名單中的一筆網段與其來源名單
+type entry struct {
+	network *net.IPNet
+	source  string
+}
+
+// List IP 信譽名單，由本地檔案載入（如代理、資料中心、已知黃牛 IP）
+type List struct {
+	entries []entry
+}
+
+// Load 載入名單檔案，每行一個 IP 或 CIDR，# 之後為註解；來源名稱為檔名（不含副檔名）
+func Load(paths ...string) (*List, error) {
+	list := &List{}
+	for _, path := range paths {
+		if err := list.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (l *List) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	source := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		network, err := parseNetwork(fields[0])
+		if err != nil {
+			return fmt.Errorf("%s 第 %d 行: %w", path, lineNumber, err)
+		}
+		l.entries = append(l.entries, entry{network: network, source: source})
+	}
+	return scanner.Err()
+}
+
+// Lookup 查詢 IP 是否在名單中，返回所屬名單名稱
+func (l *List) Lookup(ip string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", false
+	}
+	for _, e := range l.entries {
+		if e.network.Contains(parsed) {
+			return e.source, true
+		}
+	}
+	return "", false
+}
+
+// Len 名單中的網段數量
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.entries)
+}
+
+// parseNetwork 解析 CIDR，單一 IP 視為 /32 或 /128
+func parseNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("無效的網段: %s", value)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("無效的 IP: %s", value)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
@@ -0,0 +1,242 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits RSA 金鑰最小長度
+const minRSAKeyBits = 2048
+
+// Key 單一簽章金鑰；privateKey 為 nil 時僅供驗證（已退役但仍在有效期內的令牌）
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// KeySet 以 kid 區分的金鑰集合，使用啟用中的金鑰簽章，並以所有金鑰驗證以支援輪替
+type KeySet struct {
+	activeID string
+	keys     map[string]*Key
+}
+
+// JWK JSON Web Key 公鑰
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKey 以 RSA（RS256）或 Ed25519（EdDSA）金鑰建立簽章金鑰，傳入公鑰時僅供驗證
+func NewKey(id string, key interface{}) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("金鑰 ID 不可為空")
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA 金鑰長度不足 %d 位元: %s", minRSAKeyBits, id)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, privateKey: k, publicKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA 金鑰長度不足 %d 位元: %s", minRSAKeyBits, id)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, publicKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, privateKey: k, publicKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, publicKey: k}, nil
+	default:
+		return nil, fmt.Errorf("不支援的金鑰類型: %T", key)
+	}
+}
+
+// GenerateEd25519 產生新的 Ed25519 簽章金鑰
+func GenerateEd25519(id string) (*Key, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(id, privateKey)
+}
+
+// NewKeySet 建立金鑰集合，activeID 指定用於簽章的金鑰
+func NewKeySet(activeID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{
+		activeID: activeID,
+		keys:     make(map[string]*Key, len(keys)),
+	}
+	for _, key := range keys {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("重複的金鑰 ID: %s", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("找不到啟用中的金鑰: %s", activeID)
+	}
+	if active.privateKey == nil {
+		return nil, fmt.Errorf("啟用中的金鑰缺少私鑰: %s", activeID)
+	}
+
+	return set, nil
+}
+
+// LoadDir 從目錄載入 PEM 金鑰，檔名（去除 .pem）即為 kid；
+// 私鑰可用於簽章，僅有公鑰的檔案用於驗證已退役金鑰簽發的令牌。
+// activeID 為空且目錄中只有一把私鑰時，以該金鑰簽章。
+func LoadDir(dir string, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var keys []*Key
+	var privateIDs []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("讀取金鑰失敗: %w", err)
+		}
+		parsed, err := parsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析金鑰 %s 失敗: %w", filepath.Base(path), err)
+		}
+		key, err := NewKey(strings.TrimSuffix(filepath.Base(path), ".pem"), parsed)
+		if err != nil {
+			return nil, err
+		}
+		if key.privateKey != nil {
+			privateIDs = append(privateIDs, key.ID)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("目錄中沒有任何金鑰: %s", dir)
+	}
+	if activeID == "" {
+		if len(privateIDs) != 1 {
+			return nil, errors.New("有多把私鑰時必須指定啟用中的金鑰")
+		}
+		activeID = privateIDs[0]
+	}
+
+	return NewKeySet(activeID, keys...)
+}
+
+// ActiveKeyID 返回目前用於簽章的 kid
+func (s *KeySet) ActiveKeyID() string {
+	return s.activeID
+}
+
+// Sign 以啟用中的金鑰簽署令牌，並在標頭加入 kid
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	active := s.keys[s.activeID]
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.ID
+	return token.SignedString(active.privateKey)
+}
+
+// Parse 依 kid 選擇公鑰驗證令牌；演算法必須與該金鑰一致，避免演算法混淆攻擊
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	options = append(options, jwt.WithValidMethods([]string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
+
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("未知的金鑰 ID: %s", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("無效的簽名方法: %v", token.Header["alg"])
+		}
+		return key.publicKey, nil
+	}, options...)
+}
+
+// JWKS 返回所有公鑰，供其他服務獨立驗證令牌
+func (s *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := s.keys[id]
+		jwk := JWK{
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Kid: key.ID,
+		}
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// parsePEM 解析 PKCS#8、PKCS#1 私鑰或 PKIX、PKCS#1 公鑰
+func parsePEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("無效的 PEM 格式")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("不支援的 PEM 類型: %s", block.Type)
+	}
+}
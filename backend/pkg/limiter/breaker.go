@@ -0,0 +1,153 @@
+package limiter
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// 斷路器狀態
+const (
+	CircuitClosed   = "closed"    // 使用主要限制器
+	CircuitOpen     = "open"      // 主要限制器故障，使用備援
+	CircuitHalfOpen = "half_open" // 冷卻時間已過，以單一請求探測主要限制器是否恢復
+)
+
+// Breaker 斷路器；連續失敗達門檻後開路，冷卻時間後以單一請求探測，成功即恢復
+type Breaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+}
+
+// NewBreaker 創建新的 Breaker 實例
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		state:     CircuitClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow 是否應嘗試主要限制器；半開時只允許一個探測請求
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		return true
+	default:
+		// 已有探測請求進行中
+		return false
+	}
+}
+
+// Success 記錄主要限制器成功
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state != CircuitClosed {
+		b.setState(CircuitClosed)
+	}
+}
+
+// Failure 記錄主要限制器失敗
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// State 目前狀態
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) setState(state string) {
+	log.Printf("限流斷路器狀態變更: %s -> %s", b.state, state)
+	b.state = state
+}
+
+// FallbackLimiter 以斷路器在主要限制器（Redis）與備援限制器（程序內）之間切換
+//
+// 主要限制器出錯的請求立即改用備援計數，不會放行；斷路器開路期間不再嘗試主要限制器。
+type FallbackLimiter struct {
+	Primary  Limiter
+	Fallback Limiter
+	Breaker  *Breaker
+}
+
+// NewFallbackLimiter 創建新的 FallbackLimiter 實例
+func NewFallbackLimiter(primary Limiter, fallback Limiter, breaker *Breaker) *FallbackLimiter {
+	return &FallbackLimiter{
+		Primary:  primary,
+		Fallback: fallback,
+		Breaker:  breaker,
+	}
+}
+
+// Allow 檢查是否允許請求通過；只有主要限制器本身的錯誤（Redis 或連線錯誤）計入斷路器失敗次數
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	// 設定錯誤與 Redis 是否正常無關，先檢查以免開路或佔用半開時的探測請求
+	if err := validate(limit, window); err != nil {
+		return false, 0, 0, err
+	}
+
+	if l.Breaker.Allow() {
+		allowed, remaining, retryAfter, err := l.Primary.Allow(ctx, key, limit, window)
+		if err == nil {
+			l.Breaker.Success()
+			return allowed, remaining, retryAfter, nil
+		}
+		l.Breaker.Failure()
+	}
+	return l.Fallback.Allow(ctx, key, limit, window)
+}
+
+// Remaining 查詢剩餘可用數量；斷路器開路或主要限制器出錯時查詢備援限制器
+func (l *FallbackLimiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	if err := validate(limit, window); err != nil {
+		return 0, err
+	}
+
+	if l.Breaker.Allow() {
+		remaining, err := l.Primary.Remaining(ctx, key, limit, window)
+		if err == nil {
+			l.Breaker.Success()
+			return remaining, nil
+		}
+		l.Breaker.Failure()
+	}
+	return l.Fallback.Remaining(ctx, key, limit, window)
+}
+
+// Reset 重置主要與備援限制器中的鍵
+func (l *FallbackLimiter) Reset(ctx context.Context, key string) error {
+	if err := l.Fallback.Reset(ctx, key); err != nil {
+		return err
+	}
+	return l.Primary.Reset(ctx, key)
+}
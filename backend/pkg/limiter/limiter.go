@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 限流演算法
+const (
+	StrategyFixedWindow   = "fixed_window"
+	StrategySlidingWindow = "sliding_window"
+	StrategyTokenBucket   = "token_bucket"
+)
+
+// Limiter 頻率限制器的共同介面
+//
+// Allow 返回是否允許、剩餘可用數量，以及被拒絕時須等待的時間（允許時為窗口或桶的重置時間）。
+// Remaining 以相同的 limit 與 window 查詢剩餘可用數量，不計數。
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error)
+	Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// New 依演算法名稱創建限制器，空字串視為固定窗口
+func New(strategy string, redisClient *redis.Client, prefix string) (Limiter, error) {
+	switch strategy {
+	case "", StrategyFixedWindow:
+		return NewRateLimiter(redisClient, prefix), nil
+	case StrategySlidingWindow:
+		return NewSlidingWindowLimiter(redisClient, prefix), nil
+	case StrategyTokenBucket:
+		return NewTokenBucketLimiter(redisClient, prefix), nil
+	default:
+		return nil, fmt.Errorf("不支援的限流演算法: %s", strategy)
+	}
+}
+
+// prefixedLimiter 為所有鍵加上前綴，讓多個用途共用同一個限制器而不互相計數
+type prefixedLimiter struct {
+	limiter Limiter
+	prefix  string
+}
+
+// WithPrefix 返回為所有鍵加上 prefix 的限制器
+func WithPrefix(l Limiter, prefix string) Limiter {
+	return &prefixedLimiter{limiter: l, prefix: prefix}
+}
+
+// Allow 檢查是否允許請求通過
+func (l *prefixedLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	return l.limiter.Allow(ctx, l.prefix+":"+key, limit, window)
+}
+
+// Remaining 查詢剩餘可用數量
+func (l *prefixedLimiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	return l.limiter.Remaining(ctx, l.prefix+":"+key, limit, window)
+}
+
+// Reset 重置特定鍵的限制
+func (l *prefixedLimiter) Reset(ctx context.Context, key string) error {
+	return l.limiter.Reset(ctx, l.prefix+":"+key)
+}
+
+// validate 檢查限制數與窗口；滑動窗口與令牌桶須以兩者計算速率
+func validate(limit int, window time.Duration) error {
+	if limit <= 0 || window < time.Millisecond {
+		return fmt.Errorf("無效的限流設定: limit=%d window=%s", limit, window)
+	}
+	return nil
+}
+
+// runScript 執行限流腳本並轉換 {是否允許, 剩餘數量, 毫秒數} 的回傳值
+func runScript(ctx context.Context, redisClient *redis.Client, script *redis.Script, key string, args ...interface{}) (bool, int, time.Duration, error) {
+	result, err := script.Run(ctx, redisClient, []string{key}, args...).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if len(result) != 3 {
+		return false, 0, 0, fmt.Errorf("限流腳本回傳格式錯誤: %v", result)
+	}
+	return result[0] == 1, int(result[1]), time.Duration(result[2]) * time.Millisecond, nil
+}
@@ -0,0 +1,141 @@
+package limiter
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// 分片數，降低高併發時的鎖競爭
+	memoryShardCount = 64
+
+	// 每個分片清除過期項目的間隔
+	memorySweepInterval = time.Minute
+)
+
+// MemoryLimiter 程序內的令牌桶限制器，Redis 無法使用時的備援
+//
+// 計數只存在於單一程序，多台服務器時每台各自計算；instances 為服務器數量時，
+// 每台的限制數為 limit/instances（至少 1），使整體限制接近原本的設定。
+type MemoryLimiter struct {
+	shards    [memoryShardCount]memoryShard
+	instances int
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time // 桶補滿的時間，之後與不存在相同
+}
+
+// NewMemoryLimiter 創建新的 MemoryLimiter 實例
+func NewMemoryLimiter(instances int) *MemoryLimiter {
+	if instances < 1 {
+		instances = 1
+	}
+	l := &MemoryLimiter{instances: instances}
+	for i := range l.shards {
+		l.shards[i].buckets = make(map[string]*memoryBucket)
+	}
+	return l
+}
+
+// Allow 檢查是否允許請求通過
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	if err := validate(limit, window); err != nil {
+		return false, 0, 0, err
+	}
+
+	capacity := math.Max(1, math.Floor(float64(limit)/float64(l.instances)))
+	rate := capacity / float64(window)
+	now := time.Now()
+
+	shard := l.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) > memorySweepInterval {
+		for k, bucket := range shard.buckets {
+			if now.After(bucket.expiresAt) {
+				delete(shard.buckets, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	bucket, ok := shard.buckets[key]
+	if !ok || now.After(bucket.expiresAt) {
+		bucket = &memoryBucket{tokens: capacity, updatedAt: now}
+		shard.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.updatedAt))*rate)
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	var wait time.Duration
+	if allowed {
+		bucket.tokens--
+		wait = time.Duration(math.Ceil((capacity - bucket.tokens) / rate))
+	} else {
+		wait = time.Duration(math.Ceil((1 - bucket.tokens) / rate))
+	}
+	bucket.expiresAt = now.Add(time.Duration(math.Ceil((capacity - bucket.tokens) / rate)))
+
+	return allowed, int(bucket.tokens), wait, nil
+}
+
+// Remaining 查詢桶內目前的令牌數，不消耗令牌
+func (l *MemoryLimiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	if err := validate(limit, window); err != nil {
+		return 0, err
+	}
+
+	capacity := math.Max(1, math.Floor(float64(limit)/float64(l.instances)))
+	rate := capacity / float64(window)
+	now := time.Now()
+
+	shard := l.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	bucket, ok := shard.buckets[key]
+	if !ok || now.After(bucket.expiresAt) {
+		return int(capacity), nil
+	}
+	return int(math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.updatedAt))*rate)), nil
+}
+
+// Reset 重置特定鍵的限制
+func (l *MemoryLimiter) Reset(ctx context.Context, key string) error {
+	shard := l.shard(key)
+	shard.mu.Lock()
+	delete(shard.buckets, key)
+	shard.mu.Unlock()
+	return nil
+}
+
+// Len 目前保存的鍵數，包含尚未清除的過期項目
+func (l *MemoryLimiter) Len() int {
+	total := 0
+	for i := range l.shards {
+		l.shards[i].mu.Lock()
+		total += len(l.shards[i].buckets)
+		l.shards[i].mu.Unlock()
+	}
+	return total
+}
+
+func (l *MemoryLimiter) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &l.shards[h.Sum32()%memoryShardCount]
+}
@@ -0,0 +1,158 @@
+package limiter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 限流政策的計數對象
+const (
+	KeyIP          = "ip"
+	KeyUser        = "user"
+	KeyAPIKey      = "api_key"
+	KeyFingerprint = "fingerprint"
+	KeyCluster     = "cluster" // 共用裝置的帳號群組，無法取得時以用戶計算
+)
+
+// Policy 一條限流規則；符合路由、方法與角色的請求以 Key 指定的對象計數
+type Policy struct {
+	Name     string   `json:"name" yaml:"name"`
+	Routes   []string `json:"routes" yaml:"routes"`       // gin 路由樣板，支援 path.Match 萬用字元，結尾 /** 表示前綴，空白表示全部
+	Methods  []string `json:"methods" yaml:"methods"`     // 空白表示全部
+	Roles    []string `json:"roles" yaml:"roles"`         // 僅套用於已登入且為這些角色的請求，空白表示不限
+	Key      string   `json:"key" yaml:"key"`             // ip、user、api_key、fingerprint 或 cluster
+	PerRoute bool     `json:"per_route" yaml:"per_route"` // 每個路由分別計數
+	Strategy string   `json:"strategy" yaml:"strategy"`   // fixed_window、sliding_window 或 token_bucket，空白為固定窗口
+	Limit    int      `json:"limit" yaml:"limit"`
+	Window   string   `json:"window" yaml:"window"` // 如 60s、1m
+	DryRun   bool     `json:"dry_run" yaml:"dry_run"`
+	Message  string   `json:"message" yaml:"message"` // 拒絕時的錯誤訊息
+
+	window time.Duration
+}
+
+// PolicyFile 限流政策檔
+type PolicyFile struct {
+	DryRun   bool     `json:"dry_run" yaml:"dry_run"` // 所有政策只記錄不拒絕
+	Policies []Policy `json:"policies" yaml:"policies"`
+}
+
+// ParsePolicyFile 解析政策檔，副檔名為 .json 時以 JSON 解析，否則以 YAML 解析；不允許未知欄位
+func ParsePolicyFile(data []byte, filename string) (*PolicyFile, error) {
+	var file PolicyFile
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("解析限流政策失敗: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("解析限流政策失敗: %w", err)
+		}
+	}
+
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Validate 檢查並正規化所有政策
+func (f *PolicyFile) Validate() error {
+	names := make(map[string]bool, len(f.Policies))
+	for i := range f.Policies {
+		p := &f.Policies[i]
+		if p.Name == "" {
+			return fmt.Errorf("第 %d 條限流政策缺少名稱", i+1)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("限流政策名稱重複: %s", p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.Key {
+		case KeyIP, KeyUser, KeyAPIKey, KeyFingerprint, KeyCluster:
+		default:
+			return fmt.Errorf("限流政策 %s 的計數對象無效: %q", p.Name, p.Key)
+		}
+		switch p.Strategy {
+		case "", StrategyFixedWindow, StrategySlidingWindow, StrategyTokenBucket:
+		default:
+			return fmt.Errorf("限流政策 %s 的演算法無效: %q", p.Name, p.Strategy)
+		}
+		if p.Limit <= 0 {
+			return fmt.Errorf("限流政策 %s 的限制數須大於 0", p.Name)
+		}
+
+		window, err := time.ParseDuration(p.Window)
+		if err != nil || window < time.Millisecond {
+			return fmt.Errorf("限流政策 %s 的窗口無效: %q", p.Name, p.Window)
+		}
+		p.window = window
+
+		for j, method := range p.Methods {
+			p.Methods[j] = strings.ToUpper(method)
+		}
+		for _, route := range p.Routes {
+			if _, err := path.Match(route, "/"); err != nil {
+				return fmt.Errorf("限流政策 %s 的路由樣板無效: %q", p.Name, route)
+			}
+		}
+	}
+	return nil
+}
+
+// WindowDuration 政策的時間窗口，須先經 Validate 解析
+func (p *Policy) WindowDuration() time.Duration {
+	return p.window
+}
+
+// Matches 請求的路由、方法與角色是否符合政策；role 為空表示未登入
+func (p *Policy) Matches(route string, method string, role string) bool {
+	if len(p.Methods) > 0 && !contains(p.Methods, method) {
+		return false
+	}
+	if len(p.Roles) > 0 && (role == "" || !contains(p.Roles, role)) {
+		return false
+	}
+	if len(p.Routes) == 0 {
+		return true
+	}
+	for _, pattern := range p.Routes {
+		if matchRoute(pattern, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute 比對路由樣板；* 符合所有路由，結尾 /** 符合該前綴下的所有路由
+func matchRoute(pattern string, route string) bool {
+	if pattern == "*" || pattern == route {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+	matched, _ := path.Match(pattern, route)
+	return matched
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
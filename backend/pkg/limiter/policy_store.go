@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PolicyStore 保存目前生效的限流政策，並在政策檔變更時重新載入
+//
+// 重新載入失敗時保留原本的政策，避免錯誤的設定使限流失效。
+type PolicyStore struct {
+	path     string
+	current  atomic.Pointer[PolicyFile]
+	mu       sync.Mutex
+	modTime  time.Time
+	fileSize int64
+}
+
+// NewPolicyStore 創建新的 PolicyStore 實例；path 為空時只使用 defaults，否則啟動時即須能載入政策檔
+func NewPolicyStore(path string, defaults *PolicyFile) (*PolicyStore, error) {
+	if err := defaults.Validate(); err != nil {
+		return nil, err
+	}
+
+	store := &PolicyStore{path: path}
+	store.current.Store(defaults)
+	if path != "" {
+		if _, err := store.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Current 目前生效的政策
+func (s *PolicyStore) Current() *PolicyFile {
+	return s.current.Load()
+}
+
+// Path 政策檔路徑，未設定時為空
+func (s *PolicyStore) Path() string {
+	return s.path
+}
+
+// Reload 政策檔的修改時間或大小改變時重新載入，返回是否已套用新政策
+func (s *PolicyStore) Reload() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.fileSize {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, err
+	}
+	file, err := ParsePolicyFile(data, s.path)
+	if err != nil {
+		return false, err
+	}
+
+	s.current.Store(file)
+	s.modTime = info.ModTime()
+	s.fileSize = info.Size()
+	return true, nil
+}
+
+// Watch 每隔 interval 檢查政策檔，直到 ctx 結束
+func (s *PolicyStore) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.Reload()
+			if err != nil {
+				log.Printf("重新載入限流政策失敗，沿用目前的政策: %v", err)
+				continue
+			}
+			if reloaded {
+				log.Printf("已重新載入限流政策 %s，共 %d 條", s.path, len(s.Current().Policies))
+			}
+		}
+	}
+}
@@ -8,7 +8,34 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// RateLimiter Redis 實現的頻率限制器
+// fixedWindowScript 固定窗口計數；未超過限制時遞增並於首次請求設定過期時間
+// KEYS[1]: 計數 key；ARGV[1]: 限制數；ARGV[2]: 窗口毫秒數
+// 返回 {是否允許, 剩餘數量, 窗口剩餘毫秒數}
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count >= limit then
+	local ttl = redis.call("PTTL", KEYS[1])
+	if ttl < 0 then
+		redis.call("PEXPIRE", KEYS[1], window)
+		ttl = window
+	end
+	return {0, 0, ttl}
+end
+count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], window)
+	ttl = window
+end
+return {1, limit - count, ttl}
+`)
+
+// RateLimiter Redis 實現的固定窗口頻率限制器
+//
+// 計算簡單且計數可直接以 GET 讀取，但窗口交界處最多可通過兩倍的請求；
+// 需要平滑限制時改用 SlidingWindowLimiter 或 TokenBucketLimiter。
 type RateLimiter struct {
 	redisClient *redis.Client
 	prefix      string
@@ -22,58 +49,31 @@ func NewRateLimiter(redisClient *redis.Client, prefix string) *RateLimiter {
 	}
 }
 
-// Allow 檢查是否允許請求通過
+// Allow 檢查是否允許請求通過，計數與過期時間在同一個 Lua 腳本中更新
 // key: 唯一標識符（通常為 IP 或 用戶 ID）
 // limit: 在時間窗口內允許的最大請求數
 // window: 時間窗口（例如 1 分鐘）
 func (l *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
-	// 建立 Redis key
 	redisKey := fmt.Sprintf("%s:%s", l.prefix, key)
-	
-	// 獲取目前計數
-	count, err := l.redisClient.Get(ctx, redisKey).Int()
-	
-	// 如果 key 不存在，初始化為 1 並設置過期時間
-	if err == redis.Nil {
-		_, err = l.redisClient.Set(ctx, redisKey, 1, window).Result()
-		if err != nil {
-			return false, 0, 0, err
-		}
-		return true, limit - 1, window, nil
-	} else if err != nil {
-		return false, 0, 0, err
-	}
-	
-	// 如果計數超過限制，拒絕請求
-	if count >= limit {
-		// 獲取剩餘過期時間
-		ttl, err := l.redisClient.TTL(ctx, redisKey).Result()
-		if err != nil {
-			return false, 0, 0, err
-		}
-		return false, 0, ttl, nil
+	return runScript(ctx, l.redisClient, fixedWindowScript, redisKey, limit, window.Milliseconds())
+}
+
+// Remaining 查詢目前窗口的剩餘可用數量
+func (l *RateLimiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	if err := validate(limit, window); err != nil {
+		return 0, err
 	}
-	
-	// 增加計數
-	_, err = l.redisClient.Incr(ctx, redisKey).Result()
-	if err != nil {
-		return false, 0, 0, err
+	count, err := l.redisClient.Get(ctx, fmt.Sprintf("%s:%s", l.prefix, key)).Int()
+	if err == redis.Nil {
+		return limit, nil
 	}
-	
-	// 獲取剩餘過期時間
-	ttl, err := l.redisClient.TTL(ctx, redisKey).Result()
 	if err != nil {
-		return false, 0, 0, err
+		return 0, err
 	}
-	
-	// 如果 TTL 為負數，重新設置過期時間
-	if ttl < 0 {
-		l.redisClient.Expire(ctx, redisKey, window)
-		ttl = window
+	if count >= limit {
+		return 0, nil
 	}
-	
-	// 返回允許通過、剩餘可用數量和 TTL
-	return true, limit - count - 1, ttl, nil
+	return limit - count, nil
 }
 
 // Reset 重置特定鍵的限制
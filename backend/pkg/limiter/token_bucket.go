@@ -0,0 +1,97 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript 令牌桶；桶容量為 limit，每 window 補滿一次，以 Redis 伺服器時間（毫秒）計算補充量
+// KEYS[1]: 桶 key；ARGV[1]: 容量；ARGV[2]: 補滿所需毫秒數
+// 返回 {是否允許, 剩餘令牌數, 毫秒數}
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local rate = capacity / window
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+	wait = math.ceil((capacity - tokens) / rate)
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.max(1, math.ceil((capacity - tokens) / rate)))
+return {allowed, math.floor(tokens), wait}
+`)
+
+// tokenBucketPeekScript 計算目前的令牌數，不消耗令牌也不更新桶
+// KEYS[1]: 桶 key；ARGV[1]: 容量；ARGV[2]: 補滿所需毫秒數
+var tokenBucketPeekScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	return capacity
+end
+return math.floor(math.min(capacity, tokens + math.max(0, now - ts) * capacity / window))
+`)
+
+// TokenBucketLimiter 令牌桶限制器，允許最多 limit 次的突發請求，之後以 limit/window 的速率恢復
+//
+// 每個鍵只保存令牌數與更新時間，適合限制數較大的一般 API。
+type TokenBucketLimiter struct {
+	redisClient *redis.Client
+	prefix      string
+}
+
+// NewTokenBucketLimiter 創建新的 TokenBucketLimiter 實例
+func NewTokenBucketLimiter(redisClient *redis.Client, prefix string) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		redisClient: redisClient,
+		prefix:      prefix,
+	}
+}
+
+// Allow 檢查是否允許請求通過
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	if err := validate(limit, window); err != nil {
+		return false, 0, 0, err
+	}
+	return runScript(ctx, l.redisClient, tokenBucketScript, l.key(key), limit, window.Milliseconds())
+}
+
+// Remaining 查詢桶內目前的令牌數
+func (l *TokenBucketLimiter) Remaining(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	if err := validate(limit, window); err != nil {
+		return 0, err
+	}
+	return tokenBucketPeekScript.Run(ctx, l.redisClient, []string{l.key(key)}, limit, window.Milliseconds()).Int()
+}
+
+// Reset 重置特定鍵的限制
+func (l *TokenBucketLimiter) Reset(ctx context.Context, key string) error {
+	return l.redisClient.Del(ctx, l.key(key)).Err()
+}
+
+func (l *TokenBucketLimiter) key(key string) string {
+	return fmt.Sprintf("%s:bucket:%s", l.prefix, key)
+}
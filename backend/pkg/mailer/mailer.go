@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"log"
+)
+
+// Message 電子郵件內容
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 純文字內容
+}
+
+// Mailer 寄送電子郵件的介面
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer 將郵件內容輸出到日誌，供開發環境使用
+type LogMailer struct{}
+
+// NewLogMailer 創建新的 LogMailer 實例
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send 將郵件輸出到日誌
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("寄送郵件至 %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
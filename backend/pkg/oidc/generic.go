@@ -0,0 +1,275 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderConfig 標準 OIDC 身分提供者設定
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// discoveryDocument /.well-known/openid-configuration 中使用到的欄位
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims ID Token 聲明
+type idTokenClaims struct {
+	Nonce           string      `json:"nonce"`
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"` // 部分提供者以字串表示
+	Name            string      `json:"name"`
+	AuthorizedParty string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// GenericProvider 透過 Discovery 文件設定的標準 OIDC 依賴方（授權碼流程 + PKCE）
+type GenericProvider struct {
+	config     ProviderConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      *keySet
+}
+
+// NewGenericProvider 創建標準 OIDC 身分提供者；httpClient 為 nil 時使用預設逾時設定
+func NewGenericProvider(config ProviderConfig, httpClient *http.Client) *GenericProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &GenericProvider{
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// NewGoogleProvider 創建 Google 登入提供者
+func NewGoogleProvider(clientID, clientSecret, redirectURL string) *GenericProvider {
+	return NewGenericProvider(ProviderConfig{
+		Name:         "google",
+		Issuer:       "https://accounts.google.com",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}, nil)
+}
+
+// NewLINEProvider 創建 LINE 登入提供者；取得電子郵件需在 LINE Developers 申請權限
+func NewLINEProvider(clientID, clientSecret, redirectURL string) *GenericProvider {
+	return NewGenericProvider(ProviderConfig{
+		Name:         "line",
+		Issuer:       "https://access.line.me",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}, nil)
+}
+
+// Name 提供者名稱
+func (p *GenericProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL 產生授權碼流程的登入連結
+func (p *GenericProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Authenticate 以授權碼交換令牌並驗證 ID Token
+func (p *GenericProvider) Authenticate(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := p.exchange(ctx, doc, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, doc, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("ID Token nonce 不符")
+	}
+
+	return &Identity{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// exchange 以授權碼與 PKCE 驗證碼向令牌端點交換 ID Token
+func (p *GenericProvider) exchange(ctx context.Context, doc *discoveryDocument, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("client_secret", p.config.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("解析令牌回應失敗: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("交換授權碼失敗: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("令牌回應缺少 ID Token")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken 驗證 ID Token 的簽章、簽發者、受眾與有效期限
+func (p *GenericProvider) verifyIDToken(ctx context.Context, doc *discoveryDocument, rawIDToken string) (*idTokenClaims, error) {
+	methods := []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+	if p.config.ClientSecret != "" {
+		// OIDC Core 10.1：HS 系列以 client secret 簽章（LINE 網頁登入即採用此方式）
+		methods = append(methods, "HS256")
+	}
+
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(p.config.ClientSecret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.keySet().key(ctx, kid)
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("無效的 ID Token: %w", err)
+	}
+
+	// 多個受眾時，azp 必須是本應用
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, errors.New("無效的 ID Token: azp 不符")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("無效的 ID Token: 缺少 sub")
+	}
+
+	return claims, nil
+}
+
+// discover 讀取並快取 Discovery 文件
+func (p *GenericProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("讀取 OIDC 設定失敗: 狀態碼 %d", resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析 OIDC 設定失敗: %w", err)
+	}
+	// 防止 Discovery 文件被替換成其他簽發者
+	if doc.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("OIDC 簽發者不符: %s", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("OIDC 設定缺少必要端點")
+	}
+
+	p.discovery = &doc
+	p.keys = newKeySet(doc.JWKSURI, p.httpClient)
+	return p.discovery, nil
+}
+
+// keySet 返回 Discovery 文件對應的 JWKS 快取
+func (p *GenericProvider) keySet() *keySet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys
+}
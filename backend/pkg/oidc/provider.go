@@ -0,0 +1,44 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Identity 身分提供者驗證後的用戶身分
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider 第三方登入身分提供者
+type Provider interface {
+	// Name 提供者名稱，例如 google、line
+	Name() string
+
+	// AuthCodeURL 產生授權碼流程的登入連結，codeChallenge 為 PKCE S256 挑戰值
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+
+	// Authenticate 以授權碼交換令牌並驗證 ID Token，返回用戶身分
+	Authenticate(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// RandomString 產生 URL 安全的隨機字串，用於 state、nonce 與 PKCE 驗證碼
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge 計算 PKCE S256 挑戰值
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
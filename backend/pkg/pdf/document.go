@@ -0,0 +1,70 @@
+package pdf
+
+import (
+	"bytes"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	// 內建字型，僅支援 Latin-1 字元
+	coreFontFamily = "Helvetica"
+
+	// 自訂 UTF-8 字型名稱
+	customFontFamily = "custom"
+)
+
+// Document 封裝 gofpdf，統一處理字型載入與輸出
+type Document struct {
+	*gofpdf.Fpdf
+	fontFamily string
+	translate  func(string) string
+}
+
+// NewDocument 創建 A4 直式 PDF 文件
+// fontPath 指向 TTF 字型時使用該字型以支援中文，否則退回內建 Helvetica
+func NewDocument(fontPath string) *Document {
+	f := gofpdf.New("P", "mm", "A4", "")
+	f.SetMargins(15, 15, 15)
+	f.SetAutoPageBreak(true, 15)
+
+	doc := &Document{
+		Fpdf:       f,
+		fontFamily: coreFontFamily,
+		translate:  f.UnicodeTranslatorFromDescriptor(""),
+	}
+
+	if fontPath != "" {
+		// 同一字型檔同時註冊一般與粗體樣式，避免切換樣式時找不到字型
+		f.AddUTF8Font(customFontFamily, "", fontPath)
+		f.AddUTF8Font(customFontFamily, "B", fontPath)
+		if f.Ok() {
+			doc.fontFamily = customFontFamily
+			doc.translate = func(s string) string { return s }
+		} else {
+			// 字型載入失敗時退回內建字型
+			f.ClearError()
+		}
+	}
+
+	return doc
+}
+
+// Font 設置字型樣式與大小
+func (d *Document) Font(style string, size float64) {
+	d.SetFont(d.fontFamily, style, size)
+}
+
+// Encode 將字串轉換為目前字型可輸出的編碼
+func (d *Document) Encode(s string) string {
+	return d.translate(s)
+}
+
+// Bytes 輸出 PDF 內容
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"fmt"
+
+	"github.com/lipeichen/ticket-getter/internal/models"
+)
+
+// Issuer 發票開立方資訊
+type Issuer struct {
+	Name    string
+	TaxID   string
+	Address string
+}
+
+// RenderInvoice 將發票或折讓單繪製為 PDF
+// original 為折讓單對應的原發票，一般發票傳入 nil
+func RenderInvoice(fontPath string, issuer Issuer, invoice *models.Invoice, original *models.Invoice) ([]byte, error) {
+	doc := NewDocument(fontPath)
+	doc.SetTitle(invoice.InvoiceNumber, true)
+	doc.AddPage()
+
+	title := "INVOICE / RECEIPT"
+	if invoice.Type == models.InvoiceTypeCreditNote {
+		title = "CREDIT NOTE"
+	}
+
+	// 開立方資訊
+	doc.Font("B", 16)
+	doc.CellFormat(110, 8, doc.Encode(issuer.Name), "", 0, "L", false, 0, "")
+	doc.CellFormat(0, 8, title, "", 1, "R", false, 0, "")
+	doc.Font("", 9)
+	if issuer.Address != "" {
+		doc.CellFormat(0, 5, doc.Encode(issuer.Address), "", 1, "L", false, 0, "")
+	}
+	if issuer.TaxID != "" {
+		doc.CellFormat(0, 5, "Tax ID: "+issuer.TaxID, "", 1, "L", false, 0, "")
+	}
+	doc.Ln(6)
+
+	// 發票基本資訊
+	meta := [][2]string{
+		{"Number", invoice.InvoiceNumber},
+		{"Issued", invoice.IssuedAt.Format("2006-01-02 15:04")},
+		{"Order", invoice.OrderID.String()},
+	}
+	if original != nil {
+		meta = append(meta, [2]string{"Original invoice", original.InvoiceNumber})
+	}
+	for _, row := range meta {
+		doc.Font("B", 10)
+		doc.CellFormat(35, 6, row[0], "", 0, "L", false, 0, "")
+		doc.Font("", 10)
+		doc.CellFormat(0, 6, row[1], "", 1, "L", false, 0, "")
+	}
+	doc.Ln(4)
+
+	// 買受人資訊
+	doc.Font("B", 10)
+	doc.CellFormat(0, 6, "Bill to", "", 1, "L", false, 0, "")
+	doc.Font("", 10)
+	doc.CellFormat(0, 6, doc.Encode(invoice.BuyerName), "", 1, "L", false, 0, "")
+	doc.CellFormat(0, 6, invoice.BuyerEmail, "", 1, "L", false, 0, "")
+	doc.Ln(6)
+
+	// 明細表格
+	widths := []float64{100, 20, 30, 30}
+	doc.Font("B", 10)
+	doc.SetFillColor(230, 230, 230)
+	for i, header := range []string{"Description", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		doc.CellFormat(widths[i], 7, header, "B", 0, align, true, 0, "")
+	}
+	doc.Ln(-1)
+
+	doc.Font("", 10)
+	for _, item := range invoice.InvoiceItems {
+		doc.CellFormat(widths[0], 7, doc.Encode(item.Description), "", 0, "L", false, 0, "")
+		doc.CellFormat(widths[1], 7, fmt.Sprintf("%d", item.Quantity), "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[2], 7, formatAmount(item.UnitPrice), "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[3], 7, formatAmount(item.Amount), "", 1, "R", false, 0, "")
+	}
+	doc.Ln(2)
+
+	// 金額合計
+	totals := [][2]string{
+		{"Subtotal", formatAmount(invoice.Subtotal)},
+		{"Fees", formatAmount(invoice.FeeAmount)},
+		{fmt.Sprintf("Tax included (%.0f%%)", invoice.TaxRate*100), formatAmount(invoice.TaxAmount)},
+	}
+	for _, row := range totals {
+		doc.CellFormat(widths[0]+widths[1], 6, "", "", 0, "L", false, 0, "")
+		doc.CellFormat(widths[2], 6, row[0], "", 0, "R", false, 0, "")
+		doc.CellFormat(widths[3], 6, row[1], "", 1, "R", false, 0, "")
+	}
+	doc.Font("B", 11)
+	doc.CellFormat(widths[0]+widths[1], 8, "", "", 0, "L", false, 0, "")
+	doc.CellFormat(widths[2], 8, "Total", "T", 0, "R", false, 0, "")
+	doc.CellFormat(widths[3], 8, formatAmount(invoice.TotalAmount), "T", 1, "R", false, 0, "")
+
+	if invoice.Type == models.InvoiceTypeCreditNote {
+		doc.Ln(6)
+		doc.Font("", 9)
+		doc.MultiCell(0, 5, "The amounts above have been refunded and credited against the original invoice.", "", "L", false)
+	}
+
+	return doc.Bytes()
+}
+
+// formatAmount 格式化金額
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TicketPage 單張電子票券所需資料
+type TicketPage struct {
+	Ticket     *models.Ticket
+	TicketType *models.TicketType
+	Event      *models.Event
+}
+
+// RenderTickets 將電子票券繪製為 PDF，每張票券一頁
+func RenderTickets(fontPath string, brand string, pages []TicketPage) ([]byte, error) {
+	doc := NewDocument(fontPath)
+	doc.SetTitle(brand+" E-Ticket", true)
+
+	for _, page := range pages {
+		if err := renderTicketPage(doc, brand, page); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// renderTicketPage 繪製單張票券
+func renderTicketPage(doc *Document, brand string, page TicketPage) error {
+	doc.AddPage()
+
+	// 品牌標題列
+	doc.SetFillColor(33, 37, 41)
+	doc.SetTextColor(255, 255, 255)
+	doc.Font("B", 18)
+	doc.CellFormat(0, 14, doc.Encode(brand), "", 1, "C", true, 0, "")
+	doc.SetTextColor(0, 0, 0)
+	doc.Ln(8)
+
+	// 活動資訊
+	doc.Font("B", 16)
+	doc.MultiCell(0, 8, doc.Encode(page.Event.Title), "", "L", false)
+	doc.Ln(2)
+
+	doc.Font("", 11)
+	details := [][2]string{
+		{"Date", page.Event.StartTime.Format("2006-01-02 (Mon)")},
+		{"Time", fmt.Sprintf("%s - %s", page.Event.StartTime.Format("15:04"), page.Event.EndTime.Format("15:04"))},
+		{"Venue", page.Event.Location},
+		{"Ticket", page.TicketType.Name},
+	}
+	for _, row := range details {
+		doc.Font("B", 11)
+		doc.CellFormat(25, 7, row[0], "", 0, "L", false, 0, "")
+		doc.Font("", 11)
+		doc.CellFormat(0, 7, doc.Encode(row[1]), "", 1, "L", false, 0, "")
+	}
+	doc.Ln(8)
+
+	// 票券碼 QR Code
+	png, err := qrcode.Encode(page.Ticket.TicketCode, qrcode.Medium, 512)
+	if err != nil {
+		return err
+	}
+
+	imageName := "qr-" + page.Ticket.ID.String()
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	doc.RegisterImageOptionsReader(imageName, options, bytes.NewReader(png))
+
+	pageWidth, _ := doc.GetPageSize()
+	size := 80.0
+	doc.ImageOptions(imageName, (pageWidth-size)/2, doc.GetY(), size, size, true, options, 0, "")
+	doc.Ln(4)
+
+	doc.Font("", 10)
+	doc.CellFormat(0, 6, page.Ticket.TicketCode, "", 1, "C", false, 0, "")
+	doc.Ln(6)
+
+	doc.SetTextColor(110, 110, 110)
+	doc.Font("", 9)
+	doc.MultiCell(0, 5, "Present this QR code at the entrance. Each ticket can be scanned only once; do not share it.", "", "C", false)
+	doc.SetTextColor(0, 0, 0)
+
+	return doc.Error()
+}
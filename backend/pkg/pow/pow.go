@@ -0,0 +1,149 @@
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SolutionHeader 客戶端送回解答的請求標頭
+const SolutionHeader = "X-PoW-Solution"
+
+// MaxDifficulty 難度（前導零位元數）上限，避免簽發無法解出的挑戰
+const MaxDifficulty = 32
+
+var (
+	ErrInvalidSolution  = errors.New("無效的工作量證明")
+	ErrExpiredChallenge = errors.New("工作量證明挑戰已過期")
+)
+
+// Challenge 已簽章的工作量證明挑戰
+//
+// 客戶端須找出計數器 n，使 SHA-256(Token + ":" + n) 至少有 Difficulty 個前導零位元，
+// 再以 Token + ":" + n 作為解答送回。
+type Challenge struct {
+	Token      string
+	Nonce      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Issuer 以 HMAC 簽發與驗證挑戰，伺服器不需保存已簽發的挑戰
+type Issuer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewIssuer 創建新的 Issuer 實例
+func NewIssuer(secret []byte, ttl time.Duration) *Issuer {
+	return &Issuer{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue 為 subject（如用戶 ID）簽發指定難度的挑戰，解答只能由同一 subject 使用
+func (i *Issuer) Issue(subject string, difficulty int) (*Challenge, error) {
+	if difficulty < 0 || difficulty > MaxDifficulty {
+		return nil, fmt.Errorf("無效的難度: %d", difficulty)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	challenge := &Challenge{
+		Nonce:      hex.EncodeToString(buf),
+		Difficulty: difficulty,
+		ExpiresAt:  time.Now().Add(i.ttl).Truncate(time.Second),
+	}
+	payload := fmt.Sprintf("%s.%d.%d", challenge.Nonce, challenge.Difficulty, challenge.ExpiresAt.Unix())
+	challenge.Token = base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + i.sign(payload, subject)
+	return challenge, nil
+}
+
+// Verify 驗證解答的簽章、期限與工作量，返回對應的挑戰；是否已使用須由呼叫端檢查
+func (i *Issuer) Verify(solution string, subject string) (*Challenge, error) {
+	token, counter, found := strings.Cut(solution, ":")
+	if !found || counter == "" || len(counter) > 32 {
+		return nil, ErrInvalidSolution
+	}
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidSolution
+	}
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSolution
+	}
+	payload := string(payloadBytes)
+	if !hmac.Equal([]byte(signature), []byte(i.sign(payload, subject))) {
+		return nil, ErrInvalidSolution
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidSolution
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, ErrInvalidSolution
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSolution
+	}
+
+	challenge := &Challenge{
+		Token:      token,
+		Nonce:      parts[0],
+		Difficulty: difficulty,
+		ExpiresAt:  time.Unix(expiresAt, 0),
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrExpiredChallenge
+	}
+	if LeadingZeroBits(token, counter) < difficulty {
+		return nil, ErrInvalidSolution
+	}
+	return challenge, nil
+}
+
+// Solve 暴力搜尋符合難度的解答，供測試與非瀏覽器客戶端使用
+func Solve(token string, difficulty int) string {
+	for n := uint64(0); ; n++ {
+		counter := strconv.FormatUint(n, 10)
+		if LeadingZeroBits(token, counter) >= difficulty {
+			return token + ":" + counter
+		}
+	}
+}
+
+// LeadingZeroBits 計算 SHA-256(token + ":" + counter) 的前導零位元數
+func LeadingZeroBits(token string, counter string) int {
+	sum := sha256.Sum256([]byte(token + ":" + counter))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+func (i *Issuer) sign(payload string, subject string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(payload + "|" + subject))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,398 @@
+package tlsfp
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TLS 擴充功能代碼
+const (
+	extServerName          uint16 = 0x0000
+	extSupportedGroups     uint16 = 0x000a
+	extECPointFormats      uint16 = 0x000b
+	extSignatureAlgorithms uint16 = 0x000d
+	extALPN                uint16 = 0x0010
+	extSupportedVersions   uint16 = 0x002b
+)
+
+const (
+	recordTypeHandshake      = 0x16
+	handshakeTypeClientHello = 0x01
+	recordHeaderLength       = 5
+)
+
+// errIncomplete 尚未收到完整的 ClientHello
+var errIncomplete = errors.New("ClientHello 不完整")
+
+// ClientHello 計算指紋所需的 ClientHello 欄位，保留原始順序
+type ClientHello struct {
+	Version             uint16
+	CipherSuites        []uint16
+	Extensions          []uint16
+	SupportedGroups     []uint16
+	PointFormats        []uint8
+	SignatureAlgorithms []uint16
+	SupportedVersions   []uint16
+	ALPN                []string
+	ServerName          string
+}
+
+// Fingerprint TLS 用戶端指紋
+type Fingerprint struct {
+	JA3     string // JA3 原始字串
+	JA3Hash string // JA3 的 MD5
+	JA4     string
+}
+
+// ParseClientHello 從連線最初的 TLS 紀錄解析 ClientHello，ClientHello 可能跨越多個紀錄
+func ParseClientHello(data []byte) (*ClientHello, error) {
+	var handshake []byte
+	for {
+		if len(data) < recordHeaderLength {
+			return nil, errIncomplete
+		}
+		if data[0] != recordTypeHandshake {
+			return nil, errors.New("不是 TLS 交握紀錄")
+		}
+		length := int(binary.BigEndian.Uint16(data[3:5]))
+		if len(data) < recordHeaderLength+length {
+			return nil, errIncomplete
+		}
+		handshake = append(handshake, data[recordHeaderLength:recordHeaderLength+length]...)
+		data = data[recordHeaderLength+length:]
+
+		if len(handshake) >= 4 {
+			if handshake[0] != handshakeTypeClientHello {
+				return nil, errors.New("不是 ClientHello")
+			}
+			messageLength := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) >= 4+messageLength {
+				return parseClientHelloBody(handshake[4 : 4+messageLength])
+			}
+		}
+	}
+}
+
+// parseClientHelloBody 解析 ClientHello 交握訊息內容
+func parseClientHelloBody(body []byte) (*ClientHello, error) {
+	r := reader(body)
+	hello := &ClientHello{}
+
+	var ok bool
+	if hello.Version, ok = r.uint16(); !ok {
+		return nil, errors.New("無效的 ClientHello")
+	}
+	if !r.skip(32) { // random
+		return nil, errors.New("無效的 ClientHello")
+	}
+	if _, ok = r.vector8(); !ok { // session id
+		return nil, errors.New("無效的 ClientHello")
+	}
+	ciphers, ok := r.vector16()
+	if !ok || len(ciphers)%2 != 0 {
+		return nil, errors.New("無效的加密套件")
+	}
+	hello.CipherSuites = uint16List(ciphers)
+	if _, ok = r.vector8(); !ok { // compression methods
+		return nil, errors.New("無效的 ClientHello")
+	}
+
+	// 舊版用戶端可能沒有擴充功能
+	if len(r) == 0 {
+		return hello, nil
+	}
+	extensions, ok := r.vector16()
+	if !ok {
+		return nil, errors.New("無效的擴充功能")
+	}
+
+	er := reader(extensions)
+	for len(er) > 0 {
+		extType, ok := er.uint16()
+		if !ok {
+			return nil, errors.New("無效的擴充功能")
+		}
+		extData, ok := er.vector16()
+		if !ok {
+			return nil, errors.New("無效的擴充功能")
+		}
+		hello.Extensions = append(hello.Extensions, extType)
+
+		d := reader(extData)
+		switch extType {
+		case extServerName:
+			hello.ServerName = parseServerName(d)
+		case extSupportedGroups:
+			if list, ok := d.vector16(); ok {
+				hello.SupportedGroups = uint16List(list)
+			}
+		case extECPointFormats:
+			if list, ok := d.vector8(); ok {
+				hello.PointFormats = append([]uint8(nil), list...)
+			}
+		case extSignatureAlgorithms:
+			if list, ok := d.vector16(); ok {
+				hello.SignatureAlgorithms = uint16List(list)
+			}
+		case extALPN:
+			if list, ok := d.vector16(); ok {
+				lr := reader(list)
+				for len(lr) > 0 {
+					proto, ok := lr.vector8()
+					if !ok {
+						break
+					}
+					hello.ALPN = append(hello.ALPN, string(proto))
+				}
+			}
+		case extSupportedVersions:
+			if list, ok := d.vector8(); ok {
+				hello.SupportedVersions = uint16List(list)
+			}
+		}
+	}
+
+	return hello, nil
+}
+
+// Fingerprint 計算 JA3 與 JA4 指紋
+func (h *ClientHello) Fingerprint() *Fingerprint {
+	ja3 := h.JA3()
+	sum := md5.Sum([]byte(ja3))
+	return &Fingerprint{
+		JA3:     ja3,
+		JA3Hash: hex.EncodeToString(sum[:]),
+		JA4:     h.JA4(),
+	}
+}
+
+// JA3 依 SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats 組成字串，排除 GREASE 值
+func (h *ClientHello) JA3() string {
+	points := make([]string, len(h.PointFormats))
+	for i, p := range h.PointFormats {
+		points[i] = strconv.Itoa(int(p))
+	}
+
+	return strings.Join([]string{
+		strconv.Itoa(int(h.Version)),
+		joinDecimal(h.CipherSuites),
+		joinDecimal(h.Extensions),
+		joinDecimal(h.SupportedGroups),
+		strings.Join(points, "-"),
+	}, ",")
+}
+
+// JA4 依 FoxIO JA4 規格計算：協定版本 SNI 數量 ALPN _ 排序後加密套件雜湊 _ 排序後擴充功能與簽章演算法雜湊
+func (h *ClientHello) JA4() string {
+	ciphers := withoutGREASE(h.CipherSuites)
+	extensions := withoutGREASE(h.Extensions)
+
+	sni := "i"
+	if h.ServerName != "" || contains(extensions, extServerName) {
+		sni = "d"
+	}
+
+	prefix := fmt.Sprintf("t%s%s%02d%02d%s", ja4Version(h), sni, min(len(ciphers), 99), min(len(extensions), 99), ja4ALPN(h.ALPN))
+
+	// 擴充功能雜湊不含 SNI 與 ALPN，兩者已反映在前綴中
+	var hashedExtensions []uint16
+	for _, ext := range extensions {
+		if ext != extServerName && ext != extALPN {
+			hashedExtensions = append(hashedExtensions, ext)
+		}
+	}
+	extensionPart := joinHex(sortedCopy(hashedExtensions))
+	if sigs := withoutGREASE(h.SignatureAlgorithms); len(sigs) > 0 {
+		extensionPart += "_" + joinHex(sigs)
+	}
+
+	cipherHash := ja4Hash(joinHex(sortedCopy(ciphers)), len(ciphers) == 0)
+	extensionHash := ja4Hash(extensionPart, len(extensions) == 0)
+
+	return prefix + "_" + cipherHash + "_" + extensionHash
+}
+
+// ja4Version 優先使用 supported_versions 中的最高版本
+func ja4Version(h *ClientHello) string {
+	version := h.Version
+	for _, v := range withoutGREASE(h.SupportedVersions) {
+		if v > version {
+			version = v
+		}
+	}
+
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+// ja4ALPN 取第一個 ALPN 值的首尾字元，非英數字元時改用十六進位表示的首尾字元
+func ja4ALPN(protocols []string) string {
+	if len(protocols) == 0 || protocols[0] == "" {
+		return "00"
+	}
+	proto := protocols[0]
+	first, last := proto[0], proto[len(proto)-1]
+	if isAlphanumeric(first) && isAlphanumeric(last) {
+		return string([]byte{first, last})
+	}
+	encoded := hex.EncodeToString([]byte(proto))
+	return string([]byte{encoded[0], encoded[len(encoded)-1]})
+}
+
+// ja4Hash 取 SHA-256 前 12 個十六進位字元，無內容時以 0 補滿
+func ja4Hash(value string, empty bool) string {
+	if empty {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// parseServerName 取出 server_name 擴充功能中的主機名稱
+func parseServerName(r reader) string {
+	list, ok := r.vector16()
+	if !ok {
+		return ""
+	}
+	lr := reader(list)
+	for len(lr) > 0 {
+		nameType, ok := lr.uint8()
+		if !ok {
+			return ""
+		}
+		name, ok := lr.vector16()
+		if !ok {
+			return ""
+		}
+		if nameType == 0 {
+			return string(name)
+		}
+	}
+	return ""
+}
+
+// isGREASE 檢查是否為 RFC 8701 保留的 GREASE 值（0x0a0a、0x1a1a…0xfafa）
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func withoutGREASE(values []uint16) []uint16 {
+	result := make([]uint16, 0, len(values))
+	for _, v := range values {
+		if !isGREASE(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func joinDecimal(values []uint16) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range withoutGREASE(values) {
+		parts = append(parts, strconv.Itoa(int(v)))
+	}
+	return strings.Join(parts, "-")
+}
+
+func joinHex(values []uint16) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%04x", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortedCopy(values []uint16) []uint16 {
+	sorted := append([]uint16(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func contains(values []uint16, target uint16) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlphanumeric(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func uint16List(data []byte) []uint16 {
+	list := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		list = append(list, binary.BigEndian.Uint16(data[i:]))
+	}
+	return list
+}
+
+// reader 依序讀取 TLS 編碼欄位
+type reader []byte
+
+func (r *reader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *reader) uint8() (uint8, bool) {
+	if len(*r) < 1 {
+		return 0, false
+	}
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v, true
+}
+
+func (r *reader) uint16() (uint16, bool) {
+	if len(*r) < 2 {
+		return 0, false
+	}
+	v := binary.BigEndian.Uint16(*r)
+	*r = (*r)[2:]
+	return v, true
+}
+
+func (r *reader) vector8() ([]byte, bool) {
+	n, ok := r.uint8()
+	if !ok || len(*r) < int(n) {
+		return nil, false
+	}
+	v := (*r)[:n]
+	*r = (*r)[n:]
+	return v, true
+}
+
+func (r *reader) vector16() ([]byte, bool) {
+	n, ok := r.uint16()
+	if !ok || len(*r) < int(n) {
+		return nil, false
+	}
+	v := (*r)[:n]
+	*r = (*r)[n:]
+	return v, true
+}
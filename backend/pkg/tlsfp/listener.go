@@ -0,0 +1,132 @@
+package tlsfp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// maxCaptureBytes ClientHello 擷取上限，超過即放棄計算指紋
+const maxCaptureBytes = 16 * 1024
+
+type contextKey struct{}
+
+// Listener 包裝 TCP listener，記錄每條連線最初讀取的位元組以解析 ClientHello
+type Listener struct {
+	net.Listener
+}
+
+// NewListener 創建新的 Listener 實例，須置於 TLS 層之下
+func NewListener(inner net.Listener) *Listener {
+	return &Listener{
+		Listener: inner,
+	}
+}
+
+// Accept 接受連線並開始記錄讀取內容
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn, recording: true}, nil
+}
+
+// Conn 記錄 TLS 交握前讀取內容的連線
+type Conn struct {
+	net.Conn
+
+	mu          sync.Mutex
+	recording   bool
+	buf         []byte
+	fingerprint *Fingerprint
+}
+
+// Read 讀取資料，擷取 ClientHello 前同時保留一份副本
+func (c *Conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		if c.recording {
+			if len(c.buf)+n > maxCaptureBytes {
+				c.recording = false
+				c.buf = nil
+			} else {
+				c.buf = append(c.buf, p[:n]...)
+			}
+		}
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// capture 解析已讀取的 ClientHello 並停止記錄；crypto/tls 呼叫 GetConfigForClient 時 ClientHello 已完整讀入
+func (c *Conn) capture() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.recording {
+		return
+	}
+	c.recording = false
+	if hello, err := ParseClientHello(c.buf); err == nil {
+		c.fingerprint = hello.Fingerprint()
+	}
+	c.buf = nil
+}
+
+// Fingerprint 返回連線的 TLS 指紋，尚未完成交握或解析失敗時返回 nil
+func (c *Conn) Fingerprint() *Fingerprint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fingerprint
+}
+
+// WrapTLSConfig 設定 GetConfigForClient 以在交握時計算指紋，保留原有的 GetConfigForClient
+func WrapTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	wrapped := config.Clone()
+	next := config.GetConfigForClient
+	wrapped.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if conn, ok := hello.Conn.(*Conn); ok {
+			conn.capture()
+		}
+		if next != nil {
+			return next(hello)
+		}
+		return nil, nil
+	}
+	return wrapped
+}
+
+// ConnContext 供 http.Server.ConnContext 使用，將連線存入 request context
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if c, ok := conn.(*Conn); ok {
+		return context.WithValue(ctx, contextKey{}, c)
+	}
+	return ctx
+}
+
+// FromContext 取得 context 中連線的 TLS 指紋，非 TLS 連線或未經 Listener 時返回 nil
+func FromContext(ctx context.Context) *Fingerprint {
+	c, ok := ctx.Value(contextKey{}).(*Conn)
+	if !ok {
+		return nil
+	}
+	return c.Fingerprint()
+}
+
+// FromRequest 取得請求所屬連線的 TLS 指紋
+func FromRequest(r *http.Request) *Fingerprint {
+	if r == nil {
+		return nil
+	}
+	return FromContext(r.Context())
+}
@@ -7,10 +7,17 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+
+	"github.com/lipeichen/ticket-getter/pkg/clientip"
+	"github.com/lipeichen/ticket-getter/pkg/tlsfp"
 )
 
-// ExtractTLSFingerprint 從 HTTP 請求提取 TLS 指紋
+// ExtractTLSFingerprint 從 HTTP 請求提取 TLS 指紋；服務器直接終止 TLS 時使用交握計算的 JA4，否則以請求頭推估
 func ExtractTLSFingerprint(r *http.Request) string {
+	if fp := tlsfp.FromRequest(r); fp != nil {
+		return fp.JA4
+	}
+
 	// 從請求頭獲取相關信息
 	headers := map[string]string{
 		"User-Agent":       r.Header.Get("User-Agent"),
@@ -25,14 +32,8 @@ func ExtractTLSFingerprint(r *http.Request) string {
 		"Sec-Fetch-Site":     r.Header.Get("Sec-Fetch-Site"),
 	}
 
-	// 從請求獲取 IP 地址
-	ip := r.RemoteAddr
-	forwardedFor := r.Header.Get("X-Forwarded-For")
-	if forwardedFor != "" {
-		// 使用第一個代理前的 IP
-		ips := strings.Split(forwardedFor, ",")
-		ip = strings.TrimSpace(ips[0])
-	}
+	// 從請求獲取 IP 地址，僅信任可信任代理的轉發標頭
+	ip := clientip.FromRequest(r)
 	
 	// 可能的情況下從 TLS 連接獲取更多信息
 	var tlsInfo string
@@ -69,20 +70,10 @@ func ExtractTLSFingerprint(r *http.Request) string {
 // GetClientIPFingerprint 獲取客戶端 IP 和 TLS 指紋的組合
 func GetClientIPFingerprint(r *http.Request) string {
 	// 獲取客戶端 IP
-	ip := r.RemoteAddr
-	forwardedFor := r.Header.Get("X-Forwarded-For")
-	if forwardedFor != "" {
-		ips := strings.Split(forwardedFor, ",")
-		ip = strings.TrimSpace(ips[0])
-	}
-	
-	// 從客戶端獲取指紋
-	clientFingerprint := r.Header.Get("X-TLS-Fingerprint")
+	ip := clientip.FromRequest(r)
 	
-	// 如果客戶端沒有提供指紋，生成一個
-	if clientFingerprint == "" {
-		clientFingerprint = ExtractTLSFingerprint(r)
-	}
+	// 由服務器計算指紋，不信任客戶端提供的 X-TLS-Fingerprint
+	clientFingerprint := ExtractTLSFingerprint(r)
 	
 	// 組合 IP 和指紋
 	combinedFingerprint := fmt.Sprintf("%s|%s", ip, clientFingerprint)
@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// APNs 正式環境端點
+	apnsProductionURL = "https://api.push.apple.com"
+)
+
+// APNsClient 透過 APNs 通知 Wallet 票券已更新
+type APNsClient struct {
+	baseURL    string
+	topic      string
+	httpClient *http.Client
+}
+
+// NewAPNsClient 以票券憑證創建 APNs 客戶端，baseURL 為空時使用正式環境
+func NewAPNsClient(baseURL string, topic string, certificate tls.Certificate) *APNsClient {
+	if baseURL == "" {
+		baseURL = apnsProductionURL
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{certificate}},
+		ForceAttemptHTTP2: true,
+	}
+
+	return &APNsClient{
+		baseURL: baseURL,
+		topic:   topic,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   10 * time.Second,
+		},
+	}
+}
+
+// NotifyPassUpdated 推播空白通知，Wallet 收到後會向 web service 取得最新票券
+func (c *APNsClient) NotifyPassUpdated(pushToken string) error {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/3/device/%s", c.baseURL, pushToken), bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apns-topic", c.topic)
+	req.Header.Set("apns-push-type", "background")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("APNs 推播失敗 (%d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// AppleConfig Apple Wallet 票券設定
+type AppleConfig struct {
+	PassTypeIdentifier string
+	TeamIdentifier     string
+	OrganizationName   string
+	WebServiceURL      string
+	CertPath           string // 票券憑證 (PEM)
+	KeyPath            string // 票券憑證私鑰 (PEM)
+	WWDRPath           string // Apple WWDR 中繼憑證 (PEM)
+	AssetsDir          string // icon.png、logo.png 等圖片目錄
+}
+
+// ApplePassBuilder 產生並簽署 .pkpass 檔案
+type ApplePassBuilder struct {
+	config AppleConfig
+	cert   *x509.Certificate
+	key    crypto.PrivateKey
+	wwdr   *x509.Certificate
+	assets map[string][]byte
+}
+
+// applePass pass.json 結構
+type applePass struct {
+	FormatVersion       int              `json:"formatVersion"`
+	PassTypeIdentifier  string           `json:"passTypeIdentifier"`
+	SerialNumber        string           `json:"serialNumber"`
+	TeamIdentifier      string           `json:"teamIdentifier"`
+	OrganizationName    string           `json:"organizationName"`
+	Description         string           `json:"description"`
+	WebServiceURL       string           `json:"webServiceURL,omitempty"`
+	AuthenticationToken string           `json:"authenticationToken,omitempty"`
+	RelevantDate        string           `json:"relevantDate,omitempty"`
+	ExpirationDate      string           `json:"expirationDate,omitempty"`
+	Barcodes            []appleBarcode   `json:"barcodes"`
+	EventTicket         appleEventTicket `json:"eventTicket"`
+}
+
+type appleBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type appleEventTicket struct {
+	PrimaryFields   []appleField `json:"primaryFields"`
+	SecondaryFields []appleField `json:"secondaryFields"`
+	AuxiliaryFields []appleField `json:"auxiliaryFields"`
+}
+
+type appleField struct {
+	Key           string `json:"key"`
+	Label         string `json:"label"`
+	Value         string `json:"value"`
+	DateStyle     string `json:"dateStyle,omitempty"`
+	TimeStyle     string `json:"timeStyle,omitempty"`
+	ChangeMessage string `json:"changeMessage,omitempty"`
+}
+
+// NewApplePassBuilder 從設定的本機憑證創建 ApplePassBuilder
+func NewApplePassBuilder(config AppleConfig) (*ApplePassBuilder, error) {
+	if config.CertPath == "" || config.KeyPath == "" || config.WWDRPath == "" {
+		return nil, errors.New("未設定 Apple Wallet 憑證")
+	}
+
+	cert, err := loadCertificate(config.CertPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取票券憑證失敗: %w", err)
+	}
+
+	wwdr, err := loadCertificate(config.WWDRPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取 WWDR 憑證失敗: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(config.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取票券私鑰失敗: %w", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("解析票券私鑰失敗: %w", err)
+	}
+
+	assets := map[string][]byte{}
+	if config.AssetsDir != "" {
+		files, err := filepath.Glob(filepath.Join(config.AssetsDir, "*.png"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			assets[filepath.Base(file)] = content
+		}
+	}
+	if _, ok := assets["icon.png"]; !ok {
+		return nil, errors.New("Apple Wallet 票券缺少 icon.png")
+	}
+
+	return &ApplePassBuilder{
+		config: config,
+		cert:   cert,
+		key:    key,
+		wwdr:   wwdr,
+		assets: assets,
+	}, nil
+}
+
+// Build 產生已簽署的 .pkpass 檔案內容
+func (b *ApplePassBuilder) Build(ticket TicketInfo, authenticationToken string) ([]byte, error) {
+	passJSON, err := json.Marshal(b.passFor(ticket, authenticationToken))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{"pass.json": passJSON}
+	for name, content := range b.assets {
+		files[name] = content
+	}
+
+	// manifest.json 記錄每個檔案的 SHA-1 雜湊
+	manifest := make(map[string]string, len(files))
+	for name, content := range files {
+		sum := sha1.Sum(content)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := b.sign(manifestJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	files["manifest.json"] = manifestJSON
+	files["signature"] = signature
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// PassTypeIdentifier 返回票券類型識別碼
+func (b *ApplePassBuilder) PassTypeIdentifier() string {
+	return b.config.PassTypeIdentifier
+}
+
+// TLSCertificate 返回票券憑證，供 APNs 推播使用
+func (b *ApplePassBuilder) TLSCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{b.cert.Raw},
+		PrivateKey:  b.key,
+		Leaf:        b.cert,
+	}
+}
+
+// passFor 組合 pass.json 內容
+func (b *ApplePassBuilder) passFor(ticket TicketInfo, authenticationToken string) applePass {
+	pass := applePass{
+		FormatVersion:      1,
+		PassTypeIdentifier: b.config.PassTypeIdentifier,
+		SerialNumber:       ticket.SerialNumber,
+		TeamIdentifier:     b.config.TeamIdentifier,
+		OrganizationName:   b.config.OrganizationName,
+		Description:        ticket.EventTitle,
+		RelevantDate:       ticket.StartTime.Format(time.RFC3339),
+		ExpirationDate:     ticket.EndTime.Add(24 * time.Hour).Format(time.RFC3339),
+		Barcodes: []appleBarcode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         ticket.TicketCode,
+			MessageEncoding: "iso-8859-1",
+		}},
+		EventTicket: appleEventTicket{
+			PrimaryFields: []appleField{
+				{Key: "event", Label: "EVENT", Value: ticket.EventTitle},
+			},
+			SecondaryFields: []appleField{
+				{Key: "location", Label: "VENUE", Value: ticket.Location},
+				{Key: "ticket_type", Label: "TICKET", Value: ticket.TicketTypeName},
+			},
+			AuxiliaryFields: []appleField{
+				{
+					Key:           "start_time",
+					Label:         "DATE",
+					Value:         ticket.StartTime.Format(time.RFC3339),
+					DateStyle:     "PKDateStyleMedium",
+					TimeStyle:     "PKDateStyleShort",
+					ChangeMessage: "活動時間已變更為 %@",
+				},
+				{Key: "holder", Label: "HOLDER", Value: ticket.HolderName},
+			},
+		},
+	}
+
+	// 設定 web service 後，Wallet 會在收到推播時向伺服器取得最新票券
+	if b.config.WebServiceURL != "" {
+		pass.WebServiceURL = b.config.WebServiceURL
+		pass.AuthenticationToken = authenticationToken
+	}
+
+	return pass
+}
+
+// sign 以票券憑證對 manifest 產生分離式 PKCS#7 簽章
+func (b *ApplePassBuilder) sign(manifest []byte) ([]byte, error) {
+	signedData, err := pkcs7.NewSignedData(manifest)
+	if err != nil {
+		return nil, err
+	}
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	if err := signedData.AddSignerChain(b.cert, b.key, []*x509.Certificate{b.wwdr}, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, err
+	}
+	signedData.Detach()
+
+	return signedData.Finish()
+}
+
+// loadCertificate 讀取 PEM 格式憑證
+func loadCertificate(path string) (*x509.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("無效的 PEM 憑證")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// parsePrivateKey 解析 PEM 格式私鑰 (PKCS#1、PKCS#8 或 EC)
+func parsePrivateKey(content []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return nil, errors.New("無效的 PEM 私鑰")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("不支援的私鑰格式")
+}
@@ -0,0 +1,272 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Google Wallet 加入連結前綴
+	googleSaveURLPrefix = "https://pay.google.com/gp/v/save/"
+
+	// Google Wallet REST API 端點
+	googleWalletAPIURL = "https://walletobjects.googleapis.com/walletobjects/v1"
+
+	// Google Wallet API 授權範圍
+	googleWalletScope = "https://www.googleapis.com/auth/wallet_object.issuer"
+)
+
+// GoogleConfig Google Wallet 票券設定
+type GoogleConfig struct {
+	IssuerID           string
+	ServiceAccountPath string // 服務帳戶金鑰 JSON
+	Origins            []string
+}
+
+// GooglePassBuilder 產生 Google Wallet 加入連結並更新票券類別
+type GooglePassBuilder struct {
+	config      GoogleConfig
+	clientEmail string
+	privateKey  interface{}
+	tokenURI    string
+	httpClient  *http.Client
+}
+
+// googleServiceAccount 服務帳戶金鑰檔格式
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// googleLocalizedString Google Wallet 多語系字串
+type googleLocalizedString struct {
+	DefaultValue googleTranslatedString `json:"defaultValue"`
+}
+
+type googleTranslatedString struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+// googleEventTicketClass 活動票券類別，同一活動的票券共用
+type googleEventTicketClass struct {
+	ID           string                `json:"id"`
+	IssuerName   string                `json:"issuerName,omitempty"`
+	EventName    googleLocalizedString `json:"eventName"`
+	Venue        *googleVenue          `json:"venue,omitempty"`
+	DateTime     googleDateTime        `json:"dateTime"`
+	ReviewStatus string                `json:"reviewStatus,omitempty"`
+}
+
+type googleVenue struct {
+	Name    googleLocalizedString `json:"name"`
+	Address googleLocalizedString `json:"address"`
+}
+
+type googleDateTime struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// googleEventTicketObject 單張票券
+type googleEventTicketObject struct {
+	ID               string                 `json:"id"`
+	ClassID          string                 `json:"classId"`
+	State            string                 `json:"state"`
+	TicketHolderName string                 `json:"ticketHolderName,omitempty"`
+	TicketType       *googleLocalizedString `json:"ticketType,omitempty"`
+	Barcode          googleBarcode          `json:"barcode"`
+}
+
+type googleBarcode struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// NewGooglePassBuilder 從服務帳戶金鑰創建 GooglePassBuilder
+func NewGooglePassBuilder(config GoogleConfig) (*GooglePassBuilder, error) {
+	if config.IssuerID == "" || config.ServiceAccountPath == "" {
+		return nil, errors.New("未設定 Google Wallet 發行者")
+	}
+
+	content, err := os.ReadFile(config.ServiceAccountPath)
+	if err != nil {
+		return nil, fmt.Errorf("讀取服務帳戶金鑰失敗: %w", err)
+	}
+
+	var account googleServiceAccount
+	if err := json.Unmarshal(content, &account); err != nil {
+		return nil, fmt.Errorf("解析服務帳戶金鑰失敗: %w", err)
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析服務帳戶私鑰失敗: %w", err)
+	}
+
+	tokenURI := account.TokenURI
+	if tokenURI == "" {
+		tokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &GooglePassBuilder{
+		config:      config,
+		clientEmail: account.ClientEmail,
+		privateKey:  privateKey,
+		tokenURI:    tokenURI,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// SaveLink 產生「加入 Google 錢包」連結，JWT 內同時帶入類別與票券物件
+func (b *GooglePassBuilder) SaveLink(ticket TicketInfo) (string, error) {
+	classID := b.classID(ticket.EventID)
+
+	object := googleEventTicketObject{
+		ID:               fmt.Sprintf("%s.%s", b.config.IssuerID, ticket.SerialNumber),
+		ClassID:          classID,
+		State:            "ACTIVE",
+		TicketHolderName: ticket.HolderName,
+		TicketType:       localized(ticket.TicketTypeName),
+		Barcode: googleBarcode{
+			Type:  "QR_CODE",
+			Value: ticket.TicketCode,
+		},
+	}
+
+	claims := jwt.MapClaims{
+		"iss":     b.clientEmail,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"origins": b.config.Origins,
+		"payload": map[string]interface{}{
+			"eventTicketClasses": []googleEventTicketClass{b.eventClass(ticket)},
+			"eventTicketObjects": []googleEventTicketObject{object},
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(b.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return googleSaveURLPrefix + token, nil
+}
+
+// UpdateEventClass 更新活動票券類別的時間與地點，已加入錢包的票券會同步更新
+func (b *GooglePassBuilder) UpdateEventClass(ticket TicketInfo) error {
+	accessToken, err := b.accessToken()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(b.eventClass(ticket))
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/eventTicketClass/%s", googleWalletAPIURL, url.PathEscape(b.classID(ticket.EventID)))
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 尚無人加入錢包時類別不存在，無需更新
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("更新 Google Wallet 類別失敗 (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// eventClass 組合活動票券類別
+func (b *GooglePassBuilder) eventClass(ticket TicketInfo) googleEventTicketClass {
+	return googleEventTicketClass{
+		ID:        b.classID(ticket.EventID),
+		EventName: *localized(ticket.EventTitle),
+		Venue: &googleVenue{
+			Name:    *localized(ticket.Location),
+			Address: *localized(ticket.Location),
+		},
+		DateTime: googleDateTime{
+			Start: ticket.StartTime.Format(time.RFC3339),
+			End:   ticket.EndTime.Format(time.RFC3339),
+		},
+		ReviewStatus: "UNDER_REVIEW",
+	}
+}
+
+// classID 每個活動對應一個票券類別
+func (b *GooglePassBuilder) classID(eventID string) string {
+	return fmt.Sprintf("%s.event-%s", b.config.IssuerID, eventID)
+}
+
+// accessToken 以服務帳戶 JWT 換取 OAuth2 存取令牌
+func (b *GooglePassBuilder) accessToken() (string, error) {
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   b.clientEmail,
+		"scope": googleWalletScope,
+		"aud":   b.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(b.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := b.httpClient.Post(b.tokenURI, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("取得 Google 存取令牌失敗 (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
+
+// localized 建立繁體中文預設字串
+func localized(value string) *googleLocalizedString {
+	return &googleLocalizedString{
+		DefaultValue: googleTranslatedString{Language: "zh-TW", Value: value},
+	}
+}
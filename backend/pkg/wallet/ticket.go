@@ -0,0 +1,16 @@
+package wallet
+
+import "time"
+
+// TicketInfo 產生電子錢包票券所需的票券與活動資料
+type TicketInfo struct {
+	SerialNumber   string
+	TicketCode     string
+	EventID        string
+	EventTitle     string
+	Location       string
+	StartTime      time.Time
+	EndTime        time.Time
+	TicketTypeName string
+	HolderName     string
+}
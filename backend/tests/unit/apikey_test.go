@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/apikey"
+)
+
+func TestAPIKeyGenerate(t *testing.T) {
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if !strings.HasPrefix(key, apikey.KeyPrefix) || len(key) != len(apikey.KeyPrefix)+64 {
+		t.Errorf("Unexpected key format: %q", key)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Errorf("Expected display prefix %q to be a short prefix of the key", prefix)
+	}
+	if hash != apikey.Hash(key) || strings.Contains(hash, key) {
+		t.Errorf("Expected stored hash to match key without containing it")
+	}
+
+	other, _, _, _ := apikey.Generate()
+	if other == key {
+		t.Error("Expected generated keys to be unique")
+	}
+}
+
+func TestAPIKeyActiveAndScopes(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	if !(&models.APIKey{}).Active(now) || !(&models.APIKey{ExpiresAt: &future}).Active(now) {
+		t.Error("Expected key without revocation or past expiry to be active")
+	}
+	if (&models.APIKey{ExpiresAt: &past}).Active(now) || (&models.APIKey{RevokedAt: &past}).Active(now) {
+		t.Error("Expected expired or revoked key to be inactive")
+	}
+
+	if scopes := (&models.APIKey{}).ScopeList(); len(scopes) != 0 {
+		t.Errorf("Expected no scopes, got %v", scopes)
+	}
+	scopes := (&models.APIKey{Scopes: "ticket:use,order:read"}).ScopeList()
+	if len(scopes) != 2 || scopes[0] != models.PermissionTicketUse || scopes[1] != models.PermissionOrderRead {
+		t.Errorf("Unexpected scopes: %v", scopes)
+	}
+}
@@ -0,0 +1,66 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lipeichen/ticket-getter/pkg/captcha"
+)
+
+func TestSiteVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm failed: %v", err)
+		}
+		if r.PostForm.Get("secret") != "secret" || r.PostForm.Get("remoteip") != "203.0.113.7" {
+			t.Errorf("Unexpected form: %v", r.PostForm)
+		}
+
+		response := map[string]interface{}{"success": true, "hostname": "tickets.example.com"}
+		switch r.PostForm.Get("response") {
+		case "low-score":
+			response["score"] = 0.1
+		case "high-score":
+			response["score"] = 0.9
+		case "invalid":
+			response = map[string]interface{}{"success": false, "error-codes": []string{"invalid-input-response"}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	verifier := captcha.NewSiteVerifier(server.URL, "secret", 0.5)
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"no-score", true},
+		{"high-score", true},
+		{"low-score", false},
+		{"invalid", false},
+	}
+
+	for _, tt := range tests {
+		result, err := verifier.Verify(context.Background(), tt.token, "203.0.113.7")
+		if err != nil {
+			t.Fatalf("Verify(%s) failed: %v", tt.token, err)
+		}
+		if result.Success != tt.want {
+			t.Errorf("Verify(%s): expected success=%v, got %+v", tt.token, tt.want, result)
+		}
+	}
+}
+
+func TestFakeCaptcha(t *testing.T) {
+	fake := captcha.NewFake("pass")
+
+	if result, _ := fake.Verify(context.Background(), "pass", ""); !result.Success {
+		t.Error("Expected allowed token to pass")
+	}
+	if result, _ := fake.Verify(context.Background(), "other", ""); result.Success {
+		t.Error("Expected unknown token to fail")
+	}
+}
@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/middleware"
+	"github.com/lipeichen/ticket-getter/pkg/clientip"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.7:5000", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.7"},
+		{"trusted proxy without headers", "10.0.0.2:5000", nil, "10.0.0.2"},
+		{"x-forwarded-for single hop", "10.0.0.2:5000", map[string]string{"X-Forwarded-For": "198.51.100.9"}, "198.51.100.9"},
+		{"spoofed leftmost entry is skipped", "10.0.0.2:5000", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.9, 10.1.1.1"}, "198.51.100.9"},
+		{"forwarded takes precedence", "10.0.0.2:5000", map[string]string{
+			"Forwarded":       `for=192.0.2.60;proto=https, for="[2001:db8:cafe::17]:4711"`,
+			"X-Forwarded-For": "198.51.100.9",
+		}, "2001:db8:cafe::17"},
+		{"x-real-ip fallback", "[2001:db8::1]:443", map[string]string{"X-Real-IP": "192.0.2.33"}, "192.0.2.33"},
+		{"unknown hop stops at proxy", "10.0.0.2:5000", map[string]string{"Forwarded": "for=unknown"}, "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := resolver.Resolve(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := clientip.NewResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected error for invalid proxy")
+	}
+}
+
+func TestClientIPMiddlewareAlignsGin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver, _ := clientip.NewResolver([]string{"10.0.0.0/8"})
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	router.Use(middleware.ClientIP(resolver))
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP()+"|"+clientip.FromRequest(c.Request))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.9")
+	router.ServeHTTP(w, req)
+
+	if w.Body.String() != "198.51.100.9|198.51.100.9" {
+		t.Errorf("Expected gin and resolver to agree on client IP, got %s", w.Body.String())
+	}
+}
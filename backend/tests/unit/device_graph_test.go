@@ -0,0 +1,187 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"gorm.io/gorm"
+)
+
+// createDeviceGraphUsers 建立 n 個用戶，ID 依建立順序遞增，第一個用戶的 ID 最小
+func createDeviceGraphUsers(t *testing.T, db *gorm.DB, n int) []models.User {
+	users := make([]models.User, n)
+	for i := range users {
+		id := uuid.MustParse(fmt.Sprintf("00000000-0000-4000-8000-%012d", i+1))
+		users[i] = models.User{ID: id, Email: fmt.Sprintf("user%d@example.com", i), PasswordHash: "x", Name: "測試用戶", Role: models.RoleUser}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("建立用戶失敗: %v", err)
+		}
+	}
+	return users
+}
+
+// minUserID 最小的用戶 ID，即群組 ID
+func minUserID(users ...models.User) string {
+	id := users[0].ID.String()
+	for _, user := range users[1:] {
+		if user.ID.String() < id {
+			id = user.ID.String()
+		}
+	}
+	return id
+}
+
+func TestDeviceGraphRecord(t *testing.T) {
+	_, client := newTestRedis(t)
+	db := newTestDB(t)
+	service := services.NewDeviceGraphService(db, client)
+	users := createDeviceGraphUsers(t, db, 1)
+	ctx := context.Background()
+
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", TLSFingerprint: "fp-a"}
+	for i := 0; i < 2; i++ {
+		if err := service.Record(ctx, users[0].ID, info); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	var links []models.DeviceLink
+	db.Where("user_id = ?", users[0].ID).Order("kind").Find(&links)
+	if len(links) != 2 || links[0].Kind != models.DeviceLinkDevice || links[1].Kind != models.DeviceLinkIP {
+		t.Fatalf("Expected device and IP links, got %+v", links)
+	}
+	for _, link := range links {
+		if link.SeenCount != 2 {
+			t.Errorf("Expected %s link to be seen twice, got %d", link.Kind, link.SeenCount)
+		}
+	}
+
+	var user models.User
+	db.First(&user, "id = ?", users[0].ID)
+	if user.TLSFingerprint != "fp-a" {
+		t.Errorf("Expected TLS fingerprint to be stored on user, got %q", user.TLSFingerprint)
+	}
+}
+
+func TestDeviceGraphClusterID(t *testing.T) {
+	mr, client := newTestRedis(t)
+	db := newTestDB(t)
+	service := services.NewDeviceGraphService(db, client)
+	users := createDeviceGraphUsers(t, db, 3)
+	ctx := context.Background()
+
+	clusterID := func(user models.User) string {
+		id, err := service.ClusterID(ctx, user.ID.String())
+		if err != nil {
+			t.Fatalf("ClusterID failed: %v", err)
+		}
+		return id
+	}
+
+	// 共用裝置指紋的帳號屬於同一群組
+	service.Record(ctx, users[0].ID, dto.ClientInfo{TLSFingerprint: "fp-a"})
+	service.Record(ctx, users[1].ID, dto.ClientInfo{TLSFingerprint: "fp-a"})
+	want := minUserID(users[0], users[1])
+	if got := clusterID(users[0]); got != want {
+		t.Errorf("Expected cluster ID %s, got %s", want, got)
+	}
+	if got := clusterID(users[1]); got != want {
+		t.Errorf("Expected cluster ID %s, got %s", want, got)
+	}
+	if got := clusterID(users[2]); got != users[2].ID.String() {
+		t.Errorf("Expected unlinked user to be its own cluster, got %s", got)
+	}
+	if !mr.Exists("device_cluster:" + users[0].ID.String()) {
+		t.Fatal("Expected cluster ID to be cached")
+	}
+
+	// 新關聯合併群組後，所有成員的快取都須清除，不只新增關聯的帳號
+	if err := service.LinkPaymentMethod(ctx, users[2].ID, "card-fp"); err != nil {
+		t.Fatalf("LinkPaymentMethod failed: %v", err)
+	}
+	if err := service.LinkPaymentMethod(ctx, users[1].ID, "card-fp"); err != nil {
+		t.Fatalf("LinkPaymentMethod failed: %v", err)
+	}
+	want = minUserID(users...)
+	for i, user := range users {
+		if got := clusterID(user); got != want {
+			t.Errorf("User %d: expected merged cluster ID %s, got %s", i, want, got)
+		}
+	}
+
+	// 已存在的關聯不改變群組，不清除快取
+	service.Record(ctx, users[0].ID, dto.ClientInfo{TLSFingerprint: "fp-a"})
+	if !mr.Exists("device_cluster:" + users[1].ID.String()) {
+		t.Error("Expected cache to be kept when an existing link is seen again")
+	}
+
+	if _, err := service.ClusterID(ctx, "not-a-uuid"); err == nil {
+		t.Error("Expected invalid user ID to be rejected")
+	}
+}
+
+func TestDeviceGraphClusterIDConsistentWhenTruncated(t *testing.T) {
+	_, client := newTestRedis(t)
+	db := newTestDB(t)
+	service := services.NewDeviceGraphService(db, client)
+	ctx := context.Background()
+
+	// 以 IP 串成超過上限的鏈，兩端各自只能展開部分帳號；自尾端展開時看不到 ID 最小的第一個帳號
+	users := createDeviceGraphUsers(t, db, 110)
+	for i := 0; i+1 < len(users); i++ {
+		ip := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		service.Record(ctx, users[i].ID, dto.ClientInfo{IPAddress: ip})
+		service.Record(ctx, users[i+1].ID, dto.ClientInfo{IPAddress: ip})
+	}
+
+	first, err := service.ClusterID(ctx, users[0].ID.String())
+	if err != nil {
+		t.Fatalf("ClusterID failed: %v", err)
+	}
+	last, err := service.ClusterID(ctx, users[len(users)-1].ID.String())
+	if err != nil {
+		t.Fatalf("ClusterID failed: %v", err)
+	}
+	if first != last {
+		t.Errorf("Expected both ends of the cluster to share an ID, got %s and %s", first, last)
+	}
+}
+
+func TestDeviceGraphCluster(t *testing.T) {
+	_, client := newTestRedis(t)
+	db := newTestDB(t)
+	service := services.NewDeviceGraphService(db, client)
+	users := createDeviceGraphUsers(t, db, 3)
+	ctx := context.Background()
+
+	service.Record(ctx, users[0].ID, dto.ClientInfo{IPAddress: "203.0.113.7", TLSFingerprint: "fp-a"})
+	service.Record(ctx, users[1].ID, dto.ClientInfo{IPAddress: "203.0.113.7"})
+	service.Record(ctx, users[2].ID, dto.ClientInfo{IPAddress: "198.51.100.1"})
+
+	cluster, err := service.Cluster(ctx, users[1].ID.String())
+	if err != nil {
+		t.Fatalf("Cluster failed: %v", err)
+	}
+	if cluster.ClusterID != minUserID(users[0], users[1]) {
+		t.Errorf("Expected cluster ID %s, got %s", minUserID(users[0], users[1]), cluster.ClusterID)
+	}
+	if len(cluster.Users) != 2 || cluster.Truncated {
+		t.Fatalf("Expected two users without truncation, got %+v", cluster)
+	}
+	if cluster.Users[0].TLSFingerprint != "fp-a" {
+		t.Errorf("Expected users ordered by creation with TLS fingerprint, got %+v", cluster.Users)
+	}
+	// 兩人共用的 IP 與第一人的裝置指紋
+	if len(cluster.Links) != 3 {
+		t.Errorf("Expected 3 links, got %+v", cluster.Links)
+	}
+
+	if _, err := service.Cluster(ctx, uuid.New().String()); err == nil || err.Error() != "使用者不存在" {
+		t.Errorf("Expected unknown user to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package unit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func TestFingerprintAllowlist(t *testing.T) {
+	cfg := &config.Config{FingerprintAllowlist: []string{"10.20.0.0/16", "203.0.113.7", "2001:db8::1"}}
+	service, err := services.NewFingerprintService(nil, nil, cfg)
+	if err != nil {
+		t.Fatalf("NewFingerprintService failed: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.20.3.4", true},
+		{"10.21.3.4", false},
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+		{"", false},
+		{"not-an-ip", false},
+	}
+	for _, tt := range tests {
+		if got := service.Exempt(tt.ip); got != tt.want {
+			t.Errorf("Exempt(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if _, err := services.NewFingerprintService(nil, nil, &config.Config{FingerprintAllowlist: []string{"box-office"}}); err == nil {
+		t.Error("Expected error for invalid allowlist entry")
+	}
+}
+
+// createFingerprintTicketType 建立指定指紋政策的票種
+func createFingerprintTicketType(t *testing.T, db *gorm.DB, eventID uuid.UUID, maxPurchases int, scope string) string {
+	ticketType := &models.TicketType{
+		EventID:                  eventID,
+		Name:                     "全票",
+		Price:                    1000,
+		TotalQuantity:            100,
+		AvailableQuantity:        100,
+		SaleStart:                time.Now().Add(-time.Hour),
+		SaleEnd:                  time.Now().Add(time.Hour),
+		FingerprintWindowMinutes: 60,
+		FingerprintMaxPurchases:  maxPurchases,
+		FingerprintScope:         scope,
+	}
+	if err := db.Create(ticketType).Error; err != nil {
+		t.Fatalf("建立票種失敗: %v", err)
+	}
+	// 上限 0 為零值，建立時會被欄位預設值取代
+	if maxPurchases == 0 {
+		if err := db.Model(ticketType).Update("fingerprint_max_purchases", 0).Error; err != nil {
+			t.Fatalf("更新票種失敗: %v", err)
+		}
+	}
+	return ticketType.ID.String()
+}
+
+func TestFingerprintRecordAndCheck(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	service, err := services.NewFingerprintService(db, client, &config.Config{})
+	if err != nil {
+		t.Fatalf("NewFingerprintService failed: %v", err)
+	}
+	const ip = "198.51.100.1"
+
+	// 區間內達購買上限後拒絕
+	limited := createFingerprintTicketType(t, db, uuid.New(), 2, models.FingerprintScopeTicketType)
+	for i := 0; i < 2; i++ {
+		if err := service.Record(ctx, "fp-a", ip, limited, "user-1"); err != nil {
+			t.Fatalf("Record %d failed: %v", i, err)
+		}
+	}
+	if err := service.Record(ctx, "fp-a", ip, limited, "user-1"); err == nil || err.Error() != "您已經購買過此票券，請勿重複購買" {
+		t.Errorf("Expected purchase over limit to be rejected, got %v", err)
+	}
+	check, err := service.Check(ctx, "fp-a", ip, limited)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if check.Purchases != 2 || check.Remaining != 0 || !check.AlreadyPurchased {
+		t.Errorf("Expected limit to be reached, got %+v", check)
+	}
+	if check, _ := service.Check(ctx, "fp-b", ip, limited); check.Purchases != 0 || check.Remaining != 2 {
+		t.Errorf("Expected other fingerprint to be unaffected, got %+v", check)
+	}
+
+	// 超過統計區間的購買不再計算
+	expiring := createFingerprintTicketType(t, db, uuid.New(), 1, models.FingerprintScopeTicketType)
+	key := "fingerprint:ticket_type:" + expiring + ":fp-a"
+	old := time.Now().Add(-61 * time.Minute).UnixNano()
+	if err := client.ZAdd(ctx, key, redis.Z{Score: float64(old), Member: "old-" + strconv.FormatInt(old, 10)}).Err(); err != nil {
+		t.Fatalf("ZAdd failed: %v", err)
+	}
+	if check, _ := service.Check(ctx, "fp-a", ip, expiring); check.Purchases != 0 || check.AlreadyPurchased {
+		t.Errorf("Expected purchase outside window to be ignored, got %+v", check)
+	}
+	if err := service.Record(ctx, "fp-a", ip, expiring, "user-1"); err != nil {
+		t.Errorf("Expected purchase after window to be allowed, got %v", err)
+	}
+	if count := client.ZCard(ctx, key).Val(); count != 1 {
+		t.Errorf("Expected expired purchase to be removed, got %d entries", count)
+	}
+
+	// 上限為 0 表示不限制，也不保存紀錄
+	unlimited := createFingerprintTicketType(t, db, uuid.New(), 0, models.FingerprintScopeTicketType)
+	for i := 0; i < 5; i++ {
+		if err := service.Record(ctx, "fp-a", ip, unlimited, "user-1"); err != nil {
+			t.Fatalf("Expected unlimited ticket type to allow purchase %d, got %v", i, err)
+		}
+	}
+	if check, _ := service.Check(ctx, "fp-a", ip, unlimited); check.Remaining != -1 || check.AlreadyPurchased {
+		t.Errorf("Expected unlimited policy, got %+v", check)
+	}
+	if n := client.Exists(ctx, "fingerprint:ticket_type:"+unlimited+":fp-a").Val(); n != 0 {
+		t.Error("Expected no purchase record for unlimited ticket type")
+	}
+}
+
+func TestFingerprintScope(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	service, err := services.NewFingerprintService(db, client, &config.Config{})
+	if err != nil {
+		t.Fatalf("NewFingerprintService failed: %v", err)
+	}
+	const ip = "198.51.100.1"
+
+	// 依票種計算時，同一活動的不同票種各自計數
+	eventID := uuid.New()
+	first := createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeTicketType)
+	second := createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeTicketType)
+	if err := service.Record(ctx, "fp-a", ip, first, "user-1"); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := service.Record(ctx, "fp-a", ip, second, "user-1"); err != nil {
+		t.Errorf("Expected ticket_type scope to count each ticket type separately, got %v", err)
+	}
+
+	// 依活動計算時，同一活動的所有票種合併計數
+	eventID = uuid.New()
+	first = createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeEvent)
+	second = createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeEvent)
+	if err := service.Record(ctx, "fp-a", ip, first, "user-1"); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := service.Record(ctx, "fp-a", ip, second, "user-1"); err == nil {
+		t.Error("Expected event scope to share the limit across ticket types")
+	}
+	if check, _ := service.Check(ctx, "fp-a", ip, second); !check.AlreadyPurchased {
+		t.Errorf("Expected event-scoped check to include other ticket types, got %+v", check)
+	}
+}
+
+func TestFingerprintRecordPurchase(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	service, err := services.NewFingerprintService(db, client, &config.Config{})
+	if err != nil {
+		t.Fatalf("NewFingerprintService failed: %v", err)
+	}
+	const ip = "198.51.100.1"
+
+	// 依活動計算時，同一訂單中同一活動的多個票種只算一次購買
+	eventID := uuid.New()
+	first := createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeEvent)
+	second := createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeEvent)
+	if err := service.RecordPurchase(ctx, "fp-a", ip, []string{first, second}, "user-1"); err != nil {
+		t.Fatalf("RecordPurchase failed: %v", err)
+	}
+	if count := client.ZCard(ctx, "fingerprint:event:"+eventID.String()+":fp-a").Val(); count != 1 {
+		t.Errorf("Expected one purchase per event, got %d", count)
+	}
+
+	// 依票種計算時各自記錄
+	eventID = uuid.New()
+	first = createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeTicketType)
+	second = createFingerprintTicketType(t, db, eventID, 1, models.FingerprintScopeTicketType)
+	if err := service.RecordPurchase(ctx, "fp-a", ip, []string{first, second}, "user-1"); err != nil {
+		t.Fatalf("RecordPurchase failed: %v", err)
+	}
+	for _, id := range []string{first, second} {
+		if check, _ := service.Check(ctx, "fp-a", ip, id); check.Purchases != 1 {
+			t.Errorf("Expected ticket type %s to be recorded once, got %+v", id, check)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+)
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": "550e8400-e29b-41d4-a716-446655440000",
+		"typ":     "access",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestKeySetSignAndParse(t *testing.T) {
+	key, err := jwtkeys.GenerateEd25519("ed-1")
+	if err != nil {
+		t.Fatalf("GenerateEd25519 returned error: %v", err)
+	}
+	keys, err := jwtkeys.NewKeySet("ed-1", key)
+	if err != nil {
+		t.Fatalf("NewKeySet returned error: %v", err)
+	}
+
+	signed, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := keys.Parse(signed, claims)
+	if err != nil || !token.Valid {
+		t.Fatalf("Expected token to validate, got %v", err)
+	}
+	if token.Header["kid"] != "ed-1" || token.Header["alg"] != "EdDSA" {
+		t.Errorf("Unexpected token header: %v", token.Header)
+	}
+	if claims["user_id"] != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("Unexpected claims: %v", claims)
+	}
+
+	// 以 HS256 偽造的令牌應被拒絕
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = "ed-1"
+	forgedString, _ := forged.SignedString([]byte("your-secret-key"))
+	if _, err := keys.Parse(forgedString, jwt.MapClaims{}); err == nil {
+		t.Error("Expected HS256 token to be rejected")
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, _ := jwtkeys.GenerateEd25519("2024-01")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	newKey, err := jwtkeys.NewKey("2024-07", rsaKey)
+	if err != nil {
+		t.Fatalf("NewKey returned error: %v", err)
+	}
+
+	before, _ := jwtkeys.NewKeySet("2024-01", oldKey, newKey)
+	oldToken, _ := before.Sign(testClaims())
+
+	// 切換啟用金鑰後，舊金鑰簽發的令牌仍可驗證，新令牌改用 RS256
+	after, _ := jwtkeys.NewKeySet("2024-07", oldKey, newKey)
+	if _, err := after.Parse(oldToken, jwt.MapClaims{}); err != nil {
+		t.Errorf("Expected token signed with previous key to validate, got %v", err)
+	}
+	newToken, _ := after.Sign(testClaims())
+	token, err := after.Parse(newToken, jwt.MapClaims{})
+	if err != nil || token.Header["alg"] != "RS256" || token.Header["kid"] != "2024-07" {
+		t.Errorf("Expected RS256 token with new kid, got %v (%v)", token, err)
+	}
+
+	// 移除舊金鑰後，其簽發的令牌即失效
+	retired, _ := jwtkeys.NewKeySet("2024-07", newKey)
+	if _, err := retired.Parse(oldToken, jwt.MapClaims{}); err == nil {
+		t.Error("Expected token signed with removed key to be rejected")
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("Expected 2 keys in JWKS, got %d", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kid != "2024-01" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Crv != "Ed25519" || jwks.Keys[0].X == "" {
+		t.Errorf("Unexpected Ed25519 JWK: %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kid != "2024-07" || jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].N == "" || jwks.Keys[1].E != "AQAB" {
+		t.Errorf("Unexpected RSA JWK: %+v", jwks.Keys[1])
+	}
+}
+
+func TestKeySetLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaDER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	writePEM(t, filepath.Join(dir, "rsa-1.pem"), "PRIVATE KEY", rsaDER)
+
+	// 已退役金鑰只保留公鑰
+	retiredPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	retiredDER, _ := x509.MarshalPKIXPublicKey(retiredPub)
+	writePEM(t, filepath.Join(dir, "ed-0.pem"), "PUBLIC KEY", retiredDER)
+
+	keys, err := jwtkeys.LoadDir(dir, "")
+	if err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+	if keys.ActiveKeyID() != "rsa-1" {
+		t.Errorf("Expected the only private key to be active, got %q", keys.ActiveKeyID())
+	}
+	if len(keys.JWKS().Keys) != 2 {
+		t.Errorf("Expected public-only key to be published, got %d keys", len(keys.JWKS().Keys))
+	}
+
+	// 僅有公鑰的金鑰不能用於簽章
+	if _, err := jwtkeys.LoadDir(dir, "ed-0"); err == nil {
+		t.Error("Expected public-only key to be rejected as the active key")
+	}
+
+	// 多把私鑰時必須指定啟用金鑰
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPriv)
+	writePEM(t, filepath.Join(dir, "ed-1.pem"), "PRIVATE KEY", edDER)
+	if _, err := jwtkeys.LoadDir(dir, ""); err == nil {
+		t.Error("Expected an error when several private keys exist without an active key ID")
+	}
+	keys, err = jwtkeys.LoadDir(dir, "ed-1")
+	if err != nil || keys.ActiveKeyID() != "ed-1" {
+		t.Errorf("Expected ed-1 to be active, got %v", err)
+	}
+}
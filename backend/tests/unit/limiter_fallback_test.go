@@ -0,0 +1,184 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := limiter.NewMemoryLimiter(1)
+
+	for i := 0; i < 3; i++ {
+		if allowed, remaining, _, err := l.Allow(ctx, "user", 3, 100*time.Millisecond); err != nil || !allowed || remaining != 2-i {
+			t.Errorf("Request %d: allowed=%v remaining=%d err=%v", i, allowed, remaining, err)
+		}
+	}
+	allowed, _, retryAfter, _ := l.Allow(ctx, "user", 3, 100*time.Millisecond)
+	if allowed || retryAfter <= 0 {
+		t.Errorf("Expected request over limit to be rejected, got allowed=%v retryAfter=%v", allowed, retryAfter)
+	}
+
+	// 補滿後與新的鍵相同
+	time.Sleep(110 * time.Millisecond)
+	if allowed, remaining, _, _ := l.Allow(ctx, "user", 3, 100*time.Millisecond); !allowed || remaining != 2 {
+		t.Errorf("Expected bucket to be refilled, got allowed=%v remaining=%d", allowed, remaining)
+	}
+
+	// 多台服務器時每台只分得部分額度
+	shared := limiter.NewMemoryLimiter(4)
+	for i := 0; i < 3; i++ {
+		allowed, _, _, _ := shared.Allow(ctx, "user", 10, time.Minute)
+		if want := i < 2; allowed != want {
+			t.Errorf("Shared request %d: allowed=%v, want %v", i, allowed, want)
+		}
+	}
+
+	if _, _, _, err := l.Allow(ctx, "user", 0, time.Minute); err == nil {
+		t.Error("Expected error for invalid limit")
+	}
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	primary, err := limiter.New(limiter.StrategyFixedWindow, client, "test")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	breaker := limiter.NewBreaker(2, 50*time.Millisecond)
+	l := limiter.NewFallbackLimiter(primary, limiter.NewMemoryLimiter(1), breaker)
+
+	// 設定錯誤不代表 Redis 故障，不計入斷路器
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := l.Allow(ctx, "user", 0, time.Minute); err == nil {
+			t.Fatal("Expected error for invalid limit")
+		}
+	}
+	if breaker.State() != limiter.CircuitClosed {
+		t.Fatalf("Expected validation errors not to open circuit, got %s", breaker.State())
+	}
+
+	if allowed, _, _, err := l.Allow(ctx, "user", 2, time.Minute); err != nil || !allowed {
+		t.Fatalf("Expected Redis to count request, got allowed=%v err=%v", allowed, err)
+	}
+
+	// Redis 故障時改用程序內計數，仍會拒絕超量請求
+	mr.SetError("模擬 Redis 故障")
+	for i := 0; i < 3; i++ {
+		allowed, _, _, err := l.Allow(ctx, "user", 2, time.Minute)
+		if err != nil {
+			t.Fatalf("Expected fallback not to return error, got %v", err)
+		}
+		if want := i < 2; allowed != want {
+			t.Errorf("Fallback request %d: allowed=%v, want %v", i, allowed, want)
+		}
+	}
+	if breaker.State() != limiter.CircuitOpen {
+		t.Fatalf("Expected circuit to open after failures, got %s", breaker.State())
+	}
+
+	// 冷卻期間的探測失敗會再次開路
+	time.Sleep(60 * time.Millisecond)
+	l.Allow(ctx, "other", 2, time.Minute)
+	if breaker.State() != limiter.CircuitOpen {
+		t.Errorf("Expected failed probe to reopen circuit, got %s", breaker.State())
+	}
+
+	// Redis 恢復後，冷卻時間過後的探測成功即切回
+	mr.SetError("")
+	l.Allow(ctx, "other", 2, time.Minute)
+	if breaker.State() != limiter.CircuitOpen {
+		t.Errorf("Expected circuit to stay open during cooldown, got %s", breaker.State())
+	}
+	// 故障前 Redis 已計數一次
+	time.Sleep(60 * time.Millisecond)
+	if allowed, remaining, _, err := l.Allow(ctx, "user", 2, time.Minute); err != nil || !allowed || remaining != 0 {
+		t.Errorf("Expected Redis count to resume, got allowed=%v remaining=%d err=%v", allowed, remaining, err)
+	}
+	if breaker.State() != limiter.CircuitClosed {
+		t.Errorf("Expected circuit to close after recovery, got %s", breaker.State())
+	}
+}
+
+func TestRateLimitServiceMode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	path := filepath.Join(t.TempDir(), "rate_limits.yaml")
+	policies := "policies:\n  - name: ip\n    key: ip\n    limit: 1\n    window: 1m\n"
+	if err := os.WriteFile(path, []byte(policies), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	service, err := services.NewRateLimitService(client, &config.Config{
+		RateLimitPolicyFile:             path,
+		RateLimitBreakerThreshold:       1,
+		RateLimitBreakerCooldownSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("NewRateLimitService failed: %v", err)
+	}
+	if service.Mode() != services.RateLimitModeRedis {
+		t.Errorf("Expected redis mode, got %s", service.Mode())
+	}
+
+	mr.SetError("模擬 Redis 故障")
+	ctx := context.Background()
+	req := dto.RateLimitRequest{Route: "/api/v1/events", Method: "GET", IPAddress: "203.0.113.7"}
+	if decision := service.Check(ctx, req, nil); !decision.Allowed {
+		t.Errorf("Expected first request to be allowed, got %+v", decision)
+	}
+	if service.Mode() != services.RateLimitModeMemory {
+		t.Errorf("Expected memory mode after Redis failure, got %s", service.Mode())
+	}
+	if decision := service.Check(ctx, req, nil); decision.Allowed {
+		t.Error("Expected memory limiter to reject request over limit")
+	}
+}
+
+func TestRateLimitServiceSharedLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	service, err := services.NewRateLimitService(client, &config.Config{
+		RateLimitBreakerThreshold:       1,
+		RateLimitBreakerCooldownSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("NewRateLimitService failed: %v", err)
+	}
+
+	// 其他服務的計數與限流政策共用斷路器
+	ctx := context.Background()
+	mr.SetError("模擬 Redis 故障")
+	mfa := service.Limiter("mfa_attempt")
+	if allowed, _, _, err := mfa.Allow(ctx, "user", 1, time.Minute); err != nil || !allowed {
+		t.Fatalf("Expected fallback to allow first attempt, got allowed=%v err=%v", allowed, err)
+	}
+	if service.Mode() != services.RateLimitModeMemory {
+		t.Errorf("Expected memory mode after Redis failure, got %s", service.Mode())
+	}
+	if allowed, _, _, _ := service.Limiter("mfa_attempt").Allow(ctx, "user", 1, time.Minute); allowed {
+		t.Error("Expected memory limiter to reject attempt over limit")
+	}
+
+	// 不同前綴的計數互不影響
+	if allowed, _, _, _ := service.Limiter("password_reset").Allow(ctx, "user", 1, time.Minute); !allowed {
+		t.Error("Expected other prefix to be counted separately")
+	}
+}
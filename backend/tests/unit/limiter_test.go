@@ -0,0 +1,231 @@
+package unit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+var limiterStrategies = []string{limiter.StrategyFixedWindow, limiter.StrategySlidingWindow, limiter.StrategyTokenBucket}
+
+// newTestLimiter 以 miniredis 創建限制器；advance 同時推進 Redis 伺服器時間與 key 的過期時間
+func newTestLimiter(t testing.TB, strategy string) (limiter.Limiter, func(time.Duration)) {
+	mr := miniredis.RunT(t)
+	now := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	mr.SetTime(now)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	l, err := limiter.New(strategy, client, "test")
+	if err != nil {
+		t.Fatalf("New(%q) failed: %v", strategy, err)
+	}
+	return l, func(d time.Duration) {
+		now = now.Add(d)
+		mr.SetTime(now)
+		mr.FastForward(d)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	ctx := context.Background()
+	for _, strategy := range limiterStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			l, advance := newTestLimiter(t, strategy)
+
+			for i := 0; i < 5; i++ {
+				allowed, remaining, _, err := l.Allow(ctx, "user", 5, time.Minute)
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if !allowed || remaining != 4-i {
+					t.Errorf("Request %d: allowed=%v remaining=%d, want true %d", i, allowed, remaining, 4-i)
+				}
+			}
+
+			allowed, remaining, retryAfter, err := l.Allow(ctx, "user", 5, time.Minute)
+			if err != nil {
+				t.Fatalf("Allow failed: %v", err)
+			}
+			if allowed || remaining != 0 {
+				t.Errorf("Expected request over limit to be rejected, got allowed=%v remaining=%d", allowed, remaining)
+			}
+			if retryAfter <= 0 || retryAfter > time.Minute {
+				t.Errorf("Unexpected retry after: %v", retryAfter)
+			}
+
+			// 其他鍵不受影響
+			if allowed, _, _, _ := l.Allow(ctx, "other", 5, time.Minute); !allowed {
+				t.Error("Expected other key to be allowed")
+			}
+
+			// 等待建議的時間後可再次通過
+			advance(retryAfter)
+			if allowed, _, _, _ := l.Allow(ctx, "user", 5, time.Minute); !allowed {
+				t.Errorf("Expected request to be allowed after %v", retryAfter)
+			}
+
+			if err := l.Reset(ctx, "user"); err != nil {
+				t.Fatalf("Reset failed: %v", err)
+			}
+			if _, remaining, _, _ := l.Allow(ctx, "user", 5, time.Minute); remaining != 4 {
+				t.Errorf("Expected full limit after reset, got remaining=%d", remaining)
+			}
+		})
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	ctx := context.Background()
+	for _, strategy := range limiterStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			l, _ := newTestLimiter(t, strategy)
+
+			var allowedCount int64
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					allowed, _, _, err := l.Allow(ctx, "user", 10, time.Minute)
+					if err != nil {
+						t.Errorf("Allow failed: %v", err)
+					}
+					if allowed {
+						atomic.AddInt64(&allowedCount, 1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allowedCount != 10 {
+				t.Errorf("Expected exactly 10 concurrent requests to pass, got %d", allowedCount)
+			}
+		})
+	}
+}
+
+// TestLimiterWindowBoundary 固定窗口在交界處 2 秒內可通過近兩倍的請求，滑動窗口與令牌桶則不會
+func TestLimiterWindowBoundary(t *testing.T) {
+	ctx := context.Background()
+	want := map[string]int{
+		limiter.StrategyFixedWindow:   19,
+		limiter.StrategySlidingWindow: 10,
+		limiter.StrategyTokenBucket:   10,
+	}
+	for _, strategy := range limiterStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			l, advance := newTestLimiter(t, strategy)
+
+			burst := func() int {
+				count := 0
+				for i := 0; i < 10; i++ {
+					if allowed, _, _, _ := l.Allow(ctx, "user", 10, time.Minute); allowed {
+						count++
+					}
+				}
+				return count
+			}
+
+			// 第一個請求開始計算窗口，之後在窗口結尾與下一個窗口開頭各送出 10 次，間隔 2 秒
+			l.Allow(ctx, "user", 10, time.Minute)
+			advance(58 * time.Second)
+			total := burst()
+			advance(2 * time.Second)
+			total += burst()
+
+			if total != want[strategy] {
+				t.Errorf("Expected %d requests around window boundary, got %d", want[strategy], total)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	ctx := context.Background()
+	l, advance := newTestLimiter(t, limiter.StrategyTokenBucket)
+
+	for i := 0; i < 10; i++ {
+		l.Allow(ctx, "user", 10, 10*time.Second)
+	}
+	allowed, _, retryAfter, _ := l.Allow(ctx, "user", 10, 10*time.Second)
+	if allowed || retryAfter != time.Second {
+		t.Errorf("Expected rejection with 1s retry, got allowed=%v retry=%v", allowed, retryAfter)
+	}
+
+	// 每秒補充 1 個令牌
+	advance(3 * time.Second)
+	for i := 0; i < 3; i++ {
+		if allowed, _, _, _ := l.Allow(ctx, "user", 10, 10*time.Second); !allowed {
+			t.Errorf("Expected refilled token %d to be allowed", i)
+		}
+	}
+	if allowed, _, _, _ := l.Allow(ctx, "user", 10, 10*time.Second); allowed {
+		t.Error("Expected bucket to be empty again")
+	}
+}
+
+func TestLimiterRemaining(t *testing.T) {
+	ctx := context.Background()
+	limiters := map[string]limiter.Limiter{"memory": limiter.NewMemoryLimiter(1)}
+	for _, strategy := range limiterStrategies {
+		limiters[strategy], _ = newTestLimiter(t, strategy)
+	}
+
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			if remaining, err := l.Remaining(ctx, "user", 3, time.Minute); err != nil || remaining != 3 {
+				t.Fatalf("Expected unused key to have full limit, got remaining=%d err=%v", remaining, err)
+			}
+
+			// 查詢不計數
+			for i := 0; i < 2; i++ {
+				l.Allow(ctx, "user", 3, time.Minute)
+			}
+			for i := 0; i < 2; i++ {
+				if remaining, err := l.Remaining(ctx, "user", 3, time.Minute); err != nil || remaining != 1 {
+					t.Errorf("Expected remaining=1 after two requests, got remaining=%d err=%v", remaining, err)
+				}
+			}
+
+			l.Allow(ctx, "user", 3, time.Minute)
+			if remaining, _ := l.Remaining(ctx, "user", 3, time.Minute); remaining != 0 {
+				t.Errorf("Expected limit to be exhausted, got remaining=%d", remaining)
+			}
+		})
+	}
+}
+
+func TestLimiterInvalid(t *testing.T) {
+	if _, err := limiter.New("leaky_bucket", nil, "test"); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+
+	l, _ := newTestLimiter(t, limiter.StrategyTokenBucket)
+	if _, _, _, err := l.Allow(context.Background(), "user", 0, time.Minute); err == nil {
+		t.Error("Expected error for zero limit")
+	}
+}
+
+func BenchmarkLimiter(b *testing.B) {
+	ctx := context.Background()
+	for _, strategy := range limiterStrategies {
+		b.Run(strategy, func(b *testing.B) {
+			l, _ := newTestLimiter(b, strategy)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := l.Allow(ctx, "user:"+strconv.Itoa(i%100), 1000, time.Minute); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+)
+
+func TestLoginProgressiveDelayAndLockout(t *testing.T) {
+	mr, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test"}
+
+	// 電子郵件大小寫與空白不同時，仍視為同一帳號計數
+	wrong := dto.LoginRequest{Email: "  User@Example.com ", Password: "wrong-password"}
+	login := func(req dto.LoginRequest) error {
+		_, err := service.Login(req, info)
+		return err
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := login(wrong); err == nil || err.Error() != "電子郵件或密碼不正確" {
+			t.Fatalf("Failure %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	if ttl := mr.TTL("login_delay:user@example.com"); ttl != time.Second {
+		t.Fatalf("Expected 1s delay after 3 failures, got %v", ttl)
+	}
+
+	// 延遲期間即使密碼正確也拒絕
+	if err := login(dto.LoginRequest{Email: user.Email, Password: "password123"}); err == nil || err.Error() != "登入失敗次數過多，請稍後再試" {
+		t.Fatalf("Expected login to be throttled during delay, got %v", err)
+	}
+
+	// 每次失敗延遲加倍
+	for i := 4; i <= 9; i++ {
+		mr.FastForward(loginDelayFor(i - 1))
+		if err := login(wrong); err == nil || err.Error() != "電子郵件或密碼不正確" {
+			t.Fatalf("Failure %d: expected invalid credentials, got %v", i, err)
+		}
+		if ttl := mr.TTL("login_delay:user@example.com"); ttl != loginDelayFor(i) {
+			t.Errorf("Failure %d: expected delay %v, got %v", i, loginDelayFor(i), ttl)
+		}
+	}
+
+	// 第 10 次失敗鎖定帳號並通知擁有者
+	mr.FastForward(loginDelayFor(9))
+	if err := login(wrong); err == nil || err.Error() != "電子郵件或密碼不正確" {
+		t.Fatalf("Failure 10: expected invalid credentials, got %v", err)
+	}
+	if !mr.Exists("login_lock:account:user@example.com") {
+		t.Fatal("Expected account to be locked after 10 failures")
+	}
+	if messages := service.Mailer.(*mailer.MemoryMailer).Messages(); len(messages) != 1 || messages[0].To != user.Email {
+		t.Errorf("Expected lock notification to be sent, got %+v", messages)
+	}
+	if err := login(dto.LoginRequest{Email: user.Email, Password: "password123"}); err == nil || err.Error() != "登入失敗次數過多，請稍後再試" {
+		t.Fatalf("Expected locked account to be rejected, got %v", err)
+	}
+
+	// 鎖定期滿後可正常登入，並清除失敗次數
+	mr.FastForward(15 * time.Minute)
+	if err := login(dto.LoginRequest{Email: "USER@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Expected login after lockout to succeed, got %v", err)
+	}
+	if mr.Exists("login_failure:account:user@example.com") {
+		t.Error("Expected failure count to be reset after successful login")
+	}
+}
+
+func TestLoginLockoutByIP(t *testing.T) {
+	mr, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test"}
+
+	// 不存在的帳號同樣計入 IP 失敗次數；每次換帳號以避開帳號延遲
+	for i := 0; i < 50; i++ {
+		req := dto.LoginRequest{Email: fmt.Sprintf("missing%d@example.com", i), Password: "wrong-password"}
+		if _, err := service.Login(req, info); err == nil || err.Error() != "電子郵件或密碼不正確" {
+			t.Fatalf("Failure %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	if !mr.Exists("login_lock:ip:203.0.113.7") {
+		t.Fatal("Expected IP to be locked after 50 failures")
+	}
+	if _, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, info); err == nil {
+		t.Error("Expected login from locked IP to be rejected")
+	}
+	if _, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, dto.ClientInfo{IPAddress: "198.51.100.1"}); err != nil {
+		t.Errorf("Expected login from another IP to succeed, got %v", err)
+	}
+}
+
+// loginDelayFor 第 n 次失敗後的延遲：1 秒起每次加倍，最多 30 秒
+func loginDelayFor(failures int) time.Duration {
+	delay := time.Second << uint(failures-3)
+	if delay > 30*time.Second {
+		return 30 * time.Second
+	}
+	return delay
+}
+
+func TestLoginLockoutWhileRedisDown(t *testing.T) {
+	mr, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+	rateLimits, err := services.NewRateLimitService(client, &config.Config{
+		RateLimitBreakerThreshold:       1,
+		RateLimitBreakerCooldownSeconds: 60,
+		RateLimitFallbackInstances:      1,
+	})
+	if err != nil {
+		t.Fatalf("NewRateLimitService failed: %v", err)
+	}
+	service.RateLimits = rateLimits
+	mr.Close()
+
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test"}
+	login := func(email string, password string) error {
+		_, err := service.Login(dto.LoginRequest{Email: email, Password: password}, info)
+		return err
+	}
+
+	// Redis 停止時失敗次數與延遲改由程序內計數，仍然生效
+	for i := 1; i <= 3; i++ {
+		if err := login(user.Email, "wrong-password"); err == nil || err.Error() != "電子郵件或密碼不正確" {
+			t.Fatalf("Failure %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	if err := login(user.Email, "password123"); err == nil || err.Error() != "登入失敗次數過多，請稍後再試" {
+		t.Fatalf("Expected login to be throttled during delay, got %v", err)
+	}
+	if rateLimits.Mode() != "memory" {
+		t.Errorf("Expected rate limits to fall back to memory, got %s", rateLimits.Mode())
+	}
+
+	// IP 鎖定同樣由程序內計數，前面 3 次失敗已計入
+	for i := 0; i < 47; i++ {
+		if err := login(fmt.Sprintf("missing%d@example.com", i), "wrong-password"); err == nil || err.Error() != "電子郵件或密碼不正確" {
+			t.Fatalf("Failure %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	if err := login("missing0@example.com", "wrong-password"); err == nil || err.Error() != "登入失敗次數過多，請稍後再試" {
+		t.Errorf("Expected login from locked IP to be rejected, got %v", err)
+	}
+}
+
+func TestLoginFailsClosedWhenRedisDown(t *testing.T) {
+	mr, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+	mr.Close()
+
+	// 未設定程序內備援時無法確認鎖定狀態，即使密碼正確也拒絕登入
+	_, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, dto.ClientInfo{IPAddress: "203.0.113.7"})
+	if err == nil || err.Error() != "登入服務暫時無法使用，請稍後再試" {
+		t.Fatalf("Expected login to be unavailable while Redis is down, got %v", err)
+	}
+}
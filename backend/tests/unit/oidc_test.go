@@ -0,0 +1,309 @@
+package unit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lipeichen/ticket-getter/pkg/oidc"
+)
+
+// standInOIDC 本地模擬的 OIDC 身分提供者，支援授權碼流程與 PKCE
+type standInOIDC struct {
+	server       *httptest.Server
+	clientID     string
+	clientSecret string
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	signKID  string
+	hidden   string // 不公布於 JWKS 的金鑰
+	audience string
+	grants   map[string]standInGrant
+}
+
+type standInGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+func newStandInOIDC(t *testing.T) *standInOIDC {
+	t.Helper()
+
+	op := &standInOIDC{
+		clientID:     "ticket-getter",
+		clientSecret: "stand-in-secret",
+		keys:         map[string]*rsa.PrivateKey{},
+		grants:       map[string]standInGrant{},
+	}
+	op.addKey(t, "key-1")
+	op.signKID = "key-1"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 op.server.URL,
+			"authorization_endpoint": op.server.URL + "/authorize",
+			"token_endpoint":         op.server.URL + "/token",
+			"jwks_uri":               op.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", op.authorize)
+	mux.HandleFunc("/token", op.token)
+	mux.HandleFunc("/jwks", op.jwks)
+
+	op.server = httptest.NewServer(mux)
+	t.Cleanup(op.server.Close)
+	return op
+}
+
+func (op *standInOIDC) addKey(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	op.mu.Lock()
+	op.keys[kid] = key
+	op.mu.Unlock()
+}
+
+// authorize 模擬用戶同意授權，直接導回 redirect_uri 並附上授權碼
+func (op *standInOIDC) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != op.clientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := base64.RawURLEncoding.EncodeToString([]byte(q.Get("state")))
+	op.mu.Lock()
+	op.grants[code] = standInGrant{
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	op.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token 驗證授權碼與 PKCE 驗證碼後簽發 ID Token
+func (op *standInOIDC) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	if r.PostForm.Get("client_id") != op.clientID || r.PostForm.Get("client_secret") != op.clientSecret {
+		fail("invalid_client")
+		return
+	}
+
+	op.mu.Lock()
+	grant, ok := op.grants[r.PostForm.Get("code")]
+	delete(op.grants, r.PostForm.Get("code"))
+	op.mu.Unlock()
+	if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") {
+		fail("invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "stand-in-access-token",
+		"token_type":   "Bearer",
+		"id_token":     op.idToken(grant.nonce),
+	})
+}
+
+func (op *standInOIDC) idToken(nonce string) string {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	audience := op.audience
+	if audience == "" {
+		audience = op.clientID
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            op.server.URL,
+		"sub":            "stand-in-user-1",
+		"aud":            audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "fan@example.com",
+		"email_verified": true,
+		"name":           "Stand-in Fan",
+	})
+	token.Header["kid"] = op.signKID
+	signed, _ := token.SignedString(op.keys[op.signKID])
+	return signed
+}
+
+func (op *standInOIDC) jwks(w http.ResponseWriter, r *http.Request) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	keys := []map[string]string{}
+	for kid, key := range op.keys {
+		if kid == op.hidden {
+			continue
+		}
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// login 執行一次完整的授權流程，返回回呼收到的授權碼與 state
+func (op *standInOIDC) login(t *testing.T, provider oidc.Provider, nonce, verifier string) (string, string) {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-123", nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL returned error: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Authorization request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from authorization endpoint, got %d", resp.StatusCode)
+	}
+
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func (op *standInOIDC) provider() *oidc.GenericProvider {
+	return oidc.NewGenericProvider(oidc.ProviderConfig{
+		Name:         "stand-in",
+		Issuer:       op.server.URL,
+		ClientID:     op.clientID,
+		ClientSecret: op.clientSecret,
+		RedirectURL:  "http://localhost:3000/auth/callback/stand-in",
+	}, op.server.Client())
+}
+
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	op := newStandInOIDC(t)
+	provider := op.provider()
+
+	code, state := op.login(t, provider, "nonce-abc", "verifier-abc")
+	if state != "state-123" {
+		t.Errorf("Expected state to round-trip, got %q", state)
+	}
+
+	identity, err := provider.Authenticate(context.Background(), code, "verifier-abc", "nonce-abc")
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if identity.Provider != "stand-in" || identity.Subject != "stand-in-user-1" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if identity.Email != "fan@example.com" || !identity.EmailVerified || identity.Name != "Stand-in Fan" {
+		t.Errorf("Unexpected identity claims: %+v", identity)
+	}
+
+	// 授權碼只能使用一次
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-abc", "nonce-abc"); err == nil {
+		t.Error("Expected reused authorization code to be rejected")
+	}
+}
+
+func TestOIDCRejectsInvalidResponses(t *testing.T) {
+	op := newStandInOIDC(t)
+	provider := op.provider()
+
+	// PKCE 驗證碼不符
+	code, _ := op.login(t, provider, "nonce-1", "verifier-1")
+	if _, err := provider.Authenticate(context.Background(), code, "another-verifier", "nonce-1"); err == nil {
+		t.Error("Expected mismatched code verifier to be rejected")
+	}
+
+	// nonce 不符
+	code, _ = op.login(t, provider, "nonce-2", "verifier-2")
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-2", "nonce-other"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Expected nonce mismatch error, got %v", err)
+	}
+
+	// 受眾不是本應用
+	op.mu.Lock()
+	op.audience = "someone-else"
+	op.mu.Unlock()
+	code, _ = op.login(t, provider, "nonce-3", "verifier-3")
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-3", "nonce-3"); err == nil {
+		t.Error("Expected ID token for another audience to be rejected")
+	}
+	op.mu.Lock()
+	op.audience = ""
+	op.mu.Unlock()
+
+	// 以未公布的金鑰簽章
+	op.addKey(t, "rogue")
+	op.mu.Lock()
+	op.signKID = "rogue"
+	op.hidden = "rogue"
+	op.mu.Unlock()
+	code, _ = op.login(t, provider, "nonce-4", "verifier-4")
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-4", "nonce-4"); err == nil {
+		t.Error("Expected ID token signed with an unpublished key to be rejected")
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	op := newStandInOIDC(t)
+	// 提供者預先公布下一把金鑰
+	op.addKey(t, "key-2")
+	provider := op.provider()
+
+	code, _ := op.login(t, provider, "nonce-1", "verifier-1")
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-1", "nonce-1"); err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+
+	op.mu.Lock()
+	op.signKID = "key-2"
+	op.mu.Unlock()
+	code, _ = op.login(t, provider, "nonce-2", "verifier-2")
+	if _, err := provider.Authenticate(context.Background(), code, "verifier-2", "nonce-2"); err != nil {
+		t.Errorf("Expected ID token signed with rotated key to validate, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+func TestGetUserOrders(t *testing.T) {
+	db := newTestDB(t)
+	orderService := services.NewOrderService(db, services.NewInvoiceService(db, &config.Config{}))
+	user := createPasswordUser(t, db, "user@example.com")
+	other := createPasswordUser(t, db, "other@example.com")
+	ticketType := createTestTicketType(t, db, "演唱會", time.Now().Add(24*time.Hour))
+
+	// 依序 3 筆已付款、2 筆待付款，另有其他用戶的訂單
+	var orderIDs []string
+	for i := 0; i < 3; i++ {
+		orderIDs = append(orderIDs, createPaidOrder(t, orderService, user.ID, ticketType, 1))
+	}
+	for i := 0; i < 2; i++ {
+		order, err := orderService.CreateOrder(user.ID.String(), dto.CreateOrderRequest{
+			Items: []dto.OrderItemRequest{{TicketTypeID: ticketType.ID.String(), Quantity: 1}},
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder failed: %v", err)
+		}
+		orderIDs = append(orderIDs, order.ID.String())
+	}
+	createPaidOrder(t, orderService, other.ID, ticketType, 1)
+
+	// 建立時間由資料庫預設值填入，SQLite 上須自行指定
+	base := time.Now().Add(-time.Hour)
+	for i, id := range orderIDs {
+		db.Model(&models.Order{}).Where("id = ?", id).Update("created_at", base.Add(time.Duration(i)*time.Minute))
+	}
+
+	orders, total, err := orderService.GetUserOrders(user.ID.String(), "", 1, 2)
+	if err != nil {
+		t.Fatalf("GetUserOrders failed: %v", err)
+	}
+	if total != 5 || len(orders) != 2 {
+		t.Fatalf("Expected page of 2 out of 5 orders, got %d of %d", len(orders), total)
+	}
+	// 最新的訂單排在前面
+	if orders[0].ID.String() != orderIDs[4] || orders[1].ID.String() != orderIDs[3] {
+		t.Errorf("Expected newest orders first, got %+v", orders)
+	}
+
+	orders, total, err = orderService.GetUserOrders(user.ID.String(), "", 3, 2)
+	if err != nil {
+		t.Fatalf("GetUserOrders failed: %v", err)
+	}
+	if total != 5 || len(orders) != 1 || orders[0].ID.String() != orderIDs[0] {
+		t.Errorf("Expected last page to hold the oldest order, got %+v", orders)
+	}
+
+	orders, total, err = orderService.GetUserOrders(user.ID.String(), "paid", 1, 10)
+	if err != nil {
+		t.Fatalf("GetUserOrders failed: %v", err)
+	}
+	if total != 3 || len(orders) != 3 {
+		t.Fatalf("Expected 3 paid orders, got %d of %d", len(orders), total)
+	}
+	for _, order := range orders {
+		if order.Status != "paid" {
+			t.Errorf("Expected only paid orders, got %s", order.Status)
+		}
+	}
+
+	orders, total, err = orderService.GetUserOrders(user.ID.String(), "cancelled", 1, 10)
+	if err != nil || total != 0 || len(orders) != 0 {
+		t.Errorf("Expected no cancelled orders, got %d of %d (%v)", len(orders), total, err)
+	}
+}
+
+func TestGetUserOrderDetail(t *testing.T) {
+	db := newTestDB(t)
+	orderService := services.NewOrderService(db, services.NewInvoiceService(db, &config.Config{}))
+	user := createPasswordUser(t, db, "user@example.com")
+	other := createPasswordUser(t, db, "other@example.com")
+	ticketType := createTestTicketType(t, db, "演唱會", time.Now().Add(24*time.Hour))
+	orderID := createPaidOrder(t, orderService, user.ID, ticketType, 2)
+
+	detail, err := orderService.GetUserOrderDetail(orderID, user.ID.String())
+	if err != nil {
+		t.Fatalf("GetUserOrderDetail failed: %v", err)
+	}
+	if len(detail.Items) != 1 || detail.Items[0].EventTitle != "演唱會" || len(detail.Items[0].Tickets) != 2 {
+		t.Errorf("Expected one item with two tickets, got %+v", detail.Items)
+	}
+
+	// 其他用戶的訂單與不存在的訂單回應相同
+	if _, err := orderService.GetUserOrderDetail(orderID, other.ID.String()); err == nil || err.Error() != "訂單不存在" {
+		t.Errorf("Expected another user's order to be not found, got %v", err)
+	}
+	if _, err := orderService.GetUserOrderDetail("not-a-uuid", user.ID.String()); err == nil || err.Error() != "無效的訂單 ID" {
+		t.Errorf("Expected invalid order ID to be rejected, got %v", err)
+	}
+}
+
+func TestGetUserTickets(t *testing.T) {
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	cfg := &config.Config{}
+	orderService := services.NewOrderService(db, services.NewInvoiceService(db, cfg))
+	ticketService := services.NewTicketService(db, client, cfg)
+	user := createPasswordUser(t, db, "user@example.com")
+
+	// 進行中的活動尚未結束，仍列為即將到來
+	createPaidOrder(t, orderService, user.ID, createTestTicketType(t, db, "下週", time.Now().Add(7*24*time.Hour)), 1)
+	createPaidOrder(t, orderService, user.ID, createTestTicketType(t, db, "明天", time.Now().Add(24*time.Hour)), 1)
+	createPaidOrder(t, orderService, user.ID, createTestTicketType(t, db, "進行中", time.Now().Add(-time.Hour)), 1)
+	createPaidOrder(t, orderService, user.ID, createTestTicketType(t, db, "上個月", time.Now().Add(-30*24*time.Hour)), 1)
+	createPaidOrder(t, orderService, user.ID, createTestTicketType(t, db, "昨天", time.Now().Add(-24*time.Hour)), 1)
+
+	tickets, err := ticketService.GetUserTickets(user.ID.String())
+	if err != nil {
+		t.Fatalf("GetUserTickets failed: %v", err)
+	}
+
+	titles := func(list []vo.TicketResponse) []string {
+		result := make([]string, len(list))
+		for i, ticket := range list {
+			result[i] = ticket.EventTitle
+		}
+		return result
+	}
+	// 即將到來依開始時間排序，過往活動以最近的排在前面
+	if got := titles(tickets.Upcoming); !reflect.DeepEqual(got, []string{"進行中", "明天", "下週"}) {
+		t.Errorf("Unexpected upcoming tickets: %v", got)
+	}
+	if got := titles(tickets.Past); !reflect.DeepEqual(got, []string{"昨天", "上個月"}) {
+		t.Errorf("Unexpected past tickets: %v", got)
+	}
+
+	// 其他用戶沒有票券
+	other := createPasswordUser(t, db, "other@example.com")
+	tickets, err = ticketService.GetUserTickets(other.ID.String())
+	if err != nil || len(tickets.Upcoming) != 0 || len(tickets.Past) != 0 {
+		t.Errorf("Expected no tickets for another user, got %+v (%v)", tickets, err)
+	}
+}
@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/controllers"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+)
+
+// requestPasswordReset 申請重設密碼並等待背景寄出的郵件，返回連結中的令牌
+func requestPasswordReset(t *testing.T, service *services.AuthService, email string) string {
+	mail := service.Mailer.(*mailer.MemoryMailer)
+	sent := len(mail.Messages())
+	service.ForgotPassword(dto.ForgotPasswordRequest{Email: email})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if messages := mail.Messages(); len(messages) > sent {
+			return tokenFromLink(t, messages[len(messages)-1].Body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected password reset mail to be sent")
+	return ""
+}
+
+func TestResetPasswordTokenSingleUse(t *testing.T) {
+	_, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+	ctx := context.Background()
+
+	token := requestPasswordReset(t, service, " USER@example.com ")
+	if err := service.ResetPassword(ctx, dto.ResetPasswordRequest{Token: token, Password: "newPassword123"}); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	if _, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "newPassword123"}, dto.ClientInfo{}); err != nil {
+		t.Errorf("Expected login with new password to succeed, got %v", err)
+	}
+
+	if err := service.ResetPassword(ctx, dto.ResetPasswordRequest{Token: token, Password: "anotherPassword123"}); err == nil || err.Error() != "無效或已過期的令牌" {
+		t.Errorf("Expected used token to be rejected, got %v", err)
+	}
+
+	// 新連結寄出後舊連結失效
+	first := requestPasswordReset(t, service, user.Email)
+	second := requestPasswordReset(t, service, user.Email)
+	if err := service.ResetPassword(ctx, dto.ResetPasswordRequest{Token: first, Password: "anotherPassword123"}); err == nil || err.Error() != "無效或已過期的令牌" {
+		t.Errorf("Expected superseded token to be rejected, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, dto.ResetPasswordRequest{Token: second, Password: "anotherPassword123"}); err != nil {
+		t.Errorf("Expected latest token to work, got %v", err)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	_, client := newTestRedis(t)
+	service, db, user := newTestAuthService(t, client, "user@example.com")
+
+	token := requestPasswordReset(t, service, user.Email)
+	db.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	if err := service.ResetPassword(context.Background(), dto.ResetPasswordRequest{Token: token, Password: "newPassword123"}); err == nil || err.Error() != "無效或已過期的令牌" {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+	if _, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, dto.ClientInfo{}); err != nil {
+		t.Errorf("Expected old password to remain valid, got %v", err)
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	_, client := newTestRedis(t)
+	service, db, user := newTestAuthService(t, client, "user@example.com")
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test"}
+
+	var logins []string
+	for i := 0; i < 2; i++ {
+		login, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, info)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		logins = append(logins, login.RefreshToken)
+	}
+
+	token := requestPasswordReset(t, service, user.Email)
+	if err := service.ResetPassword(context.Background(), dto.ResetPasswordRequest{Token: token, Password: "newPassword123"}); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+
+	var active int64
+	db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+	if active != 0 {
+		t.Errorf("Expected all sessions to be revoked, got %d active", active)
+	}
+	for i, refreshToken := range logins {
+		if _, _, err := service.RefreshToken(refreshToken, info); err == nil {
+			t.Errorf("Session %d: expected refresh token to be rejected after reset", i)
+		}
+	}
+}
+
+func TestForgotPasswordSameResponseForUnknownEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, client := newTestRedis(t)
+	service, _, user := newTestAuthService(t, client, "user@example.com")
+
+	router := gin.New()
+	router.POST("/auth/forgot-password", controllers.NewAuthController(service).ForgotPassword)
+	forgot := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/forgot-password", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	known := forgot(user.Email)
+	unknown := forgot("missing@example.com")
+	if known.Code != http.StatusAccepted || unknown.Code != known.Code || unknown.Body.String() != known.Body.String() {
+		t.Errorf("Expected identical responses, got %d %s and %d %s", known.Code, known.Body, unknown.Code, unknown.Body)
+	}
+
+	// 只有已註冊的電子郵件會收到郵件
+	mail := service.Mailer.(*mailer.MemoryMailer)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(mail.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if messages := mail.Messages(); len(messages) != 1 || messages[0].To != user.Email {
+		t.Errorf("Expected one mail to the registered address, got %+v", messages)
+	}
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/pdf"
+)
+
+func TestRenderInvoice(t *testing.T) {
+	invoice := &models.Invoice{
+		ID:            uuid.New(),
+		InvoiceNumber: "INV-2024-000001",
+		Type:          models.InvoiceTypeInvoice,
+		OrderID:       uuid.New(),
+		BuyerName:     "Test Buyer",
+		BuyerEmail:    "buyer@example.com",
+		Subtotal:      4000,
+		FeeAmount:     40,
+		TaxRate:       0.05,
+		TaxAmount:     192.38,
+		TotalAmount:   4040,
+		IssuedAt:      time.Now(),
+		InvoiceItems: []models.InvoiceItem{
+			{Description: "Concert - VIP", Quantity: 2, UnitPrice: 2000, Amount: 4000},
+			{Description: "Service fee", Quantity: 2, UnitPrice: 20, Amount: 40},
+		},
+	}
+	issuer := pdf.Issuer{Name: "Ticker-Getter", TaxID: "12345678"}
+
+	// 測試發票輸出
+	t.Run("Invoice", func(t *testing.T) {
+		content, err := pdf.RenderInvoice("", issuer, invoice, nil)
+		if err != nil {
+			t.Fatalf("RenderInvoice failed: %v", err)
+		}
+		if !bytes.HasPrefix(content, []byte("%PDF-")) {
+			t.Error("Expected PDF header in output")
+		}
+	})
+
+	// 測試折讓單輸出
+	t.Run("CreditNote", func(t *testing.T) {
+		creditNote := *invoice
+		creditNote.InvoiceNumber = "CN-2024-000001"
+		creditNote.Type = models.InvoiceTypeCreditNote
+
+		content, err := pdf.RenderInvoice("", issuer, &creditNote, invoice)
+		if err != nil {
+			t.Fatalf("RenderInvoice failed: %v", err)
+		}
+		if !bytes.HasPrefix(content, []byte("%PDF-")) {
+			t.Error("Expected PDF header in output")
+		}
+	})
+
+	// 字型檔不存在時應退回內建字型
+	t.Run("MissingFont", func(t *testing.T) {
+		if _, err := pdf.RenderInvoice("/nonexistent/font.ttf", issuer, invoice, nil); err != nil {
+			t.Fatalf("Expected fallback to core font, got error: %v", err)
+		}
+	})
+}
+
+func TestRenderTickets(t *testing.T) {
+	event := &models.Event{
+		ID:        uuid.New(),
+		Title:     "Test Concert",
+		Location:  "Taipei Arena",
+		StartTime: time.Now().Add(24 * time.Hour),
+		EndTime:   time.Now().Add(27 * time.Hour),
+	}
+	ticketType := &models.TicketType{ID: uuid.New(), EventID: event.ID, Name: "VIP"}
+
+	var pages []pdf.TicketPage
+	for i := 0; i < 3; i++ {
+		ticket := &models.Ticket{ID: uuid.New(), TicketCode: uuid.New().String()}
+		pages = append(pages, pdf.TicketPage{Ticket: ticket, TicketType: ticketType, Event: event})
+	}
+
+	// 多張票券輸出為多頁
+	content, err := pdf.RenderTickets("", "Ticker-Getter", pages)
+	if err != nil {
+		t.Fatalf("RenderTickets failed: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Error("Expected PDF header in output")
+	}
+	if count := bytes.Count(content, []byte("/Type /Page\n")); count != len(pages) {
+		t.Errorf("Expected %d pages, got %d", len(pages), count)
+	}
+}
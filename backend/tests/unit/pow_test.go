@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/pkg/pow"
+)
+
+func TestProofOfWorkChallenge(t *testing.T) {
+	issuer := pow.NewIssuer([]byte("test-secret"), time.Minute)
+
+	challenge, err := issuer.Issue("user-1", 8)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	solution := pow.Solve(challenge.Token, challenge.Difficulty)
+	verified, err := issuer.Verify(solution, "user-1")
+	if err != nil {
+		t.Fatalf("Expected valid solution, got %v", err)
+	}
+	if verified.Nonce != challenge.Nonce || verified.Difficulty != 8 {
+		t.Errorf("Unexpected challenge: %+v", verified)
+	}
+
+	// 解答綁定用戶，其他用戶無法使用
+	if _, err := issuer.Verify(solution, "user-2"); err != pow.ErrInvalidSolution {
+		t.Errorf("Expected ErrInvalidSolution for another subject, got %v", err)
+	}
+
+	// 未完成工作量的計數器應被拒絕
+	for n := 0; n < 16; n++ {
+		counter := "x" + strconv.Itoa(n)
+		if pow.LeadingZeroBits(challenge.Token, counter) >= challenge.Difficulty {
+			continue
+		}
+		if _, err := issuer.Verify(challenge.Token+":"+counter, "user-1"); err != pow.ErrInvalidSolution {
+			t.Errorf("Expected ErrInvalidSolution for insufficient work, got %v", err)
+		}
+		break
+	}
+
+	// 不同金鑰簽發的挑戰無法通過驗證
+	other := pow.NewIssuer([]byte("other-secret"), time.Minute)
+	if _, err := other.Verify(solution, "user-1"); err != pow.ErrInvalidSolution {
+		t.Errorf("Expected ErrInvalidSolution for different secret, got %v", err)
+	}
+
+	expired := pow.NewIssuer([]byte("test-secret"), -time.Minute)
+	old, _ := expired.Issue("user-1", 0)
+	if _, err := expired.Verify(pow.Solve(old.Token, 0), "user-1"); err != pow.ErrExpiredChallenge {
+		t.Errorf("Expected ErrExpiredChallenge, got %v", err)
+	}
+
+	if _, err := issuer.Issue("user-1", pow.MaxDifficulty+1); err == nil {
+		t.Error("Expected error for difficulty above maximum")
+	}
+}
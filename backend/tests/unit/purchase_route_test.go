@@ -0,0 +1,179 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/cmd/api"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+	"github.com/lipeichen/ticket-getter/pkg/pow"
+	"gorm.io/gorm"
+)
+
+// newPurchaseRouter 以實際的路由設定建立伺服器並登入一個用戶
+func newPurchaseRouter(t *testing.T, emailVerified bool) (*gin.Engine, *gorm.DB, string) {
+	gin.SetMode(gin.TestMode)
+	_, client := newTestRedis(t)
+	authService, db, user := newTestAuthService(t, client, "buyer@example.com")
+	if emailVerified {
+		if err := db.Model(user).Update("email_verified_at", time.Now()).Error; err != nil {
+			t.Fatalf("更新用戶失敗: %v", err)
+		}
+	}
+
+	login, err := authService.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, dto.ClientInfo{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	rateLimitService, err := services.NewRateLimitService(client, &config.Config{})
+	if err != nil {
+		t.Fatalf("NewRateLimitService failed: %v", err)
+	}
+	router := gin.New()
+	api.RegisterRoutes(router.Group("/api/v1"), db, client, authService.SigningKeys, rateLimitService)
+	return router, db, login.Token
+}
+
+// purchase 以 JSON 送出購票請求，solution 為空時不附工作量證明
+func purchase(router *gin.Engine, token string, body string, solution string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	req.Header.Set("Accept-Language", "zh-TW")
+	if solution != "" {
+		req.Header.Set(pow.SolutionHeader, solution)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPurchaseRequiresProofOfWork(t *testing.T) {
+	router, db, token := newPurchaseRouter(t, true)
+
+	ticketType := &models.TicketType{
+		EventID:           uuid.New(),
+		Name:              "全票",
+		Price:             1000,
+		TotalQuantity:     5,
+		AvailableQuantity: 5,
+		SaleStart:         time.Now().Add(-time.Hour),
+		SaleEnd:           time.Now().Add(time.Hour),
+	}
+	if err := db.Create(ticketType).Error; err != nil {
+		t.Fatalf("建立票種失敗: %v", err)
+	}
+	body := `{"items":[{"ticket_type_id":"` + ticketType.ID.String() + `","quantity":2}]}`
+
+	w := purchase(router, token, body, "")
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("Expected purchase without PoW solution to be rejected with 428, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		PoW vo.PoWChallengeResponse `json:"pow"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.PoW.Challenge == "" {
+		t.Fatalf("Expected response to include a new challenge, got %s", w.Body.String())
+	}
+
+	// 附上解答後建立訂單並扣除庫存
+	w = purchase(router, token, body, pow.Solve(response.PoW.Challenge, response.PoW.Difficulty))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected purchase with PoW solution to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored models.TicketType
+	if err := db.First(&stored, ticketType.ID).Error; err != nil || stored.AvailableQuantity != 3 {
+		t.Errorf("Expected available quantity to be 3, got %d (err=%v)", stored.AvailableQuantity, err)
+	}
+
+	// 票種預設每個裝置指紋只能購買一次
+	if w := purchase(router, token, body, solveChallenge(t, router, token, body)); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected repeated purchase from the same device to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// solveChallenge 取得新的工作量證明挑戰並求解
+func solveChallenge(t *testing.T, router *gin.Engine, token string, body string) string {
+	w := purchase(router, token, body, "")
+	var response struct {
+		PoW vo.PoWChallengeResponse `json:"pow"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.PoW.Challenge == "" {
+		t.Fatalf("Expected response to include a new challenge, got %d: %s", w.Code, w.Body.String())
+	}
+	return pow.Solve(response.PoW.Challenge, response.PoW.Difficulty)
+}
+
+func TestEmailVerificationOnlyRequiredForPurchase(t *testing.T) {
+	router, _, token := newPurchaseRouter(t, false)
+
+	body := `{"items":[{"ticket_type_id":"` + uuid.NewString() + `","quantity":1}]}`
+	if w := purchase(router, token, body, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected unverified user to be forbidden from purchasing, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 下載發票等讀取請求不要求電子郵件驗證
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/"+uuid.NewString()+"/invoice", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected invoice request to reach the handler, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPurchaseRequiresCaptcha(t *testing.T) {
+	t.Setenv("CAPTCHA_PROVIDER", "fake")
+	t.Setenv("CAPTCHA_ON_PURCHASE", "true")
+	router, _, token := newPurchaseRouter(t, true)
+
+	body := `{"items":[{"ticket_type_id":"` + uuid.NewString() + `","quantity":1}]}`
+	w := purchase(router, token, body, "")
+	if w.Code != http.StatusPreconditionRequired || !strings.Contains(w.Body.String(), "captcha_required") {
+		t.Errorf("Expected purchase without CAPTCHA token to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFailedPurchaseDoesNotUseFingerprintQuota(t *testing.T) {
+	router, db, token := newPurchaseRouter(t, true)
+
+	ticketType := &models.TicketType{
+		EventID:           uuid.New(),
+		Name:              "全票",
+		Price:             1000,
+		TotalQuantity:     1,
+		AvailableQuantity: 1,
+		SaleStart:         time.Now().Add(-time.Hour),
+		SaleEnd:           time.Now().Add(time.Hour),
+	}
+	if err := db.Create(ticketType).Error; err != nil {
+		t.Fatalf("建立票種失敗: %v", err)
+	}
+	order := func(quantity int) string {
+		return `{"items":[{"ticket_type_id":"` + ticketType.ID.String() + `","quantity":` + strconv.Itoa(quantity) + `}]}`
+	}
+
+	// 數量不足的訂單失敗，不佔用裝置的購買次數
+	if w := purchase(router, token, order(2), solveChallenge(t, router, token, order(2))); w.Code != http.StatusConflict {
+		t.Fatalf("Expected order over available quantity to fail, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := purchase(router, token, order(1), solveChallenge(t, router, token, order(1))); w.Code != http.StatusCreated {
+		t.Fatalf("Expected retry from the same device to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := purchase(router, token, order(1), solveChallenge(t, router, token, order(1))); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected successful order to use the quota, got %d: %s", w.Code, w.Body.String())
+	}
+}
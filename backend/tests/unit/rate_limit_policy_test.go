@@ -0,0 +1,210 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParsePolicyFile(t *testing.T) {
+	data, err := os.ReadFile("../../config/rate_limits.example.yaml")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	file, err := limiter.ParsePolicyFile(data, "rate_limits.yaml")
+	if err != nil {
+		t.Fatalf("ParsePolicyFile failed: %v", err)
+	}
+	if len(file.Policies) == 0 || file.Policies[0].WindowDuration() != time.Minute {
+		t.Errorf("Unexpected policies: %+v", file.Policies)
+	}
+
+	json := `{"policies": [{"name": "login", "routes": ["/api/v1/auth/login"], "methods": ["post"], "key": "ip", "limit": 5, "window": "1m"}]}`
+	file, err = limiter.ParsePolicyFile([]byte(json), "rate_limits.json")
+	if err != nil {
+		t.Fatalf("ParsePolicyFile(json) failed: %v", err)
+	}
+	if !file.Policies[0].Matches("/api/v1/auth/login", "POST", "") {
+		t.Error("Expected lowercase method to be normalized")
+	}
+
+	invalid := []string{
+		"policies:\n  - name: a\n    key: ip\n    limit: 1\n    window: 1m\n    limt: 2\n",
+		"policies:\n  - name: a\n    key: session\n    limit: 1\n    window: 1m\n",
+		"policies:\n  - name: a\n    key: ip\n    limit: 0\n    window: 1m\n",
+		"policies:\n  - name: a\n    key: ip\n    limit: 1\n    window: soon\n",
+		"policies:\n  - name: a\n    key: ip\n    limit: 1\n    window: 1m\n    strategy: leaky\n",
+		"policies:\n  - name: a\n    key: ip\n    limit: 1\n    window: 1m\n  - name: a\n    key: user\n    limit: 1\n    window: 1m\n",
+	}
+	for _, data := range invalid {
+		if _, err := limiter.ParsePolicyFile([]byte(data), "rate_limits.yaml"); err == nil {
+			t.Errorf("Expected error for policy file:\n%s", data)
+		}
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	policy := limiter.Policy{
+		Routes:  []string{"/api/v1/orders/**", "/api/v1/tickets/*/pdf"},
+		Methods: []string{"POST", "GET"},
+		Roles:   []string{"user"},
+	}
+	tests := []struct {
+		route  string
+		method string
+		role   string
+		want   bool
+	}{
+		{"/api/v1/orders", "POST", "user", true},
+		{"/api/v1/orders/:id/invoice", "GET", "user", true},
+		{"/api/v1/orders-export", "GET", "user", false},
+		{"/api/v1/tickets/:id/pdf", "GET", "user", true},
+		{"/api/v1/tickets/:id/wallet/apple", "GET", "user", false},
+		{"/api/v1/orders", "DELETE", "user", false},
+		{"/api/v1/orders", "POST", "admin", false},
+		{"/api/v1/orders", "POST", "", false},
+	}
+	for _, tt := range tests {
+		if got := policy.Matches(tt.route, tt.method, tt.role); got != tt.want {
+			t.Errorf("Matches(%q, %q, %q) = %v, want %v", tt.route, tt.method, tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate_limits.yaml")
+	write := func(limit string, modTime time.Time) {
+		data := "policies:\n  - name: default\n    key: ip\n    limit: " + limit + "\n    window: 1m\n"
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	now := time.Now()
+	write("10", now)
+
+	store, err := limiter.NewPolicyStore(path, &limiter.PolicyFile{})
+	if err != nil {
+		t.Fatalf("NewPolicyStore failed: %v", err)
+	}
+	if store.Current().Policies[0].Limit != 10 {
+		t.Fatalf("Expected policy file to be loaded, got %+v", store.Current())
+	}
+
+	if reloaded, err := store.Reload(); err != nil || reloaded {
+		t.Errorf("Expected unchanged file to be skipped, got reloaded=%v err=%v", reloaded, err)
+	}
+
+	write("20", now.Add(time.Second))
+	if reloaded, err := store.Reload(); err != nil || !reloaded {
+		t.Fatalf("Expected changed file to be reloaded, got reloaded=%v err=%v", reloaded, err)
+	}
+	if store.Current().Policies[0].Limit != 20 {
+		t.Errorf("Expected new limit 20, got %d", store.Current().Policies[0].Limit)
+	}
+
+	// 錯誤的政策不會取代目前的政策
+	write("-1", now.Add(2*time.Second))
+	if _, err := store.Reload(); err == nil {
+		t.Error("Expected invalid policy file to fail")
+	}
+	if store.Current().Policies[0].Limit != 20 {
+		t.Errorf("Expected previous policy to be kept, got %d", store.Current().Policies[0].Limit)
+	}
+
+	if _, err := limiter.NewPolicyStore(filepath.Join(t.TempDir(), "missing.yaml"), &limiter.PolicyFile{}); err == nil {
+		t.Error("Expected error for missing policy file at startup")
+	}
+}
+
+func newTestRateLimitService(t *testing.T, policies string) *services.RateLimitService {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	path := filepath.Join(t.TempDir(), "rate_limits.yaml")
+	if err := os.WriteFile(path, []byte(policies), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	service, err := services.NewRateLimitService(client, &config.Config{RateLimitPolicyFile: path})
+	if err != nil {
+		t.Fatalf("NewRateLimitService failed: %v", err)
+	}
+	return service
+}
+
+func TestRateLimitServiceStages(t *testing.T) {
+	service := newTestRateLimitService(t, `
+policies:
+  - name: ip
+    key: ip
+    limit: 3
+    window: 1m
+  - name: user
+    key: user
+    limit: 2
+    window: 1m
+    message: 購買操作頻率過高，請稍後再試
+`)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		// 認證前只有 IP 政策可計數，用戶政策留待認證後
+		skip := map[string]bool{}
+		anonymous := dto.RateLimitRequest{Route: "/api/v1/orders", Method: "POST", IPAddress: "203.0.113.7"}
+		decision := service.Check(ctx, anonymous, skip)
+		if len(decision.Evaluated) != 1 || decision.Evaluated[0] != "ip" {
+			t.Fatalf("Expected only ip policy before auth, got %v", decision.Evaluated)
+		}
+		skip["ip"] = true
+
+		authenticated := anonymous
+		authenticated.UserID = "user-1"
+		authenticated.Role = "user"
+		decision = service.Check(ctx, authenticated, skip)
+		if len(decision.Evaluated) != 1 || decision.Evaluated[0] != "user" {
+			t.Fatalf("Expected only user policy after auth, got %v", decision.Evaluated)
+		}
+
+		if want := i < 2; decision.Allowed != want {
+			t.Errorf("Request %d: allowed=%v, want %v", i, decision.Allowed, want)
+		}
+		if !decision.Allowed && (decision.Policy != "user" || decision.Message != "購買操作頻率過高，請稍後再試") {
+			t.Errorf("Unexpected rejection: %+v", decision)
+		}
+	}
+
+	// IP 政策在第 4 次拒絕，且每次請求只計數一次
+	decision := service.Check(ctx, dto.RateLimitRequest{Route: "/api/v1/orders", Method: "POST", IPAddress: "203.0.113.7"}, nil)
+	if decision.Allowed || decision.Policy != "ip" || decision.Message != "請求頻率過高，請稍後再試" {
+		t.Errorf("Expected ip policy to reject 4th request, got %+v", decision)
+	}
+}
+
+func TestRateLimitServiceDryRun(t *testing.T) {
+	service := newTestRateLimitService(t, `
+policies:
+  - name: strict
+    key: ip
+    limit: 1
+    window: 1m
+    dry_run: true
+`)
+	ctx := context.Background()
+	req := dto.RateLimitRequest{Route: "/api/v1/events", Method: "GET", IPAddress: "203.0.113.7"}
+
+	for i := 0; i < 3; i++ {
+		if decision := service.Check(ctx, req, nil); !decision.Allowed {
+			t.Errorf("Expected dry-run policy not to reject request %d", i)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/middleware"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/pkg/apikey"
+)
+
+func TestRolePermissions(t *testing.T) {
+	cases := []struct {
+		role       string
+		permission models.Permission
+		want       bool
+	}{
+		{models.RoleAdmin, models.PermissionEventManage, true},
+		{models.RoleOrganizer, models.PermissionEventWrite, true},
+		{models.RoleOrganizer, models.PermissionEventManage, false},
+		{models.RoleOrganizer, models.PermissionOrderRefund, false},
+		{models.RoleGateStaff, models.PermissionTicketUse, true},
+		{models.RoleGateStaff, models.PermissionEventRead, false},
+		{models.RoleFinance, models.PermissionOrderRefund, true},
+		{models.RoleSupport, models.PermissionOrderRead, true},
+		{models.RoleSupport, models.PermissionOrderRefund, false},
+		{models.RoleUser, models.PermissionEventRead, false},
+		{"superuser", models.PermissionEventRead, false},
+	}
+
+	for _, tc := range cases {
+		if got := models.HasPermission(tc.role, tc.permission); got != tc.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tc.role, tc.permission, got, tc.want)
+		}
+	}
+
+	if !models.ValidRole(models.RoleUser) || !models.ValidRole(models.RoleGateStaff) || models.ValidRole("superuser") {
+		t.Error("ValidRole returned unexpected result")
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(role string, mfa bool, permissions ...models.Permission) int {
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			c.Set("role", role)
+			c.Set("mfa", mfa)
+		}, middleware.RequirePermission(permissions...), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	if code := serve(models.RoleGateStaff, false, models.PermissionTicketUse); code != http.StatusOK {
+		t.Errorf("Expected gate staff to use tickets, got %d", code)
+	}
+	if code := serve(models.RoleUser, false, models.PermissionTicketUse); code != http.StatusForbidden {
+		t.Errorf("Expected regular user to be forbidden, got %d", code)
+	}
+	if code := serve(models.RoleFinance, false, models.PermissionOrderRead, models.PermissionOrderRefund); code != http.StatusOK {
+		t.Errorf("Expected finance to read and refund orders, got %d", code)
+	}
+	if code := serve(models.RoleSupport, false, models.PermissionOrderRead, models.PermissionOrderRefund); code != http.StatusForbidden {
+		t.Errorf("Expected support to be forbidden from refunds, got %d", code)
+	}
+
+	// 預設要求管理員完成兩步驟驗證
+	if code := serve(models.RoleAdmin, false, models.PermissionOrderRefund); code != http.StatusForbidden {
+		t.Errorf("Expected admin session without MFA to be forbidden, got %d", code)
+	}
+	if code := serve(models.RoleAdmin, true, models.PermissionOrderRefund); code != http.StatusOK {
+		t.Errorf("Expected admin session with MFA to be allowed, got %d", code)
+	}
+}
+
+func TestAPIKeyDeniedOnUnregisteredRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newTestDB(t)
+
+	user := &models.User{Email: "gate@example.com", PasswordHash: "-", Name: "驗票員", Role: models.RoleGateStaff}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("建立用戶失敗: %v", err)
+	}
+	issue := func(scopes string) string {
+		key, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		record := &models.APIKey{UserID: user.ID, Name: "gate", Prefix: prefix, KeyHash: hash, Scopes: scopes, CreatedBy: user.ID}
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("建立 API 金鑰失敗: %v", err)
+		}
+		return key
+	}
+	scoped := issue(string(models.PermissionTicketUse))
+	unscoped := issue("")
+
+	apiKeyRoutes := middleware.NewAPIKeyRoutes()
+	router := gin.New()
+	group := router.Group("/api/v1")
+	group.Use(middleware.AuthRequired(db, nil, apiKeyRoutes))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	apiKeyRoutes.Handle(group, http.MethodGet, "/tickets/validate/:ticket_code", models.PermissionTicketUse, ok)
+	group.GET("/users/me", ok)
+
+	serve := func(key string, path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := serve(scoped, "/api/v1/tickets/validate/ABC"); code != http.StatusOK {
+		t.Errorf("Expected key with scope to access registered route, got %d", code)
+	}
+	if code := serve(unscoped, "/api/v1/tickets/validate/ABC"); code != http.StatusForbidden {
+		t.Errorf("Expected key without scope to be forbidden, got %d", code)
+	}
+	// 未宣告權限範圍的路由預設拒絕 API 金鑰
+	if code := serve(scoped, "/api/v1/users/me"); code != http.StatusForbidden {
+		t.Errorf("Expected API key to be denied on unregistered route, got %d", code)
+	}
+	if code := serve("tg_invalid", "/api/v1/tickets/validate/ABC"); code != http.StatusUnauthorized {
+		t.Errorf("Expected invalid key to be unauthorized, got %d", code)
+	}
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/jwtkeys"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// newTestAuthService 以 SQLite 創建 AuthService，並建立密碼為 password123 的用戶
+func newTestAuthService(t *testing.T, client *redis.Client, email string) (*services.AuthService, *gorm.DB, *models.User) {
+	db := newTestDB(t)
+
+	key, err := jwtkeys.GenerateEd25519("test")
+	if err != nil {
+		t.Fatalf("GenerateEd25519 failed: %v", err)
+	}
+	keys, err := jwtkeys.NewKeySet("test", key)
+	if err != nil {
+		t.Fatalf("NewKeySet failed: %v", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+	user := &models.User{Email: email, PasswordHash: string(hash), Name: "測試用戶", Role: models.RoleUser}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("建立用戶失敗: %v", err)
+	}
+
+	service := services.NewAuthService(db, client, &config.Config{JWTExpiryHours: 1}, mailer.NewMemoryMailer(), keys)
+	return service, db, user
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	_, client := newTestRedis(t)
+	service, db, user := newTestAuthService(t, client, "user@example.com")
+	info := dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test"}
+
+	login, err := service.Login(dto.LoginRequest{Email: user.Email, Password: "password123"}, info)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	_, rotated, err := service.RefreshToken(login.RefreshToken, info)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if rotated == login.RefreshToken {
+		t.Fatal("Expected refresh token to be rotated")
+	}
+
+	// 再次使用已輪替的令牌，整個工作階段應被撤銷
+	if _, _, err := service.RefreshToken(login.RefreshToken, info); err == nil || err.Error() != "刷新令牌已被重複使用，請重新登入" {
+		t.Fatalf("Expected reuse to be detected, got %v", err)
+	}
+
+	var session models.Session
+	if err := db.Where("user_id = ?", user.ID).First(&session).Error; err != nil {
+		t.Fatalf("查詢工作階段失敗: %v", err)
+	}
+	if session.RevokedAt == nil {
+		t.Error("Expected session to be revoked after refresh token reuse")
+	}
+
+	// 同一令牌家族中較新的令牌也隨之失效
+	if _, _, err := service.RefreshToken(rotated, info); err == nil {
+		t.Error("Expected rotated refresh token to be rejected after session revocation")
+	}
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/middleware"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/ipreputation"
+)
+
+// fixedRiskSource 返回固定分數的測試用訊號
+type fixedRiskSource struct {
+	score int
+}
+
+func (s fixedRiskSource) Name() string { return "fixed" }
+
+func (s fixedRiskSource) Evaluate(ctx context.Context, req dto.RiskRequest) ([]services.RiskSignal, error) {
+	if s.score == 0 {
+		return nil, nil
+	}
+	return []services.RiskSignal{{Name: "fixed", Score: s.score, Reason: "test"}}, nil
+}
+
+func TestRiskDecision(t *testing.T) {
+	tests := []struct {
+		scores []int
+		want   services.RiskDecision
+	}{
+		{nil, services.RiskAllow},
+		{[]int{10, 20}, services.RiskAllow},
+		{[]int{25, 15}, services.RiskChallenge},
+		{[]int{50, 30}, services.RiskBlock},
+	}
+
+	for _, tt := range tests {
+		service := &services.RiskService{ChallengeScore: 40, BlockScore: 80}
+		for _, score := range tt.scores {
+			service.Sources = append(service.Sources, fixedRiskSource{score: score})
+		}
+
+		assessment := service.Assess(context.Background(), dto.RiskRequest{})
+		if assessment.Decision != tt.want {
+			t.Errorf("Scores %v: expected %s, got %s (score %d)", tt.scores, tt.want, assessment.Decision, assessment.Score)
+		}
+		if len(assessment.Signals) != len(tt.scores) {
+			t.Errorf("Scores %v: expected %d signals, got %d", tt.scores, len(tt.scores), len(assessment.Signals))
+		}
+	}
+}
+
+func TestHeadlessSignal(t *testing.T) {
+	signal := services.NewHeadlessSignal()
+	browserHeader := http.Header{"Accept-Language": {"zh-TW"}}
+
+	tests := []struct {
+		name      string
+		userAgent string
+		header    http.Header
+		wantScore int
+	}{
+		{"regular browser", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0", browserHeader, 0},
+		{"headless chrome", "Mozilla/5.0 (X11; Linux x86_64) HeadlessChrome/120.0", browserHeader, 40},
+		{"scripted client", "python-requests/2.31", browserHeader, 40},
+		{"missing user agent", "", browserHeader, 40},
+		{"missing accept-language", "Mozilla/5.0 Chrome/120.0", http.Header{}, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signals, err := signal.Evaluate(context.Background(), dto.RiskRequest{
+				Client: dto.ClientInfo{UserAgent: tt.userAgent},
+				Header: tt.header,
+			})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			score := 0
+			for _, s := range signals {
+				score += s.Score
+			}
+			if score != tt.wantScore {
+				t.Errorf("Expected score %d, got %d (%v)", tt.wantScore, score, signals)
+			}
+		})
+	}
+}
+
+func TestIPReputationList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datacenter.txt")
+	content := "# 資料中心網段\n203.0.113.0/24\n198.51.100.7 # 已知黃牛\n\n2001:db8::/32\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write list: %v", err)
+	}
+
+	list, err := ipreputation.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if list.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", list.Len())
+	}
+
+	for _, ip := range []string{"203.0.113.50", "198.51.100.7", "2001:db8::1"} {
+		if source, ok := list.Lookup(ip); !ok || source != "datacenter" {
+			t.Errorf("Expected %s to be listed in datacenter, got %q %v", ip, source, ok)
+		}
+	}
+	if _, ok := list.Lookup("198.51.100.8"); ok {
+		t.Error("Expected 198.51.100.8 not to be listed")
+	}
+
+	signals, _ := services.NewIPReputationSignal(list).Evaluate(context.Background(), dto.RiskRequest{
+		Client: dto.ClientInfo{IPAddress: "203.0.113.9"},
+	})
+	if len(signals) != 1 || signals[0].Score != 50 {
+		t.Errorf("Expected ip reputation signal, got %v", signals)
+	}
+
+	if err := os.WriteFile(path, []byte("not-an-ip\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write list: %v", err)
+	}
+	if _, err := ipreputation.Load(path); err == nil {
+		t.Error("Expected error for invalid entry")
+	}
+}
+
+func TestRiskCheckSkipsReads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &services.RiskService{
+		Sources:        []services.RiskSignalSource{fixedRiskSource{score: 100}},
+		ChallengeScore: 40,
+		BlockScore:     80,
+	}
+
+	router := gin.New()
+	router.Use(middleware.RiskCheck(service))
+	router.Any("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(method string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/orders", nil))
+		return w.Code
+	}
+
+	// 讀取請求不評估風險，高風險寫入請求被拒絕
+	if code := serve(http.MethodGet); code != http.StatusOK {
+		t.Errorf("Expected GET to skip risk assessment, got %d", code)
+	}
+	if code := serve(http.MethodPost); code != http.StatusForbidden {
+		t.Errorf("Expected high-risk POST to be blocked, got %d", code)
+	}
+}
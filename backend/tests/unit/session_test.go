@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/middleware"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/internal/vo"
+)
+
+// loginSession 登入並返回存取令牌與其工作階段 ID
+func loginSession(t *testing.T, service *services.AuthService, email string, userAgent string) (string, string) {
+	login, err := service.Login(dto.LoginRequest{Email: email, Password: "password123"}, dto.ClientInfo{IPAddress: "203.0.113.7", UserAgent: userAgent})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims := &middleware.JWTClaims{}
+	if _, err := service.SigningKeys.Parse(login.Token, claims); err != nil {
+		t.Fatalf("解析存取令牌失敗: %v", err)
+	}
+	return login.Token, claims.SessionID
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	_, client := newTestRedis(t)
+	service, db, user := newTestAuthService(t, client, "user@example.com")
+	other := createPasswordUser(t, db, "other@example.com")
+
+	_, phone := loginSession(t, service, user.Email, "phone")
+	_, laptop := loginSession(t, service, user.Email, "laptop")
+	_, otherSession := loginSession(t, service, other.Email, "other")
+
+	sessions, err := service.ListSessions(user.ID.String(), laptop)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected only the user's two sessions, got %+v", sessions)
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID.String() == laptop) {
+			t.Errorf("Expected only the laptop session to be current, got %+v", session)
+		}
+	}
+
+	// 不可撤銷其他用戶的工作階段，回應與不存在相同
+	if err := service.RevokeSession(user.ID.String(), otherSession); err == nil || err.Error() != "工作階段不存在" {
+		t.Errorf("Expected another user's session to be not found, got %v", err)
+	}
+	var revoked int64
+	db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NOT NULL", otherSession).Count(&revoked)
+	if revoked != 0 {
+		t.Error("Expected another user's session to stay active")
+	}
+
+	if err := service.RevokeSession(user.ID.String(), phone); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if err := service.RevokeSession(user.ID.String(), phone); err == nil || err.Error() != "工作階段不存在" {
+		t.Errorf("Expected revoked session to be not found, got %v", err)
+	}
+
+	sessions, err = service.ListSessions(user.ID.String(), laptop)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID.String() != laptop {
+		t.Errorf("Expected revoked session to be hidden, got %+v", sessions)
+	}
+}
+
+func TestRevokedSessionAccessTokenRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, client := newTestRedis(t)
+	service, db, user := newTestAuthService(t, client, "user@example.com")
+
+	router := gin.New()
+	router.GET("/me", middleware.AuthRequired(db, service.SigningKeys, middleware.NewAPIKeyRoutes()), func(c *gin.Context) {
+		c.JSON(http.StatusOK, vo.BaseResponse{Message: c.GetString("sessionID")})
+	})
+	get := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	phoneToken, phone := loginSession(t, service, user.Email, "phone")
+	laptopToken, _ := loginSession(t, service, user.Email, "laptop")
+	if code := get(phoneToken); code != http.StatusOK {
+		t.Fatalf("Expected access token to be accepted before revocation, got %d", code)
+	}
+
+	// 存取令牌尚未過期，但工作階段撤銷後立即失效
+	if err := service.RevokeSession(user.ID.String(), phone); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if code := get(phoneToken); code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked session's access token to be rejected, got %d", code)
+	}
+	if code := get(laptopToken); code != http.StatusOK {
+		t.Errorf("Expected other sessions to stay valid, got %d", code)
+	}
+}
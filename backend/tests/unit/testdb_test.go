@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testSchema 測試所需的資料表；模型使用 PostgreSQL 專用的預設值（gen_random_uuid、now），無法在 SQLite 上自動遷移
+var testSchema = []string{
+	`CREATE TABLE users (id text PRIMARY KEY, email text NOT NULL UNIQUE, email_verified_at datetime, password_hash text NOT NULL, name text NOT NULL, phone text, role text NOT NULL DEFAULT 'user', tls_fingerprint text, mfa_secret text, mfa_enabled_at datetime, mfa_last_used_step integer NOT NULL DEFAULT 0, is_service_account boolean NOT NULL DEFAULT false, created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE sessions (id text PRIMARY KEY, user_id text NOT NULL, user_agent text, ip_address text, tls_fingerprint text, last_seen_at datetime, mfa_verified boolean NOT NULL DEFAULT false, revoked_at datetime, created_at datetime, updated_at datetime)`,
+	`CREATE TABLE refresh_tokens (id text PRIMARY KEY, session_id text NOT NULL, user_id text NOT NULL, expires_at datetime NOT NULL, used_at datetime, created_at datetime)`,
+	`CREATE TABLE api_keys (id text PRIMARY KEY, user_id text NOT NULL, name text NOT NULL, prefix text NOT NULL, key_hash text NOT NULL UNIQUE, scopes text NOT NULL DEFAULT '', expires_at datetime, last_used_at datetime, revoked_at datetime, created_by text NOT NULL, created_at datetime)`,
+	`CREATE TABLE device_links (id text PRIMARY KEY, user_id text NOT NULL, kind text NOT NULL, value text NOT NULL, seen_count integer NOT NULL DEFAULT 1, first_seen_at datetime, last_seen_at datetime, UNIQUE (user_id, kind, value))`,
+	`CREATE TABLE events (id text PRIMARY KEY, title text NOT NULL, description text, location text NOT NULL, start_time datetime NOT NULL, end_time datetime NOT NULL, created_by text NOT NULL, created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE ticket_types (id text PRIMARY KEY, event_id text NOT NULL, name text NOT NULL, price numeric NOT NULL, total_quantity integer NOT NULL, available_quantity integer NOT NULL, sale_start datetime NOT NULL, sale_end datetime NOT NULL, fingerprint_window_minutes integer NOT NULL DEFAULT 1440, fingerprint_max_purchases integer NOT NULL DEFAULT 1, fingerprint_scope text NOT NULL DEFAULT 'ticket_type', created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE orders (id text PRIMARY KEY, user_id text NOT NULL, total_amount numeric NOT NULL, status text NOT NULL DEFAULT 'pending', payment_method text, payment_status text DEFAULT 'unpaid', created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE order_items (id text PRIMARY KEY, order_id text NOT NULL, ticket_type_id text NOT NULL, quantity integer NOT NULL, price_per_unit numeric NOT NULL, created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE tickets (id text PRIMARY KEY, order_item_id text NOT NULL, ticket_code text NOT NULL UNIQUE, is_used boolean NOT NULL DEFAULT false, used_at datetime, created_at datetime, updated_at datetime, deleted_at datetime)`,
+	`CREATE TABLE invoices (id text PRIMARY KEY, invoice_number text NOT NULL UNIQUE, type text NOT NULL DEFAULT 'invoice', order_id text NOT NULL, user_id text NOT NULL, original_invoice_id text, year integer NOT NULL, sequence_number integer NOT NULL, buyer_name text NOT NULL, buyer_email text NOT NULL, subtotal numeric NOT NULL, fee_amount numeric NOT NULL DEFAULT 0, tax_rate numeric NOT NULL DEFAULT 0, tax_amount numeric NOT NULL DEFAULT 0, total_amount numeric NOT NULL, issued_at datetime NOT NULL, created_at datetime, updated_at datetime, UNIQUE (order_id, type))`,
+	`CREATE TABLE invoice_items (id text PRIMARY KEY, invoice_id text NOT NULL, description text NOT NULL, quantity integer NOT NULL, unit_price numeric NOT NULL, amount numeric NOT NULL, created_at datetime)`,
+	`CREATE TABLE invoice_sequences (prefix text NOT NULL, year integer NOT NULL, last_number integer NOT NULL DEFAULT 0, updated_at datetime, PRIMARY KEY (prefix, year))`,
+	`CREATE TABLE login_attempts (id text PRIMARY KEY, user_id text, email text NOT NULL, ip_address text, user_agent text, result text NOT NULL, created_at datetime)`,
+	`CREATE TABLE user_tokens (id text PRIMARY KEY, user_id text NOT NULL, purpose text NOT NULL, token_hash text NOT NULL UNIQUE, new_email text, expires_at datetime NOT NULL, used_at datetime, created_at datetime)`,
+	`CREATE TABLE user_identities (id text PRIMARY KEY, user_id text NOT NULL, provider text NOT NULL, subject text NOT NULL, email text, created_at datetime, updated_at datetime, UNIQUE (provider, subject), UNIQUE (user_id, provider))`,
+	`CREATE TABLE mfa_recovery_codes (id text PRIMARY KEY, user_id text NOT NULL, code_hash text NOT NULL, used_at datetime, created_at datetime)`,
+}
+
+// newTestDB 建立以 SQLite 暫存檔為後端的資料庫
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("無法建立測試資料庫: %v", err)
+	}
+	for _, statement := range testSchema {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("建立資料表失敗: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newTestRedis 建立 miniredis 與連線至它的客戶端
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
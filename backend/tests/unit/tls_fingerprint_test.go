@@ -40,7 +40,7 @@ func TestGetClientIPFingerprint(t *testing.T) {
 	// 創建一個 HTTP 請求
 	req := httptest.NewRequest("GET", "http://example.com", nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
-	req.Header.Set("X-Forwarded-For", "192.168.1.1")
+	req.RemoteAddr = "192.168.1.1:40000"
 	
 	// 獲取客戶端 IP 和指紋組合
 	ipFingerprint := utils.GetClientIPFingerprint(req)
@@ -57,9 +57,16 @@ func TestGetClientIPFingerprint(t *testing.T) {
 	}
 	
 	// 修改 IP，應該得到不同的指紋
-	req.Header.Set("X-Forwarded-For", "192.168.1.2")
+	req.RemoteAddr = "192.168.1.2:40000"
 	ipFingerprint3 := utils.GetClientIPFingerprint(req)
 	if ipFingerprint == ipFingerprint3 {
 		t.Error("Expected different IP fingerprints for different IPs, got the same value")
 	}
+
+	// 未經可信任代理時，客戶端自行附加的轉發標頭不影響指紋
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.Header.Set("X-TLS-Fingerprint", "spoofed")
+	if spoofed := utils.GetClientIPFingerprint(req); spoofed != ipFingerprint3 {
+		t.Error("Expected forwarded headers from untrusted clients to be ignored")
+	}
 }
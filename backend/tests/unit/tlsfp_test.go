@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lipeichen/ticket-getter/pkg/tlsfp"
+)
+
+func TestTLSFingerprintFromHandshake(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fp := tlsfp.FromRequest(r)
+		if fp == nil {
+			http.Error(w, "no fingerprint", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, fp.JA3+"\n"+fp.JA3Hash+"\n"+fp.JA4)
+	}))
+	server.Listener = tlsfp.NewListener(server.Listener)
+	server.TLS = tlsfp.WrapTLSConfig(nil)
+	server.Config.ConnContext = tlsfp.ConnContext
+	server.StartTLS()
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	parts := strings.Split(string(body), "\n")
+	if len(parts) != 3 {
+		t.Fatalf("Unexpected response: %q", body)
+	}
+	ja3, ja3Hash, ja4 := parts[0], parts[1], parts[2]
+
+	// Go 用戶端以 TLS 1.2 記錄版本（771）送出 ClientHello
+	if !regexp.MustCompile(`^771,[\d-]+,[\d-]+,[\d-]*,[\d-]*$`).MatchString(ja3) {
+		t.Errorf("Unexpected JA3: %s", ja3)
+	}
+	if len(ja3Hash) != 32 {
+		t.Errorf("Expected MD5 JA3 hash, got %s", ja3Hash)
+	}
+	// httptest 用戶端連線至 IP，不送 SNI；預設支援 TLS 1.3
+	if !regexp.MustCompile(`^t13i\d{4}[0-9a-z]{2}_[0-9a-f]{12}_[0-9a-f]{12}$`).MatchString(ja4) {
+		t.Errorf("Unexpected JA4: %s", ja4)
+	}
+
+	// 同一用戶端設定應產生相同指紋
+	resp2, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp2.Body.Close()
+	body2, _ := io.ReadAll(resp2.Body)
+	if strings.Split(string(body2), "\n")[2] != ja4 {
+		t.Errorf("Expected stable JA4, got %s and %s", ja4, body2)
+	}
+
+	// 限制用戶端的加密套件與版本，指紋應改變
+	client := server.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.MaxVersion = tls.VersionTLS12
+	transport.TLSClientConfig.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	client.Transport = transport
+	resp3, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp3.Body.Close()
+	body3, _ := io.ReadAll(resp3.Body)
+	ja4TLS12 := strings.Split(string(body3), "\n")[2]
+	if !strings.HasPrefix(ja4TLS12, "t12i01") {
+		t.Errorf("Expected TLS 1.2 JA4 with one cipher, got %s", ja4TLS12)
+	}
+}
+
+func TestParseClientHelloRejectsNonTLS(t *testing.T) {
+	if _, err := tlsfp.ParseClientHello([]byte("GET / HTTP/1.1\r\n\r\n")); err == nil {
+		t.Error("Expected error for plain HTTP request")
+	}
+	if _, err := tlsfp.ParseClientHello([]byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01}); err == nil {
+		t.Error("Expected error for truncated record")
+	}
+}
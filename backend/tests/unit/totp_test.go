@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/pkg/totp"
+)
+
+// RFC 6238 附錄 B 的 SHA-1 測試密鑰 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 測試向量的後六位
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		code, err := totp.Code(rfc6238Secret, totp.Step(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code returned error: %v", err)
+		}
+		if code != c.code {
+			t.Errorf("Expected code %s at %d, got %s", c.code, c.unix, code)
+		}
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totp.Step(now)
+
+	// 前一個時間步的驗證碼在容許誤差內
+	previous, _ := totp.Code(rfc6238Secret, step-1)
+	matched, ok := totp.Validate(rfc6238Secret, previous, now, 1)
+	if !ok || matched != step-1 {
+		t.Errorf("Expected previous step code to validate at step %d, got %d (%v)", step-1, matched, ok)
+	}
+
+	// 超出容許誤差的驗證碼應被拒絕
+	stale, _ := totp.Code(rfc6238Secret, step-2)
+	if _, ok := totp.Validate(rfc6238Secret, stale, now, 1); ok {
+		t.Error("Expected code outside the skew window to be rejected")
+	}
+
+	if _, ok := totp.Validate(rfc6238Secret, "12345", now, 1); ok {
+		t.Error("Expected malformed code to be rejected")
+	}
+}
+
+func TestTOTPSecretAndURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret returned error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("Expected 32 character secret, got %d", len(secret))
+	}
+
+	uri := totp.URI("Ticket-Getter", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Ticket-Getter:user@example.com?") {
+		t.Errorf("Unexpected otpauth URI: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+secret) || !strings.Contains(uri, "issuer=Ticket-Getter") {
+		t.Errorf("Expected secret and issuer in URI: %s", uri)
+	}
+}
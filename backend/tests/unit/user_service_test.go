@@ -0,0 +1,367 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lipeichen/ticket-getter/config"
+	"github.com/lipeichen/ticket-getter/internal/dto"
+	"github.com/lipeichen/ticket-getter/internal/models"
+	"github.com/lipeichen/ticket-getter/internal/services"
+	"github.com/lipeichen/ticket-getter/pkg/mailer"
+	"github.com/lipeichen/ticket-getter/pkg/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// newTestUserService 以 SQLite 創建 UserService
+func newTestUserService(t *testing.T) (*services.UserService, *gorm.DB) {
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	cfg := &config.Config{FrontendURL: "https://tickets.example.com"}
+	ticketService := services.NewTicketService(db, client, cfg)
+	orderService := services.NewOrderService(db, services.NewInvoiceService(db, cfg))
+	return services.NewUserService(db, cfg, mailer.NewMemoryMailer(), orderService, ticketService), db
+}
+
+// createOIDCOnlyUser 建立未設定密碼、僅以第三方登入的用戶
+func createOIDCOnlyUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	now := time.Now()
+	user := &models.User{Email: email, EmailVerifiedAt: &now, PasswordHash: "!", Name: "第三方用戶", Role: models.RoleUser}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("建立用戶失敗: %v", err)
+	}
+	if err := db.Create(&models.UserIdentity{UserID: user.ID, Provider: "google", Subject: "sub-" + email, Email: email}).Error; err != nil {
+		t.Fatalf("建立第三方身分失敗: %v", err)
+	}
+	return user
+}
+
+// createSession 建立於 loggedInAt 登入的工作階段
+func createSession(t *testing.T, db *gorm.DB, userID uuid.UUID, loggedInAt time.Time) string {
+	session := models.Session{UserID: userID, LastSeenAt: loggedInAt, CreatedAt: loggedInAt, UpdatedAt: loggedInAt}
+	if err := db.Create(&session).Error; err != nil {
+		t.Fatalf("建立工作階段失敗: %v", err)
+	}
+	return session.ID.String()
+}
+
+func TestOIDCOnlyUserReauthentication(t *testing.T) {
+	service, db := newTestUserService(t)
+	ctx := context.Background()
+
+	user := createOIDCOnlyUser(t, db, "oidc@example.com")
+	stale := createSession(t, db, user.ID, time.Now().Add(-time.Hour))
+	fresh := createSession(t, db, user.ID, time.Now())
+
+	// 沒有密碼可比對，太久以前登入的工作階段須重新登入
+	if err := service.RequestEmailChange(ctx, user.ID.String(), stale, dto.ChangeEmailRequest{Email: "new@example.com"}); err == nil || err.Error() != "請重新登入後再試" {
+		t.Errorf("Expected email change from stale session to require login, got %v", err)
+	}
+	if err := service.DeleteAccount(user.ID.String(), stale, ""); err == nil || err.Error() != "請重新登入後再試" {
+		t.Errorf("Expected deletion from stale session to require login, got %v", err)
+	}
+	if err := service.ChangePassword(user.ID.String(), "", dto.ChangePasswordRequest{NewPassword: "newPassword123"}); err == nil || err.Error() != "請重新登入後再試" {
+		t.Errorf("Expected password change without session to require login, got %v", err)
+	}
+
+	// 剛以第三方登入的工作階段視為已確認身分
+	if err := service.RequestEmailChange(ctx, user.ID.String(), fresh, dto.ChangeEmailRequest{Email: "new@example.com"}); err != nil {
+		t.Errorf("Expected email change from fresh session to succeed, got %v", err)
+	}
+	if err := service.ChangePassword(user.ID.String(), fresh, dto.ChangePasswordRequest{NewPassword: "newPassword123"}); err != nil {
+		t.Fatalf("Expected fresh session to set a password, got %v", err)
+	}
+
+	// 設定密碼後改為比對密碼
+	var updated models.User
+	db.First(&updated, "id = ?", user.ID)
+	if err := bcrypt.CompareHashAndPassword([]byte(updated.PasswordHash), []byte("newPassword123")); err != nil {
+		t.Fatalf("Expected new password to be set, got %v", err)
+	}
+	if err := service.DeleteAccount(user.ID.String(), fresh, ""); err == nil || err.Error() != "密碼不正確" {
+		t.Errorf("Expected password to be required once set, got %v", err)
+	}
+	if err := service.DeleteAccount(user.ID.String(), fresh, "newPassword123"); err != nil {
+		t.Errorf("Expected deletion with password to succeed, got %v", err)
+	}
+}
+
+func TestOIDCOnlyUserDeleteAccount(t *testing.T) {
+	service, db := newTestUserService(t)
+
+	user := createOIDCOnlyUser(t, db, "oidc@example.com")
+	fresh := createSession(t, db, user.ID, time.Now())
+	if err := service.DeleteAccount(user.ID.String(), fresh, ""); err != nil {
+		t.Fatalf("Expected deletion from fresh session to succeed, got %v", err)
+	}
+
+	var count int64
+	db.Model(&models.User{}).Where("id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Error("Expected account to be deleted")
+	}
+}
+
+func TestOIDCOnlyUserDisableMFA(t *testing.T) {
+	db := newTestDB(t)
+	_, client := newTestRedis(t)
+	mfa := services.NewMFAService(db, client, &config.Config{MFAEncryptionKey: "test-mfa-key", MFAIssuer: "Ticket-Getter"})
+
+	user := createOIDCOnlyUser(t, db, "oidc@example.com")
+	stale := createSession(t, db, user.ID, time.Now().Add(-time.Hour))
+	fresh := createSession(t, db, user.ID, time.Now())
+
+	setup, err := mfa.Setup(user.ID.String())
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(setup.Secret, step)
+	if _, err := mfa.Enable(user.ID.String(), fresh, code); err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+
+	next, _ := totp.Code(setup.Secret, step+1)
+	if err := mfa.Disable(user.ID.String(), stale, "", next); err == nil || err.Error() != "請重新登入後再試" {
+		t.Errorf("Expected stale session to require login before disabling MFA, got %v", err)
+	}
+	if err := mfa.Disable(user.ID.String(), fresh, "", next); err != nil {
+		t.Fatalf("Expected fresh session to disable MFA without password, got %v", err)
+	}
+
+	var updated models.User
+	db.First(&updated, "id = ?", user.ID)
+	if updated.MFAEnabledAt != nil {
+		t.Error("Expected MFA to be disabled")
+	}
+}
+
+// createPasswordUser 建立密碼為 password123 的用戶
+func createPasswordUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+	user := &models.User{Email: email, PasswordHash: string(hash), Name: "測試用戶", Phone: "0912345678", Role: models.RoleUser}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("建立用戶失敗: %v", err)
+	}
+	return user
+}
+
+// createTestTicketType 建立於 start 開始、持續三小時的活動及其票種
+func createTestTicketType(t *testing.T, db *gorm.DB, title string, start time.Time) *models.TicketType {
+	event := &models.Event{Title: title, Location: "台北小巨蛋", StartTime: start, EndTime: start.Add(3 * time.Hour), CreatedBy: uuid.New()}
+	if err := db.Create(event).Error; err != nil {
+		t.Fatalf("建立活動失敗: %v", err)
+	}
+	ticketType := &models.TicketType{
+		EventID:           event.ID,
+		Name:              "全票",
+		Price:             1000,
+		TotalQuantity:     100,
+		AvailableQuantity: 100,
+		SaleStart:         time.Now().Add(-time.Hour),
+		SaleEnd:           time.Now().Add(time.Hour),
+	}
+	if err := db.Create(ticketType).Error; err != nil {
+		t.Fatalf("建立票種失敗: %v", err)
+	}
+	return ticketType
+}
+
+// createPaidOrder 建立訂單並確認收款，返回訂單 ID
+func createPaidOrder(t *testing.T, orderService *services.OrderService, userID uuid.UUID, ticketType *models.TicketType, quantity int) string {
+	order, err := orderService.CreateOrder(userID.String(), dto.CreateOrderRequest{
+		Items: []dto.OrderItemRequest{{TicketTypeID: ticketType.ID.String(), Quantity: quantity}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if _, err := orderService.MarkPaid(order.ID, "credit_card", ""); err != nil {
+		t.Fatalf("MarkPaid failed: %v", err)
+	}
+	return order.ID.String()
+}
+
+func TestUpdateProfile(t *testing.T) {
+	service, db := newTestUserService(t)
+	user := createPasswordUser(t, db, "user@example.com")
+
+	profile, err := service.UpdateProfile(user.ID.String(), dto.UpdateProfileRequest{Name: "李四"})
+	if err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	// 未提供的欄位保持不變
+	if profile.Name != "李四" || profile.Phone != "0912345678" {
+		t.Errorf("Expected name to change and phone to be kept, got %+v", profile)
+	}
+
+	var updated models.User
+	db.First(&updated, "id = ?", user.ID)
+	if updated.Name != "李四" || updated.Phone != "0912345678" {
+		t.Errorf("Expected profile to be saved, got name %q phone %q", updated.Name, updated.Phone)
+	}
+
+	if _, err := service.UpdateProfile(uuid.New().String(), dto.UpdateProfileRequest{Name: "李四"}); err == nil || err.Error() != "使用者不存在" {
+		t.Errorf("Expected unknown user to be rejected, got %v", err)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	service, db := newTestUserService(t)
+	ctx := context.Background()
+	user := createPasswordUser(t, db, "user@example.com")
+	createPasswordUser(t, db, "taken@example.com")
+	mail := service.Mailer.(*mailer.MemoryMailer)
+
+	request := func(email string, password string) error {
+		return service.RequestEmailChange(ctx, user.ID.String(), "", dto.ChangeEmailRequest{Email: email, Password: password})
+	}
+	if err := request("new@example.com", "wrong-password"); err == nil || err.Error() != "密碼不正確" {
+		t.Errorf("Expected wrong password to be rejected, got %v", err)
+	}
+	if err := request("TAKEN@example.com", "password123"); err == nil || err.Error() != "電子郵件已被使用" {
+		t.Errorf("Expected taken email to be rejected, got %v", err)
+	}
+	if err := request("User@Example.com", "password123"); err == nil || err.Error() != "新電子郵件與目前相同" {
+		t.Errorf("Expected same email to be rejected, got %v", err)
+	}
+	if len(mail.Messages()) != 0 {
+		t.Fatalf("Expected no mail for rejected requests, got %d", len(mail.Messages()))
+	}
+
+	if err := request(" New@Example.com ", "password123"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	messages := mail.Messages()
+	if len(messages) != 2 || messages[0].To != "new@example.com" || messages[1].To != "user@example.com" {
+		t.Fatalf("Expected confirmation to new email and notice to old email, got %+v", messages)
+	}
+
+	// 確認前不變更
+	var current models.User
+	db.First(&current, "id = ?", user.ID)
+	if current.Email != "user@example.com" {
+		t.Fatalf("Expected email to stay until confirmed, got %s", current.Email)
+	}
+
+	token := tokenFromLink(t, messages[0].Body)
+	confirmed, err := service.ConfirmEmailChange(token)
+	if err != nil {
+		t.Fatalf("ConfirmEmailChange failed: %v", err)
+	}
+	if confirmed.Email != "new@example.com" || !confirmed.EmailVerified {
+		t.Errorf("Expected verified new email, got %+v", confirmed)
+	}
+
+	// 令牌只能使用一次
+	if _, err := service.ConfirmEmailChange(token); err == nil || err.Error() != "無效或已過期的令牌" {
+		t.Errorf("Expected used token to be rejected, got %v", err)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	service, db := newTestUserService(t)
+	user := createPasswordUser(t, db, "user@example.com")
+	ticketType := createTestTicketType(t, db, "演唱會", time.Now().Add(24*time.Hour))
+	orderID := createPaidOrder(t, service.OrderService, user.ID, ticketType, 1)
+
+	sessionID := createSession(t, db, user.ID, time.Now())
+	db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"ip_address": "203.0.113.7", "user_agent": "Mozilla/5.0", "tls_fingerprint": "fp-a",
+	})
+	// 註冊前以此電子郵件嘗試登入的紀錄沒有帳號 ID
+	db.Create(&models.LoginAttempt{UserID: &user.ID, Email: user.Email, IPAddress: "203.0.113.7", Result: models.LoginResultSuccess})
+	db.Create(&models.LoginAttempt{Email: user.Email, IPAddress: "203.0.113.8", Result: models.LoginResultInvalidCredentials})
+
+	if err := service.DeleteAccount(user.ID.String(), sessionID, "wrong-password"); err == nil || err.Error() != "密碼不正確" {
+		t.Fatalf("Expected wrong password to be rejected, got %v", err)
+	}
+	if err := service.DeleteAccount(user.ID.String(), sessionID, "password123"); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	var deleted models.User
+	db.Unscoped().First(&deleted, "id = ?", user.ID)
+	if !deleted.DeletedAt.Valid || deleted.Email == "user@example.com" || deleted.Name != "已刪除用戶" || deleted.Phone != "" {
+		t.Errorf("Expected user to be anonymised and deleted, got %+v", deleted)
+	}
+
+	var session models.Session
+	db.First(&session, "id = ?", sessionID)
+	if session.RevokedAt == nil || session.IPAddress != "" || session.UserAgent != "" || session.TLSFingerprint != "" {
+		t.Errorf("Expected session to be revoked and anonymised, got %+v", session)
+	}
+
+	var attempts int64
+	db.Model(&models.LoginAttempt{}).Count(&attempts)
+	if attempts != 0 {
+		t.Errorf("Expected login attempts to be deleted, got %d", attempts)
+	}
+
+	// 訂單因會計需求保留
+	var orders int64
+	db.Model(&models.Order{}).Where("id = ?", orderID).Count(&orders)
+	if orders != 1 {
+		t.Error("Expected orders to be kept")
+	}
+
+	// 原電子郵件可重新註冊
+	createPasswordUser(t, db, "user@example.com")
+}
+
+func TestExportUserData(t *testing.T) {
+	service, db := newTestUserService(t)
+	user := createPasswordUser(t, db, "user@example.com")
+	ticketType := createTestTicketType(t, db, "演唱會", time.Now().Add(24*time.Hour))
+	orderID := createPaidOrder(t, service.OrderService, user.ID, ticketType, 2)
+
+	sessionID := createSession(t, db, user.ID, time.Now())
+	db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"ip_address": "203.0.113.7", "user_agent": "Mozilla/5.0", "tls_fingerprint": "fp-a",
+	})
+	db.Create(&models.LoginAttempt{UserID: &user.ID, Email: user.Email, IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0", Result: models.LoginResultSuccess})
+
+	// 其他用戶的資料不可出現
+	other := createPasswordUser(t, db, "other@example.com")
+	createSession(t, db, other.ID, time.Now())
+	createPaidOrder(t, service.OrderService, other.ID, ticketType, 1)
+
+	export, err := service.ExportUserData(user.ID.String())
+	if err != nil {
+		t.Fatalf("ExportUserData failed: %v", err)
+	}
+	if export.User.Email != user.Email {
+		t.Errorf("Expected user profile, got %+v", export.User)
+	}
+	if len(export.Orders) != 1 || export.Orders[0].ID.String() != orderID || len(export.Orders[0].Items[0].Tickets) != 2 {
+		t.Errorf("Expected one order with two tickets, got %+v", export.Orders)
+	}
+	if len(export.Tickets.Upcoming) != 2 {
+		t.Errorf("Expected two upcoming tickets, got %+v", export.Tickets)
+	}
+	if len(export.Invoices) != 1 || export.Invoices[0].OrderID.String() != orderID {
+		t.Errorf("Expected one invoice, got %+v", export.Invoices)
+	}
+	if len(export.Sessions) != 1 || export.Sessions[0].IPAddress != "203.0.113.7" || export.Sessions[0].UserAgent != "Mozilla/5.0" || export.Sessions[0].TLSFingerprint != "fp-a" {
+		t.Errorf("Expected session with IP, user agent and TLS fingerprint, got %+v", export.Sessions)
+	}
+	if len(export.LoginAttempts) != 1 || export.LoginAttempts[0].IPAddress != "203.0.113.7" || export.LoginAttempts[0].Result != models.LoginResultSuccess {
+		t.Errorf("Expected login attempt, got %+v", export.LoginAttempts)
+	}
+}
+
+// tokenFromLink 取出郵件連結中的令牌
+func tokenFromLink(t *testing.T, body string) string {
+	_, rest, ok := strings.Cut(body, "token=")
+	if !ok {
+		t.Fatalf("Expected link with token in mail body: %s", body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,137 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lipeichen/ticket-getter/pkg/wallet"
+	"go.mozilla.org/pkcs7"
+)
+
+func TestApplePassBuilder(t *testing.T) {
+	dir := t.TempDir()
+
+	// 產生測試用 CA（模擬 WWDR）與票券憑證
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA failed: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	passKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	passTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.com.example.ticket"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	passDER, err := x509.CreateCertificate(rand.Reader, passTemplate, caCert, &passKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create pass certificate failed: %v", err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(passKey)
+
+	writePEM(t, filepath.Join(dir, "wwdr.pem"), "CERTIFICATE", caDER)
+	writePEM(t, filepath.Join(dir, "pass.pem"), "CERTIFICATE", passDER)
+	writePEM(t, filepath.Join(dir, "pass.key"), "PRIVATE KEY", keyDER)
+	if err := os.WriteFile(filepath.Join(dir, "icon.png"), []byte("icon"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	builder, err := wallet.NewApplePassBuilder(wallet.AppleConfig{
+		PassTypeIdentifier: "pass.com.example.ticket",
+		TeamIdentifier:     "TEAM123456",
+		OrganizationName:   "Ticket Getter",
+		WebServiceURL:      "https://example.com/api/v1/wallet",
+		CertPath:           filepath.Join(dir, "pass.pem"),
+		KeyPath:            filepath.Join(dir, "pass.key"),
+		WWDRPath:           filepath.Join(dir, "wwdr.pem"),
+		AssetsDir:          dir,
+	})
+	if err != nil {
+		t.Fatalf("NewApplePassBuilder failed: %v", err)
+	}
+
+	content, err := builder.Build(wallet.TicketInfo{
+		SerialNumber: "serial-1",
+		TicketCode:   "TICKET-CODE",
+		EventTitle:   "Concert",
+		StartTime:    time.Now().Add(24 * time.Hour),
+		EndTime:      time.Now().Add(27 * time.Hour),
+	}, "auth-token-1234567890")
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("pkpass is not a zip archive: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	// 測試 pass.json 內容
+	t.Run("PassJSON", func(t *testing.T) {
+		var pass map[string]interface{}
+		if err := json.Unmarshal(files["pass.json"], &pass); err != nil {
+			t.Fatalf("invalid pass.json: %v", err)
+		}
+		if pass["serialNumber"] != "serial-1" || pass["authenticationToken"] != "auth-token-1234567890" {
+			t.Errorf("unexpected pass.json: %s", files["pass.json"])
+		}
+	})
+
+	// 測試 manifest 簽章可由票券憑證驗證
+	t.Run("Signature", func(t *testing.T) {
+		for _, name := range []string{"manifest.json", "signature", "icon.png"} {
+			if _, ok := files[name]; !ok {
+				t.Fatalf("missing %s in pkpass", name)
+			}
+		}
+
+		p7, err := pkcs7.Parse(files["signature"])
+		if err != nil {
+			t.Fatalf("parse signature failed: %v", err)
+		}
+		p7.Content = files["manifest.json"]
+		if err := p7.Verify(); err != nil {
+			t.Errorf("signature verification failed: %v", err)
+		}
+	})
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}